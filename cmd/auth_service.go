@@ -3,13 +3,25 @@ package main
 import (
 	"fmt"
 	"log"
+	"os"
+	"time"
+	"user-authentication/internal/cli"
 	"user-authentication/internal/config"
+	"user-authentication/internal/core/apikey"
 	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/mfa"
+	"user-authentication/internal/core/oauth"
+	"user-authentication/internal/core/passkey"
+	"user-authentication/internal/core/rbac"
+	"user-authentication/internal/core/signingkey"
 	"user-authentication/internal/core/user"
 	"user-authentication/internal/handler"
 	"user-authentication/internal/postgres"
 	"user-authentication/internal/router"
 	"user-authentication/pkg/logger"
+	"user-authentication/pkg/notifier"
+	"user-authentication/pkg/password"
+	"user-authentication/pkg/ratelimit"
 )
 
 func main() {
@@ -25,6 +37,21 @@ func main() {
 		return
 	}
 
+	// subcommand given on the command line, e.g. "keys rotate"
+	command := ""
+	if len(os.Args) > 1 {
+		command = os.Args[1]
+	}
+
+	// the breach commands only work on local files
+	if command == "breach" {
+		if err := cli.RunBreachCommand(os.Args[2:]); err != nil {
+			log.Println("Breach command failed:", err)
+		}
+
+		return
+	}
+
 	log.Println("Starting the application...")
 
 	// Postgres connection string
@@ -67,17 +94,165 @@ func main() {
 	// defer postgres
 	defer postgresClient.Disconnect()
 
+	// managed signing keys, persisted so that every replica agrees on the keyring
+	if config.AuthTokenConfig.SigningKeyDir != "" {
+		signingKeyService := signingkey.NewSigningKeyService(
+			signingkey.NewSigningKeyRepo(postgresClient),
+			config.AuthTokenConfig.KeyRing,
+			config.AuthTokenConfig.SigningKeyDir,
+			config.AuthTokenConfig.SigningAlgorithm,
+			time.Duration(config.AuthTokenConfig.KeyGracePeriod)*time.Minute,
+			time.Duration(config.KeyRingSyncInterval)*time.Second,
+		)
+
+		if command == "keys" {
+			if err := cli.RunKeysCommand(os.Args[2:], signingKeyService); err != nil {
+				log.Println("Signing key command failed:", err)
+			}
+
+			return
+		}
+
+		if err := signingKeyService.EnsureActiveKey(); err != nil {
+			log.Println("Failed to load the signing keyring:", err)
+
+			return
+		}
+		stopKeyRingSync := signingKeyService.StartSync(time.Duration(config.KeyRingSyncInterval) * time.Second)
+		defer stopKeyRingSync()
+	} else if command == "keys" {
+		log.Println("Signing key commands require JWT_SIGNING_KEY_DIR to be configured")
+
+		return
+	}
+
 	// repository
 	userRepo := user.NewUserRepo(postgresClient)
+	refreshTokenRepo := auth.NewRefreshTokenRepo(postgresClient)
+	revokedTokenRepo := auth.NewRevokedTokenRepo(postgresClient)
+	rbacRepo := rbac.NewRBACRepo(postgresClient)
+	userTokenRepo := auth.NewUserTokenRepo(postgresClient)
+	mfaRepo := mfa.NewMFARepo(postgresClient)
+	passkeyRepo := passkey.NewPasskeyRepo(postgresClient)
+	oauthRepo := oauth.NewOAuthRepo(postgresClient)
+	apiKeyRepo := apikey.NewAPIKeyRepo(postgresClient)
+	passwordHistoryRepo := user.NewPasswordHistoryRepo(postgresClient)
+
+	// role permissions, cached in memory
+	rbacService := rbac.NewRBACService(rbacRepo)
+	if err := rbacService.Load(); err != nil {
+		log.Println("Failed to load the role permissions:", err)
+
+		return
+	}
+
+	if command == "admin" {
+		if err := cli.RunAdminCommand(os.Args[2:], userRepo, rbacService); err != nil {
+			log.Println("Admin command failed:", err)
+		}
+
+		return
+	}
+
+	// access token revocation list, kept in memory and synced in the background
+	revocationStore := auth.NewRevocationStore(revokedTokenRepo)
+	if err := revocationStore.Load(); err != nil {
+		log.Println("Failed to load the token revocation list:", err)
+
+		return
+	}
+	stopRevocationSync := revocationStore.StartSync(time.Duration(config.RevocationSyncInterval) * time.Second)
+	defer stopRevocationSync()
+
+	// notifications sent to the users, e.g. password reset, email verification and login links
+	userNotifier, err := notifier.New(config.NotifierSink, config.NotifierFile)
+	if err != nil {
+		log.Println("Failed to create the notifier:", err)
+
+		return
+	}
+
 	// services
-	userService := user.NewUserService(userRepo)
-	authService := auth.NewAuthService(config.AuthTokenConfig, userRepo)
+	passwordHasher, err := password.NewHasher(config.PasswordHasherConfig)
+	if err != nil {
+		log.Println("Failed to create the password hasher:", err)
+
+		return
+	}
+	passwordPolicy, err := password.NewPolicy(config.PasswordPolicyConfig)
+	if err != nil {
+		log.Println("Failed to create the password policy:", err)
+
+		return
+	}
+	passwordHistoryService := user.NewPasswordHistoryService(config.PasswordHistoryConfig, passwordHistoryRepo, passwordHasher)
+	mfaService, err := mfa.NewMFAService(config.MFAConfig, mfaRepo, userRepo)
+	if err != nil {
+		log.Println("Failed to create the MFA service:", err)
+
+		return
+	}
+	passkeyService := passkey.NewPasskeyService(config.PasskeyConfig, passkeyRepo, userRepo)
+	emailVerificationService := auth.NewEmailVerificationService(config.EmailVerificationConfig, userRepo, userTokenRepo, userNotifier)
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepo, userRepo, rbacService, config.PasswordExpiryConfig)
+	authService := auth.NewAuthService(
+		config.AuthTokenConfig,
+		userRepo,
+		refreshTokenRepo,
+		revocationStore,
+		rbacService,
+		config.LockoutConfig,
+		emailVerificationService,
+		mfaService,
+		userTokenRepo,
+		passkeyService,
+		passwordHasher,
+		passwordPolicy,
+		config.PasswordExpiryConfig,
+		apiKeyService,
+	)
+	userService := user.NewUserService(
+		userRepo,
+		rbacService,
+		authService,
+		passwordHasher,
+		passwordPolicy,
+		passwordHistoryService,
+		config.PasswordExpiryConfig,
+	)
+	passwordResetService := auth.NewPasswordResetService(
+		config.PasswordResetConfig,
+		userRepo,
+		userTokenRepo,
+		authService,
+		userNotifier,
+		passwordHasher,
+		passwordPolicy,
+		passwordHistoryService,
+	)
+	magicLinkService := auth.NewMagicLinkService(config.MagicLinkConfig, userRepo, userTokenRepo, userNotifier)
+	oauthService := oauth.NewOAuthService(config.AuthTokenConfig, oauthRepo, userRepo, authService)
+
+	// rate limit counters, kept in memory for a single node deployment
+	rateLimitStore := ratelimit.NewMemoryStore()
+	stopRateLimitPurge := rateLimitStore.StartPurge(time.Minute)
+	defer stopRateLimitPurge()
 
 	// Set up the router and start the server
 	router := router.NewRouter(
 		config.Port,
 		handler.NewUserHandler(userService),
 		handler.NewAuthHandler(authService),
+		handler.NewRBACHandler(rbacService),
+		handler.NewPasswordResetHandler(passwordResetService),
+		handler.NewEmailVerificationHandler(emailVerificationService),
+		handler.NewMFAHandler(mfaService),
+		handler.NewPasskeyHandler(passkeyService, authService),
+		handler.NewMagicLinkHandler(magicLinkService, authService),
+		handler.NewOAuthHandler(oauthService, authService),
+		handler.NewAPIKeyHandler(apiKeyService),
+		rateLimitStore,
+		config.RateLimitRules,
 	)
 
 	router.InitRouter()
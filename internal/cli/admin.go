@@ -0,0 +1,46 @@
+package cli
+
+import (
+	"fmt"
+	"user-authentication/internal/core/rbac"
+	"user-authentication/internal/core/user"
+)
+
+const adminUsage = `usage: admin <command>
+
+commands:
+  bootstrap <username>   grant the admin role to an existing account, only while no administrator exists`
+
+// RunAdminCommand runs the administration commands
+func RunAdminCommand(args []string, userRepo user.UserRepoPort, rbacService rbac.RBACServicePort) error {
+	if len(args) == 0 {
+		fmt.Println(adminUsage)
+
+		return ErrMissingArgs
+	}
+
+	switch args[0] {
+	case "bootstrap":
+		if len(args) < 2 {
+			fmt.Println(adminUsage)
+
+			return ErrMissingArgs
+		}
+
+		admin, err := userRepo.GetByUsername(args[1])
+		if err != nil {
+			return err
+		}
+
+		if err := rbacService.BootstrapAdmin(admin.ID); err != nil {
+			return err
+		}
+		fmt.Printf("user %s is now an administrator\n", admin.Username)
+	default:
+		fmt.Println(adminUsage)
+
+		return fmt.Errorf("%w: admin %s", ErrUnknownCommand, args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"user-authentication/pkg/password"
+)
+
+// false positive rate of the filters built without an explicit one
+const defaultFalsePositiveRate = 0.001
+
+var ErrEmptyDump = errors.New("no hashes found in the dump")
+
+const breachUsage = `usage: breach <command>
+
+commands:
+  build <dump> <filter> [rate]   build a bloom filter from a SHA-1 or NTLM hash dump with one HASH or
+                                 HASH:COUNT line per hash, at the false positive rate, 0.001 by default`
+
+// RunBreachCommand runs the breached password corpus commands
+func RunBreachCommand(args []string) error {
+	if len(args) == 0 {
+		fmt.Println(breachUsage)
+
+		return ErrMissingArgs
+	}
+
+	switch args[0] {
+	case "build":
+		if len(args) < 3 {
+			fmt.Println(breachUsage)
+
+			return ErrMissingArgs
+		}
+
+		rate := defaultFalsePositiveRate
+		if len(args) > 3 {
+			parsed, err := strconv.ParseFloat(args[3], 64)
+			if err != nil {
+				return fmt.Errorf("invalid false positive rate %q: %v", args[3], err)
+			}
+			rate = parsed
+		}
+
+		return buildBloomFilter(args[1], args[2], rate)
+	default:
+		fmt.Println(breachUsage)
+
+		return fmt.Errorf("%w: breach %s", ErrUnknownCommand, args[0])
+	}
+}
+
+// buildBloomFilter reads the dump twice, once to size the filter and once to fill it, so
+// that dumps larger than the memory can be used. Malformed lines are skipped.
+func buildBloomFilter(dumpPath string, filterPath string, rate float64) error {
+	hashType := ""
+	var count uint64
+	err := scanDump(dumpPath, func(line string) {
+		if hashType == "" {
+			hashType = dumpHashType(line)
+		}
+		if _, err := password.ParseHashLine(line, hashType); err == nil {
+			count++
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrEmptyDump
+	}
+
+	filter, err := password.NewBloomFilter(hashType, count, rate)
+	if err != nil {
+		return err
+	}
+
+	skipped := 0
+	err = scanDump(dumpPath, func(line string) {
+		digest, err := password.ParseHashLine(line, hashType)
+		if err != nil {
+			skipped++
+			return
+		}
+		filter.AddDigest(digest)
+	})
+	if err != nil {
+		return err
+	}
+
+	// written next to the target and renamed, a running service never reads a partial filter
+	tmp, err := os.CreateTemp(filepath.Dir(filterPath), filepath.Base(filterPath)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	if _, err := filter.WriteTo(writer); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), filterPath); err != nil {
+		return err
+	}
+
+	fmt.Printf("built %s bloom filter %s from %d hashes, %d lines skipped\n", hashType, filterPath, count, skipped)
+
+	return nil
+}
+
+// scanDump calls fn with every non-empty line of the dump
+func scanDump(path string, fn func(line string)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			fn(line)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// dumpHashType tells the hash type of a dump from the length of its hashes
+func dumpHashType(line string) string {
+	hexHash, _, _ := strings.Cut(line, ":")
+	if len(hexHash) == 32 {
+		return password.HASH_NTLM
+	}
+
+	return password.HASH_SHA1
+}
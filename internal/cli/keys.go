@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+	"user-authentication/internal/core/signingkey"
+)
+
+var (
+	ErrUnknownCommand = errors.New("unknown command")
+	ErrMissingArgs    = errors.New("missing command arguments")
+)
+
+const keysUsage = `usage: keys <command>
+
+commands:
+  list            list every signing key and its status
+  generate        generate a new pending key, published in the JWKS but not signing yet
+  promote <kid>   make a pending key the signing key, the current key starts retiring,
+                  the key must have been pending for a full keyring sync interval
+  rotate          generate a new key, wait a keyring sync interval and promote it
+  retire          retire the keys past their grace period`
+
+// RunKeysCommand runs the signing key administration commands
+func RunKeysCommand(args []string, keyService signingkey.SigningKeyServicePort) error {
+	if len(args) == 0 {
+		fmt.Println(keysUsage)
+
+		return ErrMissingArgs
+	}
+
+	switch args[0] {
+	case "list":
+		keys, err := keyService.Get()
+		if err != nil {
+			return err
+		}
+		printKeys(keys)
+	case "generate":
+		key, err := keyService.Generate()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("generated pending signing key %s (%s)\n", key.KeyID, key.Algorithm)
+	case "promote":
+		if len(args) < 2 {
+			fmt.Println(keysUsage)
+
+			return ErrMissingArgs
+		}
+		if err := keyService.Promote(args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("promoted signing key %s\n", args[1])
+	case "rotate":
+		key, err := keyService.Rotate()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("rotated to signing key %s (%s)\n", key.KeyID, key.Algorithm)
+	case "retire":
+		retired, err := keyService.RetireExpired()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("retired %d signing keys\n", retired)
+	default:
+		fmt.Println(keysUsage)
+
+		return fmt.Errorf("%w: keys %s", ErrUnknownCommand, args[0])
+	}
+
+	return nil
+}
+
+func printKeys(keys []signingkey.SigningKey) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tSTATUS\tCREATED\tRETIRE AT")
+	for _, key := range keys {
+		retireAt := "-"
+		if key.RetireAt != nil {
+			retireAt = key.RetireAt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", key.KeyID, key.Algorithm, key.Status, key.CreatedAt.Format(time.RFC3339), retireAt)
+	}
+	w.Flush()
+}
@@ -3,8 +3,16 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/mfa"
+	"user-authentication/internal/core/passkey"
+	"user-authentication/internal/core/user"
 	"user-authentication/pkg/jwt"
 	"user-authentication/pkg/logger"
+	"user-authentication/pkg/password"
+	"user-authentication/pkg/ratelimit"
+	"user-authentication/pkg/secretbox"
 
 	"github.com/joho/godotenv"
 )
@@ -27,6 +35,26 @@ type ServerConfig struct {
 	MigrationDir    string
 	PGConfig        PGConfig
 	AuthTokenConfig jwt.AuthTokenConfig
+	LockoutConfig   auth.LockoutConfig
+	RateLimitRules  ratelimit.Rules
+
+	PasswordResetConfig     auth.PasswordResetConfig
+	EmailVerificationConfig auth.EmailVerificationConfig
+	MagicLinkConfig         auth.MagicLinkConfig
+	MFAConfig               mfa.MFAConfig
+	PasskeyConfig           passkey.PasskeyConfig
+	PasswordHasherConfig    password.HasherConfig
+	PasswordPolicyConfig    password.PolicyConfig
+	PasswordHistoryConfig   user.PasswordHistoryConfig
+	PasswordExpiryConfig    user.PasswordExpiryConfig
+	// notification sink, log or file, and the file the file sink appends to
+	NotifierSink string
+	NotifierFile string
+
+	// interval of the background sync of the token revocation list
+	RevocationSyncInterval int
+	// interval of the background sync of the signing keyring
+	KeyRingSyncInterval int
 }
 
 // default configuration path
@@ -48,8 +76,87 @@ const (
 	KEY_DB_CONN_TIMEOUT = "DB_CONN_TIMEOUT"
 
 	// jwt .env config keys
-	KEY_JWT_SECRET     = "JWT_SECRET"
-	JWT_EXPIRY_MINUTES = "JWT_EXPIRY_MINUTES"
+	KEY_JWT_SECRET            = "JWT_SECRET"
+	JWT_EXPIRY_MINUTES        = "JWT_EXPIRY_MINUTES"
+	KEY_JWT_SIGNING_ALGORITHM = "JWT_SIGNING_ALGORITHM"
+	KEY_JWT_SIGNING_KEY_FILE  = "JWT_SIGNING_KEY_FILE"
+	KEY_JWT_SIGNING_KEY_ID    = "JWT_SIGNING_KEY_ID"
+
+	// jwt claims .env config keys
+	KEY_JWT_ISSUER                  = "JWT_ISSUER"
+	KEY_JWT_AUDIENCE                = "JWT_AUDIENCE"
+	KEY_JWT_NOT_BEFORE_SKEW_SECONDS = "JWT_NOT_BEFORE_SKEW_SECONDS"
+	KEY_JWT_CLOCK_LEEWAY_SECONDS    = "JWT_CLOCK_LEEWAY_SECONDS"
+
+	// signing key rotation .env config keys
+	KEY_JWT_SIGNING_KEY_DIR               = "JWT_SIGNING_KEY_DIR"
+	KEY_JWT_KEY_GRACE_PERIOD_MINUTES      = "JWT_KEY_GRACE_PERIOD_MINUTES"
+	KEY_JWT_KEYRING_SYNC_INTERVAL_SECONDS = "JWT_KEYRING_SYNC_INTERVAL_SECONDS"
+
+	// refresh token .env config keys
+	KEY_REFRESH_TOKEN_EXPIRY_HOURS = "REFRESH_TOKEN_EXPIRY_HOURS"
+
+	// account lockout .env config keys
+	KEY_LOCKOUT_THRESHOLD             = "LOCKOUT_THRESHOLD"
+	KEY_LOCKOUT_BASE_DURATION_SECONDS = "LOCKOUT_BASE_DURATION_SECONDS"
+	KEY_LOCKOUT_MAX_DURATION_SECONDS  = "LOCKOUT_MAX_DURATION_SECONDS"
+	KEY_LOCKOUT_PERMANENT_AFTER       = "LOCKOUT_PERMANENT_AFTER"
+
+	// rate limit .env config keys
+	KEY_RATE_LIMITS = "RATE_LIMITS"
+
+	// password reset .env config keys
+	KEY_PASSWORD_RESET_TOKEN_EXPIRY_MINUTES = "PASSWORD_RESET_TOKEN_EXPIRY_MINUTES"
+	KEY_PASSWORD_RESET_URL                  = "PASSWORD_RESET_URL"
+
+	// password hashing .env config keys
+	KEY_PASSWORD_HASH_ALGORITHM     = "PASSWORD_HASH_ALGORITHM"
+	KEY_PASSWORD_ARGON2_MEMORY_KIB  = "PASSWORD_ARGON2_MEMORY_KIB"
+	KEY_PASSWORD_ARGON2_ITERATIONS  = "PASSWORD_ARGON2_ITERATIONS"
+	KEY_PASSWORD_ARGON2_PARALLELISM = "PASSWORD_ARGON2_PARALLELISM"
+	KEY_PASSWORD_BCRYPT_COST        = "PASSWORD_BCRYPT_COST"
+
+	// password policy .env config keys
+	KEY_PASSWORD_MIN_LENGTH        = "PASSWORD_MIN_LENGTH"
+	KEY_PASSWORD_MAX_LENGTH        = "PASSWORD_MAX_LENGTH"
+	KEY_PASSWORD_REQUIRE_LOWERCASE = "PASSWORD_REQUIRE_LOWERCASE"
+	KEY_PASSWORD_REQUIRE_UPPERCASE = "PASSWORD_REQUIRE_UPPERCASE"
+	KEY_PASSWORD_REQUIRE_DIGIT     = "PASSWORD_REQUIRE_DIGIT"
+	KEY_PASSWORD_REQUIRE_SYMBOL    = "PASSWORD_REQUIRE_SYMBOL"
+	KEY_PASSWORD_FORBID_USER_INFO  = "PASSWORD_FORBID_USER_INFO"
+	KEY_PASSWORD_DICTIONARY_FILE   = "PASSWORD_DICTIONARY_FILE"
+	KEY_PASSWORD_BREACH_FILE       = "PASSWORD_BREACH_FILE"
+	KEY_PASSWORD_BREACH_FORMAT     = "PASSWORD_BREACH_FORMAT"
+	KEY_PASSWORD_BREACH_HASH_TYPE  = "PASSWORD_BREACH_HASH_TYPE"
+	KEY_PASSWORD_HISTORY_SIZE      = "PASSWORD_HISTORY_SIZE"
+	KEY_PASSWORD_MAX_AGE_DAYS      = "PASSWORD_MAX_AGE_DAYS"
+	KEY_PASSWORD_EXPIRY_WARNING    = "PASSWORD_EXPIRY_WARNING_DAYS"
+
+	// email verification .env config keys
+	KEY_EMAIL_VERIFICATION_REQUIRED           = "EMAIL_VERIFICATION_REQUIRED"
+	KEY_EMAIL_VERIFICATION_TOKEN_EXPIRY_HOURS = "EMAIL_VERIFICATION_TOKEN_EXPIRY_HOURS"
+	KEY_EMAIL_VERIFICATION_URL                = "EMAIL_VERIFICATION_URL"
+
+	// magic link login .env config keys
+	KEY_MAGIC_LINK_ENABLED              = "MAGIC_LINK_ENABLED"
+	KEY_MAGIC_LINK_TOKEN_EXPIRY_MINUTES = "MAGIC_LINK_TOKEN_EXPIRY_MINUTES"
+	KEY_MAGIC_LINK_URL                  = "MAGIC_LINK_URL"
+
+	// multi-factor authentication .env config keys
+	KEY_MFA_ISSUER         = "MFA_ISSUER"
+	KEY_MFA_ENCRYPTION_KEY = "MFA_ENCRYPTION_KEY"
+
+	// webauthn passkey .env config keys
+	KEY_WEBAUTHN_RP_ID   = "WEBAUTHN_RP_ID"
+	KEY_WEBAUTHN_RP_NAME = "WEBAUTHN_RP_NAME"
+	KEY_WEBAUTHN_ORIGINS = "WEBAUTHN_ORIGINS"
+
+	// notifier .env config keys
+	KEY_NOTIFIER      = "NOTIFIER"
+	KEY_NOTIFIER_FILE = "NOTIFIER_FILE"
+
+	// token revocation .env config keys
+	KEY_REVOCATION_SYNC_INTERVAL_SECONDS = "REVOCATION_SYNC_INTERVAL_SECONDS"
 )
 
 // NewServerConfig creates a new instance of ServerConfig with default values.
@@ -68,6 +175,7 @@ func (sc *ServerConfig) loadServerConfig() {
 	sc.Host = os.Getenv(KEY_SERVER_HOST)
 	sc.Port = os.Getenv(KEY_SERVER_PORT)
 	sc.MigrationDir = os.Getenv(KEY_MIGRATION_DIR)
+	sc.RevocationSyncInterval, _ = strconv.Atoi(os.Getenv(KEY_REVOCATION_SYNC_INTERVAL_SECONDS))
 }
 
 // loadPostgresConfig loads Postgres-related configurations from environment variables.
@@ -82,9 +190,121 @@ func (sc *ServerConfig) loadPostgresConfig() {
 }
 
 // loadJWTConfig loads JWT-related configurations from environment variables.
-func (sc *ServerConfig) loadAuthTokenConfig() {
+func (sc *ServerConfig) loadAuthTokenConfig() error {
 	sc.AuthTokenConfig.SecretKey = []byte(os.Getenv(KEY_JWT_SECRET))
+	sc.AuthTokenConfig.SigningAlgorithm = os.Getenv(KEY_JWT_SIGNING_ALGORITHM)
+	sc.AuthTokenConfig.SigningKeyFile = os.Getenv(KEY_JWT_SIGNING_KEY_FILE)
+	sc.AuthTokenConfig.SigningKeyID = os.Getenv(KEY_JWT_SIGNING_KEY_ID)
+	sc.AuthTokenConfig.SigningKeyDir = os.Getenv(KEY_JWT_SIGNING_KEY_DIR)
+	sc.AuthTokenConfig.KeyGracePeriod, _ = strconv.Atoi(os.Getenv(KEY_JWT_KEY_GRACE_PERIOD_MINUTES))
+	sc.KeyRingSyncInterval, _ = strconv.Atoi(os.Getenv(KEY_JWT_KEYRING_SYNC_INTERVAL_SECONDS))
 	sc.AuthTokenConfig.AccessTokenExpiry, _ = strconv.Atoi(os.Getenv(JWT_EXPIRY_MINUTES))
+	sc.AuthTokenConfig.RefreshTokenExpiry, _ = strconv.Atoi(os.Getenv(KEY_REFRESH_TOKEN_EXPIRY_HOURS))
+	sc.AuthTokenConfig.Issuer = os.Getenv(KEY_JWT_ISSUER)
+	sc.AuthTokenConfig.Audience = splitList(os.Getenv(KEY_JWT_AUDIENCE))
+	sc.AuthTokenConfig.NotBeforeSkew, _ = strconv.Atoi(os.Getenv(KEY_JWT_NOT_BEFORE_SKEW_SECONDS))
+	sc.AuthTokenConfig.ClockLeeway, _ = strconv.Atoi(os.Getenv(KEY_JWT_CLOCK_LEEWAY_SECONDS))
+
+	return sc.AuthTokenConfig.LoadSigningKey()
+}
+
+// loadLockoutConfig loads the account lockout policy from environment variables.
+func (sc *ServerConfig) loadLockoutConfig() {
+	sc.LockoutConfig.Threshold, _ = strconv.Atoi(os.Getenv(KEY_LOCKOUT_THRESHOLD))
+	sc.LockoutConfig.BaseDuration, _ = strconv.Atoi(os.Getenv(KEY_LOCKOUT_BASE_DURATION_SECONDS))
+	sc.LockoutConfig.MaxDuration, _ = strconv.Atoi(os.Getenv(KEY_LOCKOUT_MAX_DURATION_SECONDS))
+	sc.LockoutConfig.PermanentAfter, _ = strconv.Atoi(os.Getenv(KEY_LOCKOUT_PERMANENT_AFTER))
+}
+
+// loadPasswordResetConfig loads the password reset and notifier settings from environment variables.
+func (sc *ServerConfig) loadPasswordResetConfig() {
+	sc.PasswordResetConfig.TokenExpiry, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_RESET_TOKEN_EXPIRY_MINUTES))
+	sc.PasswordResetConfig.ResetURL = os.Getenv(KEY_PASSWORD_RESET_URL)
+	sc.NotifierSink = os.Getenv(KEY_NOTIFIER)
+	sc.NotifierFile = os.Getenv(KEY_NOTIFIER_FILE)
+}
+
+// loadPasswordHasherConfig loads the password hashing settings from environment variables.
+func (sc *ServerConfig) loadPasswordHasherConfig() {
+	sc.PasswordHasherConfig.Algorithm = os.Getenv(KEY_PASSWORD_HASH_ALGORITHM)
+	sc.PasswordHasherConfig.Argon2Memory, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_ARGON2_MEMORY_KIB))
+	sc.PasswordHasherConfig.Argon2Iterations, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_ARGON2_ITERATIONS))
+	sc.PasswordHasherConfig.Argon2Parallelism, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_ARGON2_PARALLELISM))
+	sc.PasswordHasherConfig.BcryptCost, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_BCRYPT_COST))
+}
+
+// loadPasswordPolicyConfig loads the password policy from environment variables.
+func (sc *ServerConfig) loadPasswordPolicyConfig() {
+	sc.PasswordPolicyConfig.MinLength, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_MIN_LENGTH))
+	sc.PasswordPolicyConfig.MaxLength, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_MAX_LENGTH))
+	sc.PasswordPolicyConfig.RequireLowercase, _ = strconv.ParseBool(os.Getenv(KEY_PASSWORD_REQUIRE_LOWERCASE))
+	sc.PasswordPolicyConfig.RequireUppercase, _ = strconv.ParseBool(os.Getenv(KEY_PASSWORD_REQUIRE_UPPERCASE))
+	sc.PasswordPolicyConfig.RequireDigit, _ = strconv.ParseBool(os.Getenv(KEY_PASSWORD_REQUIRE_DIGIT))
+	sc.PasswordPolicyConfig.RequireSymbol, _ = strconv.ParseBool(os.Getenv(KEY_PASSWORD_REQUIRE_SYMBOL))
+	sc.PasswordPolicyConfig.ForbidUserInfo, _ = strconv.ParseBool(os.Getenv(KEY_PASSWORD_FORBID_USER_INFO))
+	sc.PasswordPolicyConfig.DictionaryFile = os.Getenv(KEY_PASSWORD_DICTIONARY_FILE)
+	sc.PasswordPolicyConfig.BreachFile = os.Getenv(KEY_PASSWORD_BREACH_FILE)
+	sc.PasswordPolicyConfig.BreachFormat = os.Getenv(KEY_PASSWORD_BREACH_FORMAT)
+	sc.PasswordPolicyConfig.BreachHashType = os.Getenv(KEY_PASSWORD_BREACH_HASH_TYPE)
+	sc.PasswordHistoryConfig.Size, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_HISTORY_SIZE))
+	sc.PasswordExpiryConfig.MaxAge, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_MAX_AGE_DAYS))
+	sc.PasswordExpiryConfig.WarningPeriod, _ = strconv.Atoi(os.Getenv(KEY_PASSWORD_EXPIRY_WARNING))
+}
+
+// loadEmailVerificationConfig loads the email verification settings from environment variables.
+func (sc *ServerConfig) loadEmailVerificationConfig() {
+	sc.EmailVerificationConfig.RequireVerified, _ = strconv.ParseBool(os.Getenv(KEY_EMAIL_VERIFICATION_REQUIRED))
+	sc.EmailVerificationConfig.TokenExpiry, _ = strconv.Atoi(os.Getenv(KEY_EMAIL_VERIFICATION_TOKEN_EXPIRY_HOURS))
+	sc.EmailVerificationConfig.VerifyURL = os.Getenv(KEY_EMAIL_VERIFICATION_URL)
+}
+
+// loadMagicLinkConfig loads the magic link login settings from environment variables.
+func (sc *ServerConfig) loadMagicLinkConfig() {
+	sc.MagicLinkConfig.Enabled, _ = strconv.ParseBool(os.Getenv(KEY_MAGIC_LINK_ENABLED))
+	sc.MagicLinkConfig.TokenExpiry, _ = strconv.Atoi(os.Getenv(KEY_MAGIC_LINK_TOKEN_EXPIRY_MINUTES))
+	sc.MagicLinkConfig.CallbackURL = os.Getenv(KEY_MAGIC_LINK_URL)
+}
+
+// loadMFAConfig loads the multi-factor authentication settings from environment variables.
+func (sc *ServerConfig) loadMFAConfig() error {
+	key, err := secretbox.ParseKey(os.Getenv(KEY_MFA_ENCRYPTION_KEY))
+	if err != nil {
+		return err
+	}
+	sc.MFAConfig.EncryptionKey = key
+	sc.MFAConfig.Issuer = os.Getenv(KEY_MFA_ISSUER)
+
+	return nil
+}
+
+// loadPasskeyConfig loads the WebAuthn relying party settings from environment variables.
+func (sc *ServerConfig) loadPasskeyConfig() {
+	sc.PasskeyConfig.RPID = os.Getenv(KEY_WEBAUTHN_RP_ID)
+	sc.PasskeyConfig.RPName = os.Getenv(KEY_WEBAUTHN_RP_NAME)
+	sc.PasskeyConfig.Origins = splitList(os.Getenv(KEY_WEBAUTHN_ORIGINS))
+}
+
+// loadRateLimitConfig loads the rate limit rules from environment variables.
+func (sc *ServerConfig) loadRateLimitConfig() error {
+	rules, err := ratelimit.ParseRules(os.Getenv(KEY_RATE_LIMITS))
+	if err != nil {
+		return err
+	}
+	sc.RateLimitRules = rules
+
+	return nil
+}
+
+// splitList splits a comma separated config value, empty entries are dropped
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
 }
 
 // LoadConfigs loads all configurations from the specified .env file.
@@ -97,7 +317,28 @@ func (sc *ServerConfig) LoadConfigs() error {
 
 	sc.loadServerConfig()
 	sc.loadPostgresConfig()
-	sc.loadAuthTokenConfig()
+	sc.loadLockoutConfig()
+	sc.loadPasswordResetConfig()
+	sc.loadPasswordHasherConfig()
+	sc.loadPasswordPolicyConfig()
+	sc.loadEmailVerificationConfig()
+	sc.loadMagicLinkConfig()
+	sc.loadPasskeyConfig()
+	if err := sc.loadRateLimitConfig(); err != nil {
+		logger.Error("Loading rate limit rules failed", "error", err)
+
+		return err
+	}
+	if err := sc.loadMFAConfig(); err != nil {
+		logger.Error("Loading MFA encryption key failed", "error", err)
+
+		return err
+	}
+	if err := sc.loadAuthTokenConfig(); err != nil {
+		logger.Error("Loading signing key failed", "error", err)
+
+		return err
+	}
 
 	logger.Info("Server configuration loaded", "config", sc)
 
@@ -0,0 +1,23 @@
+package apikey
+
+import "time"
+
+// APIKey represents a personal API key of a user, only the hash of the key is persisted
+type APIKey struct {
+	ID     int    `json:"id"`
+	UserID int    `json:"-"`
+	Name   string `json:"name"`
+	// Prefix is the leading part of the key, shown to tell the keys apart
+	Prefix  string `json:"prefix"`
+	KeyHash string `json:"-"`
+	// Scopes limit the permissions of the key, none grants every permission of the user
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsExpired reports whether the key expired, keys without expiry never do
+func (key APIKey) IsExpired(now time.Time) bool {
+	return key.ExpiresAt != nil && !now.Before(*key.ExpiresAt)
+}
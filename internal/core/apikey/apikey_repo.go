@@ -0,0 +1,11 @@
+package apikey
+
+type APIKeyRepoPort interface {
+	Create(key APIKey) (APIKey, error)
+	GetByPrefix(prefix string) (APIKey, error)
+	GetByUser(userID int) ([]APIKey, error)
+	CountByUser(userID int) (int, error)
+	TouchLastUsed(id int) error
+	Delete(userID int, id int) (bool, error)
+	DeleteByUser(userID int) error
+}
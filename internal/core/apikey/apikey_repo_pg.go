@@ -0,0 +1,175 @@
+package apikey
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+)
+
+const (
+	akTable         = "api_keys"
+	akColID         = "id"
+	akColUserID     = "user_id"
+	akColPrefix     = "prefix"
+	akColLastUsedAt = "last_used_at"
+	akColCreatedAt  = "created_at"
+)
+
+// lastUsedResolution throttles the last used updates, a key used in a burst of requests
+// is only written once
+const lastUsedResolution = time.Minute
+
+type repoAPIKey struct {
+	ID         int          `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID     int          `gorm:"column:user_id"`
+	Name       string       `gorm:"column:name"`
+	Prefix     string       `gorm:"column:prefix"`
+	KeyHash    string       `gorm:"column:key_hash"`
+	Scopes     string       `gorm:"column:scopes"`
+	ExpiresAt  sql.NullTime `gorm:"column:expires_at"`
+	LastUsedAt sql.NullTime `gorm:"column:last_used_at"`
+	CreatedAt  time.Time    `gorm:"column:created_at"`
+}
+
+func (repoAPIKey) TableName() string {
+	return akTable
+}
+
+// APIKeyRepo represents the API key repository
+type APIKeyRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewAPIKeyRepo(pgClient *postgres.PGClient) *APIKeyRepo {
+	return &APIKeyRepo{
+		pgClient: pgClient,
+	}
+}
+
+func toRepoAPIKey(key APIKey) repoAPIKey {
+	rKey := repoAPIKey{
+		ID:        key.ID,
+		UserID:    key.UserID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   key.KeyHash,
+		Scopes:    strings.Join(key.Scopes, " "),
+		CreatedAt: time.Now(),
+	}
+	if key.ExpiresAt != nil {
+		rKey.ExpiresAt = sql.NullTime{Time: *key.ExpiresAt, Valid: true}
+	}
+
+	return rKey
+}
+
+func toEntityAPIKey(key repoAPIKey) APIKey {
+	entity := APIKey{
+		ID:        key.ID,
+		UserID:    key.UserID,
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		KeyHash:   key.KeyHash,
+		Scopes:    strings.Fields(key.Scopes),
+		CreatedAt: key.CreatedAt,
+	}
+	if key.ExpiresAt.Valid {
+		entity.ExpiresAt = &key.ExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		entity.LastUsedAt = &key.LastUsedAt.Time
+	}
+
+	return entity
+}
+
+func (repo *APIKeyRepo) Create(key APIKey) (APIKey, error) {
+
+	rKey := toRepoAPIKey(key)
+	if err := repo.pgClient.DB.Create(&rKey).Error; err != nil {
+		return APIKey{}, fmt.Errorf("api key create failed: %v", err)
+	}
+
+	return toEntityAPIKey(rKey), nil
+}
+
+func (repo *APIKeyRepo) GetByPrefix(prefix string) (APIKey, error) {
+
+	var rKey repoAPIKey
+	err := repo.pgClient.DB.Where(akColPrefix+" = ?", prefix).First(&rKey).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return APIKey{}, ErrAPIKeyNotFound
+	}
+	if err != nil {
+		return APIKey{}, fmt.Errorf("api key fetch failed: %v", err)
+	}
+
+	return toEntityAPIKey(rKey), nil
+}
+
+func (repo *APIKeyRepo) GetByUser(userID int) ([]APIKey, error) {
+
+	var rKeys []repoAPIKey
+	err := repo.pgClient.DB.Where(akColUserID+" = ?", userID).Order(akColCreatedAt).Find(&rKeys).Error
+	if err != nil {
+		return []APIKey{}, fmt.Errorf("api keys fetch failed: %v", err)
+	}
+
+	keys := make([]APIKey, 0, len(rKeys))
+	for _, k := range rKeys {
+		keys = append(keys, toEntityAPIKey(k))
+	}
+
+	return keys, nil
+}
+
+func (repo *APIKeyRepo) CountByUser(userID int) (int, error) {
+
+	var count int64
+	err := repo.pgClient.DB.Model(&repoAPIKey{}).Where(akColUserID+" = ?", userID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("api keys count failed: %v", err)
+	}
+
+	return int(count), nil
+}
+
+// TouchLastUsed records the use of the key, at most once per lastUsedResolution
+func (repo *APIKeyRepo) TouchLastUsed(id int) error {
+
+	now := time.Now()
+	err := repo.pgClient.DB.Model(&repoAPIKey{}).
+		Where(akColID+" = ? AND ("+akColLastUsedAt+" IS NULL OR "+akColLastUsedAt+" < ?)", id, now.Add(-lastUsedResolution)).
+		Update(akColLastUsedAt, now).Error
+	if err != nil {
+		return fmt.Errorf("api key last used update failed: %v", err)
+	}
+
+	return nil
+}
+
+// Delete deletes a key of the user, it reports false when the user has no such key
+func (repo *APIKeyRepo) Delete(userID int, id int) (bool, error) {
+
+	res := repo.pgClient.DB.Where(akColID+" = ? AND "+akColUserID+" = ?", id, userID).Delete(&repoAPIKey{})
+	if res.Error != nil {
+		return false, fmt.Errorf("api key delete failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// DeleteByUser deletes every key of the user
+func (repo *APIKeyRepo) DeleteByUser(userID int) error {
+
+	if err := repo.pgClient.DB.Where(akColUserID+" = ?", userID).Delete(&repoAPIKey{}).Error; err != nil {
+		return fmt.Errorf("api keys delete failed: %v", err)
+	}
+
+	return nil
+}
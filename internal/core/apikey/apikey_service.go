@@ -0,0 +1,247 @@
+package apikey
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/rbac"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/securetoken"
+)
+
+const (
+	// keyPrefix marks the personal API keys, it makes leaked keys easy to find in code and logs
+	keyPrefix = "uak_"
+	// entropy in bytes of the generated keys
+	keyBytes = 32
+	// length of the key prefix stored for the lookup, the marker and 8 random characters
+	lookupPrefixLength = len(keyPrefix) + 8
+	// maximum length of a key name
+	maxKeyNameLength = 100
+	// maximum number of keys of a user
+	maxKeysPerUser = 50
+)
+
+var (
+	ErrAPIKeyNotFound       = errors.New("api key: not found")
+	ErrInvalidAPIKey        = errors.New("api key: invalid or expired key")
+	ErrInvalidAPIKeyDetails = errors.New("api key: invalid name, scopes or expiry")
+	ErrAPIKeyLimit          = errors.New("api key: maximum number of keys reached")
+	ErrAPIKey               = errors.New("api key: operation failed")
+)
+
+// APIKeyServicePort represents the API key service port
+type APIKeyServicePort interface {
+	Create(userID int, name string, scopes []string, expiresAt *time.Time) (APIKey, string, error)
+	Get(userID int) ([]APIKey, error)
+	Delete(userID int, id int) error
+	RevokeAll(userID int) error
+	Authenticate(key string) (auth.Principal, error)
+}
+
+// APIKeyService manages the personal API keys and resolves them to the principal of their user
+type APIKeyService struct {
+	repo           APIKeyRepoPort
+	userRepo       user.UserRepoPort
+	rbacService    rbac.RBACServicePort
+	passwordExpiry user.PasswordExpiryConfig
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(repo APIKeyRepoPort, userRepo user.UserRepoPort, rbacService rbac.RBACServicePort, passwordExpiry user.PasswordExpiryConfig) *APIKeyService {
+	return &APIKeyService{
+		repo:           repo,
+		userRepo:       userRepo,
+		rbacService:    rbacService,
+		passwordExpiry: passwordExpiry,
+	}
+}
+
+// Create generates a new key for the user and returns it along with its record, the key is
+// only shown this once. The scopes must be permissions the user holds.
+func (service *APIKeyService) Create(userID int, name string, scopes []string, expiresAt *time.Time) (APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxKeyNameLength {
+		return APIKey{}, "", ErrInvalidAPIKeyDetails
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return APIKey{}, "", ErrInvalidAPIKeyDetails
+	}
+
+	roles, err := service.rbacService.GetUserRoles(userID)
+	if err != nil {
+		log.Printf("user roles fetch failed: %v", err)
+		return APIKey{}, "", ErrAPIKey
+	}
+	permissions := service.rbacService.Permissions(roles)
+	scopes = strings.Fields(strings.Join(scopes, " "))
+	for _, scope := range scopes {
+		if !contains(permissions, scope) {
+			return APIKey{}, "", ErrInvalidAPIKeyDetails
+		}
+	}
+
+	count, err := service.repo.CountByUser(userID)
+	if err != nil {
+		log.Printf("api keys count failed: %v", err)
+		return APIKey{}, "", ErrAPIKey
+	}
+	if count >= maxKeysPerUser {
+		return APIKey{}, "", ErrAPIKeyLimit
+	}
+
+	secret, err := securetoken.Generate(keyBytes)
+	if err != nil {
+		log.Printf("api key creation failed: %v", err)
+		return APIKey{}, "", ErrAPIKey
+	}
+	key := keyPrefix + secret
+
+	apiKey, err := service.repo.Create(APIKey{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:lookupPrefixLength],
+		KeyHash:   securetoken.Hash(key),
+		Scopes:    scopes,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		log.Printf("api key store failed: %v", err)
+		return APIKey{}, "", ErrAPIKey
+	}
+
+	return apiKey, key, nil
+}
+
+// Get lists the keys of the user
+func (service *APIKeyService) Get(userID int) ([]APIKey, error) {
+	keys, err := service.repo.GetByUser(userID)
+	if err != nil {
+		log.Printf("api keys fetch failed: %v", err)
+		return []APIKey{}, ErrAPIKey
+	}
+
+	return keys, nil
+}
+
+// Delete revokes a key of the user, requests made with it are rejected right away
+func (service *APIKeyService) Delete(userID int, id int) error {
+	deleted, err := service.repo.Delete(userID, id)
+	if err != nil {
+		log.Printf("api key delete failed: %v", err)
+		return ErrAPIKey
+	}
+	if !deleted {
+		return ErrAPIKeyNotFound
+	}
+
+	return nil
+}
+
+// RevokeAll deletes every key of the user, when all of its sessions are revoked
+func (service *APIKeyService) RevokeAll(userID int) error {
+	if err := service.repo.DeleteByUser(userID); err != nil {
+		log.Printf("api keys delete failed: %v", err)
+		return ErrAPIKey
+	}
+
+	return nil
+}
+
+// Authenticate resolves a key to the principal of its user, the same principal a login of
+// the user gets. The permissions of a scoped key are limited to its scopes.
+func (service *APIKeyService) Authenticate(key string) (auth.Principal, error) {
+	if !strings.HasPrefix(key, keyPrefix) || len(key) <= lookupPrefixLength {
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+
+	apiKey, err := service.repo.GetByPrefix(key[:lookupPrefixLength])
+	if err != nil {
+		if !errors.Is(err, ErrAPIKeyNotFound) {
+			log.Printf("api key fetch failed: %v", err)
+		}
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+
+	// the hashes have a fixed length, comparing them in constant time hides the key
+	if subtle.ConstantTimeCompare([]byte(securetoken.Hash(key)), []byte(apiKey.KeyHash)) != 1 {
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+
+	if apiKey.IsExpired(time.Now()) {
+		log.Printf("expired api key %d rejected", apiKey.ID)
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+
+	dbUser, err := service.userRepo.GetByID(apiKey.UserID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+	if err := auth.CheckAccountStatus(dbUser); err != nil {
+		log.Printf("api key %d of user %d rejected: %v", apiKey.ID, dbUser.ID, err)
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+
+	// the keys stop working with the password, like the logins, until it is changed
+	if service.passwordExpiry.IsExpired(dbUser, time.Now()) {
+		log.Printf("api key %d of user %d rejected: %v", apiKey.ID, dbUser.ID, auth.ErrPasswordExpired)
+		return auth.Principal{}, ErrInvalidAPIKey
+	}
+
+	roles, err := service.rbacService.GetUserRoles(dbUser.ID)
+	if err != nil {
+		log.Printf("user roles fetch failed: %v", err)
+		return auth.Principal{}, ErrAPIKey
+	}
+
+	if roles == nil {
+		roles = []string{}
+	}
+
+	permissions := service.rbacService.Permissions(roles)
+	if len(apiKey.Scopes) > 0 {
+		scoped := []string{}
+		for _, permission := range permissions {
+			if contains(apiKey.Scopes, permission) {
+				scoped = append(scoped, permission)
+			}
+		}
+		permissions = scoped
+	}
+
+	if err := service.repo.TouchLastUsed(apiKey.ID); err != nil {
+		log.Printf("api key last used update failed: %v", err)
+	}
+
+	principal := auth.Principal{
+		UserID:      dbUser.ID,
+		Username:    dbUser.Username,
+		Roles:       roles,
+		Permissions: permissions,
+		IssuedAt:    apiKey.CreatedAt,
+		Scope:       strings.Join(apiKey.Scopes, " "),
+		APIKeyID:    apiKey.ID,
+	}
+	if apiKey.ExpiresAt != nil {
+		principal.ExpiresAt = *apiKey.ExpiresAt
+	}
+
+	return principal, nil
+}
+
+// contains reports whether the value is part of the values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
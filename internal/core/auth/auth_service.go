@@ -3,77 +3,763 @@ package auth
 import (
 	"errors"
 	"log"
+	"strings"
+	"time"
+	"user-authentication/internal/core/mfa"
+	"user-authentication/internal/core/passkey"
+	"user-authentication/internal/core/rbac"
 	"user-authentication/internal/core/user"
 	"user-authentication/pkg/jwt"
 	"user-authentication/pkg/password"
+	"user-authentication/pkg/securetoken"
+	"user-authentication/pkg/webauthn"
 )
 
 // return errors for the authentication service layer
 var (
-	ErrIncorrectPwd       = errors.New("incorrect user password")
-	ErrTokenCreation      = errors.New("creating access token failed")
-	ErrInvalidAccessToken = errors.New("invalid access token: permission denied")
+	ErrIncorrectPwd         = errors.New("incorrect user password")
+	ErrTokenCreation        = errors.New("creating access token failed")
+	ErrInvalidAccessToken   = errors.New("invalid access token: permission denied")
+	ErrInvalidRefreshToken  = errors.New("invalid refresh token")
+	ErrRefreshTokenExpired  = errors.New("refresh token expired")
+	ErrRefreshTokenReused   = errors.New("refresh token reuse detected: session revoked")
+	ErrRefreshTokenRotation = errors.New("rotating refresh token failed")
+	ErrAccessTokenRevoked   = errors.New("access token revoked")
+	ErrLogout               = errors.New("logout failed")
+	ErrTokenRevocation      = errors.New("token revocation failed")
+	ErrUserInactive         = errors.New("user account is inactive")
+	ErrAccountLocked        = errors.New("user account is locked")
+	ErrAccountTempLocked    = errors.New("user account is temporarily locked after too many failed logins")
+	ErrInvalidMFAChallenge  = errors.New("invalid or expired mfa challenge")
+	ErrIncorrectMFACode     = errors.New("incorrect mfa code")
+	ErrPasswordExpired      = errors.New("password expired: change the password to continue")
 )
 
-// empty token to return on the user authentication failure
-const emptyToken = ""
+// token type returned to the clients along with the access token
+const tokenTypeBearer = "Bearer"
+
+// lifetime of the challenge returned by the password step of a login requiring a second factor
+const mfaChallengeExpiry = 5 * time.Minute
+
+// AuthTokens represents the token pair issued on a successful authentication
+type AuthTokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	// Scope is the scope granted to an OAuth client, empty for first-party logins
+	Scope string `json:"scope,omitempty"`
+	// IDToken is the OpenID Connect ID token, set when the openid scope was granted
+	IDToken string `json:"id_token,omitempty"`
+}
+
+// LoginResult represents the outcome of a successful password step, either the token pair
+// or, when the user enrolled a second factor, the challenge to present along with a code
+type LoginResult struct {
+	UserID       int
+	Tokens       AuthTokens
+	MFARequired  bool
+	MFAChallenge string
+	// lifetime in seconds of the MFA challenge
+	MFAExpiresIn int
+	// PasswordExpired is set when the password is past its maximum age, the tokens then only
+	// hold an access token limited to the password change
+	PasswordExpired bool
+	// PasswordExpiresAt warns about a password expiring within the warning period
+	PasswordExpiresAt *time.Time
+}
 
 // AuthServicePort represents the authentication service port
 type AuthServicePort interface {
-	Login(u user.User) (string, error)
+	Login(u user.User) (LoginResult, error)
+	VerifyMFA(challengeString string, code string) (LoginResult, error)
+	LoginWithPasskey(resp webauthn.AssertionResponse) (LoginResult, error)
+	LoginWithMagicLink(magicLinkTokenString string) (LoginResult, error)
+	Authenticate(u user.User) (LoginResult, error)
+	AuthenticateMFA(challengeString string, code string) (int, string, error)
+	StartClientSession(userID int, clientID string, scope string) (AuthTokens, error)
+	RefreshClientSession(refreshTokenString string, clientID string) (AuthTokens, error)
+	IssueClientToken(clientID string, scope string) (AuthTokens, error)
+	IntrospectToken(tokenString string) TokenInfo
+	RevokeClientToken(tokenString string, clientID string) error
+	Refresh(refreshTokenString string) (AuthTokens, error)
+	Logout(principal Principal, refreshTokenString string) error
+	LogoutAll(principal Principal) error
+	RevokeSessions(userID int) error
+	RevokeOtherSessions(userID int, tokenID string, sessionID string) error
+	RevokeClientSessions(clientID string) error
 	Create(u user.User) (user.User, error)
-	ValidateAccessToken(accessTokenString string) (bool, error)
+	ValidateAccessToken(accessTokenString string) (Principal, error)
+	JWKS() jwt.JWKS
+}
+
+// APIKeyRevokerPort revokes the API keys of a user along with its sessions
+type APIKeyRevokerPort interface {
+	RevokeAll(userID int) error
 }
 
 // AuthService represents the authentication service
 type AuthService struct {
-	config   jwt.AuthTokenConfig
-	userRepo user.UserRepoPort
+	config           jwt.AuthTokenConfig
+	userRepo         user.UserRepoPort
+	refreshTokenRepo RefreshTokenRepoPort
+	revocationStore  RevocationStorePort
+	rbacService      rbac.RBACServicePort
+	lockoutConfig    LockoutConfig
+
+	emailVerificationService EmailVerificationServicePort
+	mfaService               mfa.MFAServicePort
+	userTokenRepo            UserTokenRepoPort
+	passkeyService           passkey.PasskeyServicePort
+	passwordHasher           password.Hasher
+	passwordPolicy           *password.Policy
+	passwordExpiryConfig     user.PasswordExpiryConfig
+	apiKeyRevoker            APIKeyRevokerPort
 }
 
 // NewAuthService create a new authentication service to be used in the other layers
-func NewAuthService(config jwt.AuthTokenConfig, userRepo user.UserRepoPort) *AuthService {
+func NewAuthService(
+	config jwt.AuthTokenConfig,
+	userRepo user.UserRepoPort,
+	refreshTokenRepo RefreshTokenRepoPort,
+	revocationStore RevocationStorePort,
+	rbacService rbac.RBACServicePort,
+	lockoutConfig LockoutConfig,
+	emailVerificationService EmailVerificationServicePort,
+	mfaService mfa.MFAServicePort,
+	userTokenRepo UserTokenRepoPort,
+	passkeyService passkey.PasskeyServicePort,
+	passwordHasher password.Hasher,
+	passwordPolicy *password.Policy,
+	passwordExpiryConfig user.PasswordExpiryConfig,
+	apiKeyRevoker APIKeyRevokerPort,
+) *AuthService {
 	return &AuthService{
-		config:   config,
-		userRepo: userRepo,
+		config:           config,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocationStore:  revocationStore,
+		rbacService:      rbacService,
+		lockoutConfig:    lockoutConfig,
+
+		emailVerificationService: emailVerificationService,
+		mfaService:               mfaService,
+		userTokenRepo:            userTokenRepo,
+		passkeyService:           passkeyService,
+		passwordHasher:           passwordHasher,
+		passwordPolicy:           passwordPolicy,
+		passwordExpiryConfig:     passwordExpiryConfig,
+		apiKeyRevoker:            apiKeyRevoker,
 	}
 }
 
-// Login authenticates the user with username and password
-func (service *AuthService) Login(u user.User) (string, error) {
+// Login authenticates the user with username and password, users who enrolled a second
+// factor get an MFA challenge instead of the tokens
+func (service *AuthService) Login(u user.User) (LoginResult, error) {
+	dbUser, err := service.verifyPassword(u)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return service.completeFirstFactor(dbUser, true)
+}
+
+// Authenticate verifies the username and password like Login without starting a session,
+// used by the OAuth authorization endpoint which issues an authorization code instead
+func (service *AuthService) Authenticate(u user.User) (LoginResult, error) {
+	dbUser, err := service.verifyPassword(u)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return service.completeFirstFactor(dbUser, false)
+}
+
+// verifyPassword checks the account state and the password of the user, failures count
+// towards the account lockout
+func (service *AuthService) verifyPassword(u user.User) (user.User, error) {
 	// username and password validation
 	if u.Username == "" {
 		log.Println("user details validation failed: invalid username, cannot be empty")
-		return emptyToken, user.ErrInvalidUserDetails
+		return user.User{}, user.ErrInvalidUserDetails
 	}
 	if u.Password == "" {
 		log.Println("user details validation failed: invalid user password")
-		return emptyToken, user.ErrInvalidUserPwd
+		return user.User{}, user.ErrInvalidUserPwd
 	}
 
 	// fetching user details from the user repository layer
 	dbUser, err := service.userRepo.GetByUsername(u.Username)
 	if err != nil {
 		log.Printf("user fetch failed: %v", err)
-		return emptyToken, err
+		return user.User{}, err
+	}
+
+	// locked accounts are rejected before the password is even verified
+	if err := CheckAccountStatus(dbUser); err != nil {
+		log.Printf("login rejected for user %d: %v", dbUser.ID, err)
+		return user.User{}, err
 	}
 
 	// user password verification from the database and handler
-	isValid := password.VerifyPassword(u.Password, dbUser.PasswordHash)
+	isValid := service.passwordHasher.Verify(u.Password, dbUser.PasswordHash)
 	if !isValid {
 		log.Println("password verficiation: wrong password")
-		return emptyToken, ErrIncorrectPwd
+		service.registerFailedLogin(dbUser.ID)
+		return user.User{}, ErrIncorrectPwd
+	}
+
+	// hashes of an older algorithm or weaker parameters are upgraded while the password is at hand
+	if service.passwordHasher.NeedsRehash(dbUser.PasswordHash) {
+		service.rehashPassword(dbUser, u.Password)
+	}
+
+	// checked after the password so that the verification state is not disclosed to guessers
+	if err := service.emailVerificationService.CheckVerified(dbUser); err != nil {
+		log.Printf("login rejected for user %d: %v", dbUser.ID, err)
+		return user.User{}, err
+	}
+
+	return dbUser, nil
+}
+
+// rehashPassword stores a new hash of the verified password, failures are only logged since
+// the old hash keeps working
+func (service *AuthService) rehashPassword(dbUser user.User, plainPassword string) {
+	hashedPwd, err := service.passwordHasher.Hash(plainPassword)
+	if err != nil {
+		log.Printf("error hashing password %v", err)
+		return
+	}
+
+	if err := service.userRepo.RehashPassword(dbUser.ID, dbUser.PasswordHash, hashedPwd); err != nil {
+		log.Printf("password rehash failed for user %d: %v", dbUser.ID, err)
+		return
+	}
+
+	log.Printf("password hash of user %d upgraded", dbUser.ID)
+}
+
+// completeFirstFactor ends a successful first factor with an MFA challenge when the user
+// enrolled a second factor, otherwise with the tokens of a new session if withSession is set
+func (service *AuthService) completeFirstFactor(dbUser user.User, withSession bool) (LoginResult, error) {
+	mfaEnabled, err := service.mfaService.IsEnabled(dbUser.ID)
+	if err != nil {
+		log.Printf("mfa state fetch failed: %v", err)
+		return LoginResult{}, ErrTokenCreation
+	}
+
+	// the failed login counters are only reset once every factor succeeded, otherwise
+	// knowing the password would allow guessing codes without ever being locked out
+	if mfaEnabled {
+		return service.issueMFAChallenge(dbUser)
+	}
+
+	if dbUser.FailedLoginAttempts > 0 || dbUser.LockoutCount > 0 {
+		if err := service.userRepo.ResetFailedLogins(dbUser.ID); err != nil {
+			log.Printf("failed login reset failed: %v", err)
+		}
+	}
+
+	if !withSession {
+		if service.passwordExpiryConfig.IsExpired(dbUser, time.Now()) {
+			log.Printf("login rejected for user %d: %v", dbUser.ID, ErrPasswordExpired)
+			return LoginResult{}, ErrPasswordExpired
+		}
+
+		return LoginResult{UserID: dbUser.ID}, nil
+	}
+
+	return service.finishLogin(dbUser)
+}
+
+// finishLogin starts the session of a login once every factor succeeded. An expired password
+// only gets an access token limited to the password change, and no refresh token.
+func (service *AuthService) finishLogin(dbUser user.User) (LoginResult, error) {
+	now := time.Now()
+	if service.passwordExpiryConfig.IsExpired(dbUser, now) {
+		log.Printf("password of user %d expired, issuing a password change token", dbUser.ID)
+		tokens, err := service.issuePasswordChangeToken(dbUser)
+		if err != nil {
+			return LoginResult{}, err
+		}
+
+		return LoginResult{UserID: dbUser.ID, Tokens: tokens, PasswordExpired: true}, nil
+	}
+
+	tokens, err := service.startSession(dbUser)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return LoginResult{
+		UserID:            dbUser.ID,
+		Tokens:            tokens,
+		PasswordExpiresAt: service.passwordExpiryConfig.ExpiryWarning(dbUser, now),
+	}, nil
+}
+
+// issuePasswordChangeToken issues an access token only accepted by the password change route
+func (service *AuthService) issuePasswordChangeToken(dbUser user.User) (AuthTokens, error) {
+	authToken := jwt.NewAuthToken(service.config)
+	err := authToken.CreateForClient(dbUser.ID, dbUser.Username, []string{}, "", SCOPE_PASSWORD_CHANGE)
+	if err != nil {
+		log.Printf("password change token creation failed: %v", err)
+		return AuthTokens{}, ErrTokenCreation
+	}
+
+	return AuthTokens{
+		AccessToken: authToken.EncodedAccessToken,
+		TokenType:   tokenTypeBearer,
+		ExpiresIn:   int(service.config.AccessTokenTTL().Seconds()),
+		Scope:       SCOPE_PASSWORD_CHANGE,
+	}, nil
+}
+
+// LoginWithMagicLink exchanges the single-use token of a magic link for the tokens, the
+// link only proves control of the mailbox so users who enrolled a second factor still get
+// an MFA challenge
+func (service *AuthService) LoginWithMagicLink(magicLinkTokenString string) (LoginResult, error) {
+	if magicLinkTokenString == "" {
+		return LoginResult{}, ErrInvalidMagicLink
+	}
+
+	magicLinkToken, err := service.userTokenRepo.Consume(securetoken.Hash(magicLinkTokenString), PURPOSE_MAGIC_LINK)
+	if err != nil {
+		log.Printf("magic link token consume failed: %v", err)
+		return LoginResult{}, ErrInvalidMagicLink
+	}
+
+	dbUser, err := service.userRepo.GetByID(magicLinkToken.UserID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return LoginResult{}, err
+	}
+
+	// the link only logs in to the account while it still has the email it was sent to
+	if dbUser.Email != magicLinkToken.Email {
+		log.Printf("magic link rejected for user %d: email changed", dbUser.ID)
+		return LoginResult{}, ErrInvalidMagicLink
+	}
+
+	if err := CheckAccountStatus(dbUser); err != nil {
+		log.Printf("magic link login rejected for user %d: %v", dbUser.ID, err)
+		return LoginResult{}, err
+	}
+
+	// following the link proves control of the email
+	if !dbUser.IsEmailVerified() {
+		if _, err := service.userRepo.MarkEmailVerified(dbUser.ID, magicLinkToken.Email); err != nil {
+			log.Printf("email verification by magic link failed: %v", err)
+		}
+	}
+
+	return service.completeFirstFactor(dbUser, true)
+}
+
+// issueMFAChallenge stores a short lived challenge proving the password step succeeded
+func (service *AuthService) issueMFAChallenge(dbUser user.User) (LoginResult, error) {
+	challengeString, err := securetoken.Generate(securetoken.DefaultTokenBytes)
+	if err != nil {
+		log.Printf("mfa challenge creation failed: %v", err)
+		return LoginResult{}, ErrTokenCreation
+	}
+
+	_, err = service.userTokenRepo.Create(UserToken{
+		UserID:    dbUser.ID,
+		Purpose:   PURPOSE_MFA_CHALLENGE,
+		TokenHash: securetoken.Hash(challengeString),
+		ExpiresAt: time.Now().Add(mfaChallengeExpiry),
+	})
+	if err != nil {
+		log.Printf("mfa challenge store failed: %v", err)
+		return LoginResult{}, ErrTokenCreation
+	}
+
+	return LoginResult{
+		UserID:       dbUser.ID,
+		MFARequired:  true,
+		MFAChallenge: challengeString,
+		MFAExpiresIn: int(mfaChallengeExpiry.Seconds()),
+	}, nil
+}
+
+// VerifyMFA exchanges the challenge of the password step and a TOTP or recovery code for
+// the token pair, wrong codes count as failed logins
+func (service *AuthService) VerifyMFA(challengeString string, code string) (LoginResult, error) {
+	dbUser, _, err := service.verifyMFAChallenge(challengeString, code)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	return service.finishLogin(dbUser)
+}
+
+// AuthenticateMFA verifies the second factor like VerifyMFA without starting a session and
+// returns the id of the authenticated user and the factor the code was verified as
+func (service *AuthService) AuthenticateMFA(challengeString string, code string) (int, string, error) {
+	dbUser, factor, err := service.verifyMFAChallenge(challengeString, code)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if service.passwordExpiryConfig.IsExpired(dbUser, time.Now()) {
+		log.Printf("login rejected for user %d: %v", dbUser.ID, ErrPasswordExpired)
+		return 0, "", ErrPasswordExpired
+	}
+
+	return dbUser.ID, factor, nil
+}
+
+// verifyMFAChallenge consumes the challenge of the password step once the code is verified
+func (service *AuthService) verifyMFAChallenge(challengeString string, code string) (user.User, string, error) {
+	if challengeString == "" {
+		return user.User{}, "", ErrInvalidMFAChallenge
+	}
+
+	// the challenge survives wrong codes until it expires, the lockout bounds the guesses
+	challenge, err := service.userTokenRepo.Get(securetoken.Hash(challengeString), PURPOSE_MFA_CHALLENGE)
+	if err != nil {
+		log.Printf("mfa challenge lookup failed: %v", err)
+		return user.User{}, "", ErrInvalidMFAChallenge
+	}
+
+	dbUser, err := service.userRepo.GetByID(challenge.UserID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return user.User{}, "", err
+	}
+
+	if err := CheckAccountStatus(dbUser); err != nil {
+		log.Printf("mfa verification rejected for user %d: %v", dbUser.ID, err)
+		return user.User{}, "", err
+	}
+
+	factor, err := service.mfaService.Verify(dbUser.ID, code)
+	if err != nil {
+		log.Printf("mfa verification failed for user %d: %v", dbUser.ID, err)
+		service.registerFailedLogin(dbUser.ID)
+		return user.User{}, "", ErrIncorrectMFACode
+	}
+
+	// consuming is conditional so a challenge cannot start two sessions
+	if _, err := service.userTokenRepo.Consume(challenge.TokenHash, PURPOSE_MFA_CHALLENGE); err != nil {
+		log.Printf("mfa challenge consume failed: %v", err)
+		return user.User{}, "", ErrInvalidMFAChallenge
+	}
+
+	if err := service.userRepo.ResetFailedLogins(dbUser.ID); err != nil {
+		log.Printf("failed login reset failed: %v", err)
+	}
+
+	return dbUser, factor, nil
+}
+
+// LoginWithPasskey authenticates the user with a passkey assertion. The assertion requires
+// user verification on the authenticator, so it stands for both factors and no TOTP code is asked.
+func (service *AuthService) LoginWithPasskey(resp webauthn.AssertionResponse) (LoginResult, error) {
+	userID, err := service.passkeyService.FinishLogin(resp)
+	if err != nil {
+		return LoginResult{}, err
+	}
+
+	dbUser, err := service.userRepo.GetByID(userID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return LoginResult{}, err
+	}
+
+	if err := CheckAccountStatus(dbUser); err != nil {
+		log.Printf("passkey login rejected for user %d: %v", dbUser.ID, err)
+		return LoginResult{}, err
+	}
+
+	if err := service.emailVerificationService.CheckVerified(dbUser); err != nil {
+		log.Printf("passkey login rejected for user %d: %v", dbUser.ID, err)
+		return LoginResult{}, err
+	}
+
+	return service.finishLogin(dbUser)
+}
+
+// StartClientSession issues the tokens of a new session of the user bound to the OAuth
+// client, its refresh tokens can only be used by the same client
+func (service *AuthService) StartClientSession(userID int, clientID string, scope string) (AuthTokens, error) {
+	dbUser, err := service.userRepo.GetByID(userID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return AuthTokens{}, err
+	}
+
+	if err := CheckAccountStatus(dbUser); err != nil {
+		log.Printf("client session rejected for user %d: %v", dbUser.ID, err)
+		return AuthTokens{}, err
+	}
+
+	return service.startClientSession(dbUser, clientID, scope)
+}
+
+// IssueClientToken issues an access token to an OAuth client acting on its own behalf, the
+// client authenticates again to get a new one so no refresh token is issued
+func (service *AuthService) IssueClientToken(clientID string, scope string) (AuthTokens, error) {
+	authToken := jwt.NewAuthToken(service.config)
+	if err := authToken.CreateClientToken(clientID, scope); err != nil {
+		log.Printf("client access token creation failed: %v", err)
+		return AuthTokens{}, ErrTokenCreation
+	}
+
+	return AuthTokens{
+		AccessToken: authToken.EncodedAccessToken,
+		TokenType:   tokenTypeBearer,
+		ExpiresIn:   int(service.config.AccessTokenTTL().Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+// startSession issues the tokens of a new first-party session
+func (service *AuthService) startSession(dbUser user.User) (AuthTokens, error) {
+	return service.startClientSession(dbUser, "", "")
+}
+
+// startClientSession issues the tokens of a new session, a fresh login starts a new refresh token family
+func (service *AuthService) startClientSession(dbUser user.User, clientID string, scope string) (AuthTokens, error) {
+	familyID, err := securetoken.Generate(securetoken.DefaultTokenBytes)
+	if err != nil {
+		log.Printf("refresh token family creation failed: %v", err)
+		return AuthTokens{}, ErrTokenCreation
+	}
+
+	return service.issueTokens(dbUser, RefreshToken{FamilyID: familyID, ClientID: clientID, Scope: scope})
+}
+
+// Refresh exchanges a refresh token for a new token pair, the presented refresh token
+// is rotated on every use and replaying an already rotated token revokes its family
+func (service *AuthService) Refresh(refreshTokenString string) (AuthTokens, error) {
+	return service.refresh(refreshTokenString, "")
+}
+
+// RefreshClientSession rotates a refresh token issued to the OAuth client like Refresh
+func (service *AuthService) RefreshClientSession(refreshTokenString string, clientID string) (AuthTokens, error) {
+	return service.refresh(refreshTokenString, clientID)
+}
+
+// refresh rotates a refresh token of the session bound to the client, first-party
+// sessions have no client
+func (service *AuthService) refresh(refreshTokenString string, clientID string) (AuthTokens, error) {
+	if refreshTokenString == "" {
+		return AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	storedToken, err := service.refreshTokenRepo.GetByHash(securetoken.Hash(refreshTokenString))
+	if err != nil {
+		log.Printf("refresh token lookup failed: %v", err)
+		return AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	// a token only refreshes the session it was issued to, presenting it elsewhere does not rotate it
+	if storedToken.ClientID != clientID {
+		log.Printf("refresh token of client %q presented by client %q", storedToken.ClientID, clientID)
+		return AuthTokens{}, ErrInvalidRefreshToken
+	}
+
+	// an already rotated token being presented again means it has leaked
+	if storedToken.IsRevoked() {
+		service.revokeFamily(storedToken.FamilyID)
+		return AuthTokens{}, ErrRefreshTokenReused
+	}
+
+	if storedToken.IsExpired(time.Now()) {
+		return AuthTokens{}, ErrRefreshTokenExpired
+	}
+
+	// revoking is conditional so two concurrent refreshes cannot both succeed
+	revoked, err := service.refreshTokenRepo.Revoke(storedToken.ID)
+	if err != nil {
+		log.Printf("refresh token rotation failed: %v", err)
+		return AuthTokens{}, ErrRefreshTokenRotation
+	}
+	if !revoked {
+		service.revokeFamily(storedToken.FamilyID)
+		return AuthTokens{}, ErrRefreshTokenReused
+	}
+
+	dbUser, err := service.userRepo.GetByID(storedToken.UserID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return AuthTokens{}, err
+	}
+
+	if err := CheckAccountStatus(dbUser); err != nil {
+		log.Printf("refresh rejected for user %d: %v", dbUser.ID, err)
+		return AuthTokens{}, err
+	}
+
+	// the session ends with the password, a new login gets the password change token
+	if service.passwordExpiryConfig.IsExpired(dbUser, time.Now()) {
+		log.Printf("refresh rejected for user %d: %v", dbUser.ID, ErrPasswordExpired)
+		return AuthTokens{}, ErrPasswordExpired
+	}
+
+	return service.issueTokens(dbUser, storedToken)
+}
+
+// CheckAccountStatus rejects inactive and locked accounts
+func CheckAccountStatus(dbUser user.User) error {
+	if !dbUser.IsActive {
+		return ErrUserInactive
+	}
+	if dbUser.IsLocked {
+		return ErrAccountLocked
+	}
+	if dbUser.IsTemporarilyLocked(time.Now()) {
+		return ErrAccountTempLocked
+	}
+
+	return nil
+}
+
+// issueTokens creates the access token and a refresh token rotated from the parent, which
+// carries the family and the client binding of the session. Every successful authentication
+// flow ends here.
+func (service *AuthService) issueTokens(dbUser user.User, parent RefreshToken) (AuthTokens, error) {
+
+	// the roles are embedded in the access token, changes apply on the next issuance
+	roles, err := service.rbacService.GetUserRoles(dbUser.ID)
+	if err != nil {
+		log.Printf("user roles fetch failed: %v", err)
+		return AuthTokens{}, ErrTokenCreation
 	}
 
 	// creating a new accesstoken
 	authToken := jwt.NewAuthToken(service.config)
-	err = authToken.Create(dbUser.ID, u.Username)
+	err = authToken.CreateForSession(dbUser.ID, dbUser.Username, roles, parent.ClientID, parent.Scope, parent.FamilyID)
 	if err != nil {
 		log.Printf("access token creation failed: %v", err)
-		return emptyToken, ErrTokenCreation
+		return AuthTokens{}, ErrTokenCreation
+	}
+
+	// creating a new refreshtoken, only the hash is stored
+	refreshTokenString, err := securetoken.Generate(securetoken.DefaultTokenBytes)
+	if err != nil {
+		log.Printf("refresh token creation failed: %v", err)
+		return AuthTokens{}, ErrTokenCreation
 	}
 
-	return authToken.EncodedAccessToken, nil
+	_, err = service.refreshTokenRepo.Create(RefreshToken{
+		UserID:    dbUser.ID,
+		TokenHash: securetoken.Hash(refreshTokenString),
+		FamilyID:  parent.FamilyID,
+		ParentID:  parent.ID,
+		ClientID:  parent.ClientID,
+		Scope:     parent.Scope,
+		ExpiresAt: time.Now().Add(time.Duration(service.config.RefreshTokenExpiry) * time.Hour),
+	})
+	if err != nil {
+		log.Printf("refresh token store failed: %v", err)
+		return AuthTokens{}, ErrTokenCreation
+	}
+
+	return AuthTokens{
+		AccessToken:  authToken.EncodedAccessToken,
+		RefreshToken: refreshTokenString,
+		TokenType:    tokenTypeBearer,
+		ExpiresIn:    int(service.config.AccessTokenTTL().Seconds()),
+		Scope:        parent.Scope,
+	}, nil
+}
+
+// revokeFamily revokes the whole refresh token family after a reuse was detected
+func (service *AuthService) revokeFamily(familyID string) {
+	log.Printf("refresh token reuse detected, revoking family %s", familyID)
+	if err := service.refreshTokenRepo.RevokeFamily(familyID); err != nil {
+		log.Printf("refresh token family revoke failed: %v", err)
+	}
+}
+
+// Logout revokes the access token of the principal and, when given, the refresh token family it belongs to
+func (service *AuthService) Logout(principal Principal, refreshTokenString string) error {
+	// API keys have no access token to revoke, they are deleted instead
+	if principal.APIKeyID == 0 {
+		err := service.revocationStore.Revoke(principal.TokenID, principal.UserID, principal.ExpiresAt)
+		if err != nil {
+			log.Printf("access token revoke failed: %v", err)
+			return ErrLogout
+		}
+	}
+
+	if refreshTokenString == "" {
+		return nil
+	}
+
+	storedToken, err := service.refreshTokenRepo.GetByHash(securetoken.Hash(refreshTokenString))
+	if err != nil {
+		log.Printf("refresh token lookup failed: %v", err)
+		return ErrInvalidRefreshToken
+	}
+
+	// a user can only end their own sessions
+	if storedToken.UserID != principal.UserID {
+		return ErrInvalidRefreshToken
+	}
+
+	if err := service.refreshTokenRepo.RevokeFamily(storedToken.FamilyID); err != nil {
+		log.Printf("refresh token family revoke failed: %v", err)
+		return ErrLogout
+	}
+
+	return nil
+}
+
+// LogoutAll revokes every access and refresh token issued to the principal so far, and its API keys
+func (service *AuthService) LogoutAll(principal Principal) error {
+	return service.RevokeSessions(principal.UserID)
+}
+
+// RevokeSessions revokes every access and refresh token issued to the user so far and its
+// API keys, used on logout from all sessions and after password resets
+func (service *AuthService) RevokeSessions(userID int) error {
+	return service.RevokeOtherSessions(userID, "", "")
+}
+
+// RevokeOtherSessions revokes every access and refresh token issued to the user so far, except
+// the access token and the session of the caller, used after password changes
+func (service *AuthService) RevokeOtherSessions(userID int, tokenID string, sessionID string) error {
+	cutoff := TokenCutoff{At: time.Now(), KeepSessionID: sessionID, KeepTokenID: tokenID}
+	if err := service.revocationStore.RevokeAllForUser(userID, cutoff); err != nil {
+		log.Printf("user access tokens revoke failed: %v", err)
+		return ErrLogout
+	}
+
+	if err := service.refreshTokenRepo.RevokeByUser(userID, sessionID); err != nil {
+		log.Printf("user refresh tokens revoke failed: %v", err)
+		return ErrLogout
+	}
+
+	// the keys would otherwise outlive the sessions, e.g. after a password reset
+	if err := service.apiKeyRevoker.RevokeAll(userID); err != nil {
+		log.Printf("user api keys revoke failed: %v", err)
+		return ErrLogout
+	}
+
+	return nil
+}
+
+// RevokeClientSessions revokes every access and refresh token issued to the client so far, used
+// when the client is deleted or its secret rotated. The iat claim only has a second precision,
+// so tokens issued within the second of the cutoff are revoked as well.
+func (service *AuthService) RevokeClientSessions(clientID string) error {
+	now := time.Now()
+	if err := service.revocationStore.RevokeAllForClient(clientID, now, now.Add(service.config.AccessTokenTTL())); err != nil {
+		log.Printf("client access tokens revoke failed: %v", err)
+		return ErrLogout
+	}
+
+	if err := service.refreshTokenRepo.RevokeByClient(clientID); err != nil {
+		log.Printf("client refresh tokens revoke failed: %v", err)
+		return ErrLogout
+	}
+
+	return nil
 }
 
 func (service *AuthService) Create(u user.User) (user.User, error) {
@@ -81,7 +767,15 @@ func (service *AuthService) Create(u user.User) (user.User, error) {
 		return user.User{}, user.ErrInvalidUserDetails
 	}
 
-	hashedPwd, err := password.HashPassword(u.Password)
+	if err := service.passwordPolicy.Check(u.Password, u.Username, u.Email); err != nil {
+		if !errors.Is(err, password.ErrPolicyViolation) {
+			log.Printf("password policy check failed: %v", err)
+			return user.User{}, user.ErrUserCreation
+		}
+		return user.User{}, err
+	}
+
+	hashedPwd, err := service.passwordHasher.Hash(u.Password)
 	if err != nil {
 		log.Printf("error hashing password %v", err)
 
@@ -89,30 +783,109 @@ func (service *AuthService) Create(u user.User) (user.User, error) {
 	}
 
 	u.PasswordHash = hashedPwd
+	// the account state is never taken from the signup request
+	u.IsActive = true
+	u.IsLocked = false
 
 	u, err = service.userRepo.Create(u)
 	if err != nil {
 		return user.User{}, err
 	}
 
+	if err := service.rbacService.AssignDefaultRole(u.ID); err != nil {
+		log.Printf("default role assign failed: %v", err)
+
+		return user.User{}, err
+	}
+
+	// the account is created anyway, the user can ask for a new link when the delivery fails
+	if err := service.emailVerificationService.Send(u); err != nil {
+		log.Printf("email verification send failed for user %d: %v", u.ID, err)
+	}
+
 	return u, nil
 }
 
-// ValidateAccessToken validates the accesstoken on the Authorization Header
-func (service *AuthService) ValidateAccessToken(accessTokenString string) (bool, error) {
+// ValidateAccessToken validates the accesstoken on the Authorization Header, checking the
+// signature, the expiry and the revocation state, and returns the caller's principal
+func (service *AuthService) ValidateAccessToken(accessTokenString string) (Principal, error) {
 
 	// validates the access token for empty string
 	if accessTokenString == "" {
-		return false, ErrInvalidAccessToken
+		return Principal{}, ErrInvalidAccessToken
 	}
 
 	// validates the access token
 	authToken := jwt.NewAuthToken(service.config)
-	isValid, err := authToken.Validate(accessTokenString)
+	claims, err := authToken.Validate(accessTokenString)
 	if err != nil {
 		log.Println("access token validation failed")
-		return isValid, err
+		return Principal{}, err
+	}
+
+	principal := toPrincipal(claims)
+	if principal.IsClient() {
+		// a client acting on its own behalf holds no roles, its scope lists its permissions
+		principal.Permissions = strings.Fields(principal.Scope)
+	} else if principal.IsDelegated() {
+		// a client acting for a user holds the permissions of the user it was granted, if any
+		principal.Permissions = scopedPermissions(service.rbacService.Permissions(principal.Roles), principal)
+	} else if principal.IsPasswordChangeOnly() {
+		principal.Permissions = []string{}
+	} else {
+		principal.Permissions = service.rbacService.Permissions(principal.Roles)
+	}
+
+	// validates the access token against the revocation list
+	if service.revocationStore.IsRevoked(principal.TokenID, principal.UserID, principal.ClientID, principal.SessionID, principal.IssuedAt) {
+		log.Printf("access token %s is revoked", principal.TokenID)
+		return Principal{}, ErrAccessTokenRevoked
+	}
+
+	return principal, nil
+}
+
+// scopedPermissions returns the permissions also granted by the scope of the principal
+func scopedPermissions(permissions []string, principal Principal) []string {
+	granted := []string{}
+	for _, permission := range permissions {
+		if principal.HasScope(permission) {
+			granted = append(granted, permission)
+		}
+	}
+
+	return granted
+}
+
+// toPrincipal maps the access token claims to the principal of the request
+func toPrincipal(claims *jwt.AccessTokenClaims) Principal {
+	principal := Principal{
+		UserID:    claims.UserID,
+		Username:  claims.UserName,
+		Roles:     claims.Roles,
+		TokenID:   claims.ID,
+		SessionID: claims.SessionID,
+		ClientID:  claims.ClientID,
+		Scope:     claims.Scope,
+	}
+	if principal.Roles == nil {
+		principal.Roles = []string{}
+	}
+	if claims.IssuedAt != nil {
+		principal.IssuedAt = claims.IssuedAt.Time
+	}
+	if claims.ExpiresAt != nil {
+		principal.ExpiresAt = claims.ExpiresAt.Time
+	}
+
+	return principal
+}
+
+// JWKS returns the public keys used to verify the access tokens
+func (service *AuthService) JWKS() jwt.JWKS {
+	if service.config.KeyRing == nil {
+		return jwt.NewJWKS()
 	}
 
-	return isValid, nil
+	return jwt.NewJWKS(service.config.KeyRing.Keys()...)
 }
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/notifier"
+	"user-authentication/pkg/securetoken"
+)
+
+// default lifetime of an email verification token when none is configured
+const defaultEmailVerificationTokenExpiry = 24 * time.Hour
+
+var (
+	ErrEmailNotVerified         = errors.New("user email is not verified")
+	ErrInvalidVerificationToken = errors.New("invalid or expired email verification token")
+	ErrEmailVerification        = errors.New("email verification failed")
+)
+
+// EmailVerificationConfig represents the email verification settings
+type EmailVerificationConfig struct {
+	// RequireVerified rejects the logins of users who have not verified their email yet
+	RequireVerified bool
+	// TokenExpiry is the lifetime in hours of a verification token
+	TokenExpiry int
+	// VerifyURL is the page the verification link points to, the token is appended as the token query parameter
+	VerifyURL string
+}
+
+// tokenTTL returns the lifetime of a verification token
+func (config EmailVerificationConfig) tokenTTL() time.Duration {
+	if config.TokenExpiry <= 0 {
+		return defaultEmailVerificationTokenExpiry
+	}
+
+	return time.Duration(config.TokenExpiry) * time.Hour
+}
+
+// EmailVerificationServicePort represents the email verification service port
+type EmailVerificationServicePort interface {
+	Send(u user.User) error
+	Resend(email string) error
+	Verify(verificationTokenString string) error
+	CheckVerified(u user.User) error
+}
+
+// EmailVerificationService lets users prove control of their email through a
+// single-use token delivered by the notifier
+type EmailVerificationService struct {
+	config        EmailVerificationConfig
+	userRepo      user.UserRepoPort
+	userTokenRepo UserTokenRepoPort
+	notifier      notifier.Notifier
+}
+
+// NewEmailVerificationService creates a new email verification service
+func NewEmailVerificationService(
+	config EmailVerificationConfig,
+	userRepo user.UserRepoPort,
+	userTokenRepo UserTokenRepoPort,
+	notifier notifier.Notifier,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		config:        config,
+		userRepo:      userRepo,
+		userTokenRepo: userTokenRepo,
+		notifier:      notifier,
+	}
+}
+
+// Send issues a verification token for the user and sends the verification link,
+// previously sent links stop working
+func (service *EmailVerificationService) Send(u user.User) error {
+	msg, err := service.issueVerificationLink(u)
+	if err != nil {
+		log.Printf("email verification link creation failed: %v", err)
+		return ErrEmailVerification
+	}
+
+	sendInBackground(service.notifier, msg)
+
+	return nil
+}
+
+// issueVerificationLink stores a new verification token for the user and returns the message
+// carrying its link
+func (service *EmailVerificationService) issueVerificationLink(u user.User) (notifier.Message, error) {
+	verificationTokenString, err := securetoken.Generate(securetoken.DefaultTokenBytes)
+	if err != nil {
+		return notifier.Message{}, fmt.Errorf("email verification token creation: %w", err)
+	}
+
+	if err := service.userTokenRepo.DeleteByUser(u.ID, PURPOSE_EMAIL_VERIFICATION); err != nil {
+		log.Printf("previous email verification tokens delete failed: %v", err)
+	}
+
+	_, err = service.userTokenRepo.Create(UserToken{
+		UserID:    u.ID,
+		Purpose:   PURPOSE_EMAIL_VERIFICATION,
+		TokenHash: securetoken.Hash(verificationTokenString),
+		Email:     u.Email,
+		ExpiresAt: time.Now().Add(service.config.tokenTTL()),
+	})
+	if err != nil {
+		return notifier.Message{}, fmt.Errorf("email verification token store for user %d: %w", u.ID, err)
+	}
+
+	return notifier.Message{
+		To:      u.Email,
+		Subject: "Verify your email",
+		Body: fmt.Sprintf(
+			"Welcome %s, please verify your email.\n\nVerify your email: %s\n\nThe link expires in %v.",
+			u.Username, tokenLink(service.config.VerifyURL, verificationTokenString), service.config.tokenTTL(),
+		),
+	}, nil
+}
+
+// Resend sends a new verification link to the account of the email. Unknown emails and
+// verified accounts are silently ignored so that the caller cannot enumerate accounts.
+func (service *EmailVerificationService) Resend(email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return user.ErrInvalidUserDetails
+	}
+
+	// the account lookup, the token store and the delivery only run for unverified accounts,
+	// they're done in the background so that the response is the same for every email
+	runInBackground("email verification request", func(ctx context.Context) error {
+		return service.resendVerificationLink(ctx, email)
+	})
+
+	return nil
+}
+
+// resendVerificationLink issues a new verification token for the account of the email and sends its link
+func (service *EmailVerificationService) resendVerificationLink(ctx context.Context, email string) error {
+	dbUser, err := service.userRepo.GetByEmail(email)
+	if err != nil {
+		log.Printf("email verification requested for unknown email: %v", err)
+		return nil
+	}
+
+	if dbUser.IsEmailVerified() || !dbUser.IsActive {
+		return nil
+	}
+
+	msg, err := service.issueVerificationLink(dbUser)
+	if err != nil {
+		return err
+	}
+
+	return service.notifier.Send(ctx, msg)
+}
+
+// Verify consumes the verification token and marks the email of its user as verified
+func (service *EmailVerificationService) Verify(verificationTokenString string) error {
+	if verificationTokenString == "" {
+		return ErrInvalidVerificationToken
+	}
+
+	verificationToken, err := service.userTokenRepo.Consume(securetoken.Hash(verificationTokenString), PURPOSE_EMAIL_VERIFICATION)
+	if err != nil {
+		log.Printf("email verification token consume failed: %v", err)
+		return ErrInvalidVerificationToken
+	}
+
+	// the token only verifies the email it was sent to
+	verified, err := service.userRepo.MarkEmailVerified(verificationToken.UserID, verificationToken.Email)
+	if err != nil {
+		log.Printf("email verification failed: %v", err)
+		return ErrEmailVerification
+	}
+	if !verified {
+		log.Printf("email verification rejected for user %d: email changed or already verified", verificationToken.UserID)
+		return ErrInvalidVerificationToken
+	}
+
+	return nil
+}
+
+// CheckVerified rejects users with an unverified email when verification is required
+func (service *EmailVerificationService) CheckVerified(u user.User) error {
+	if service.config.RequireVerified && !u.IsEmailVerified() {
+		return ErrEmailNotVerified
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"log"
+	"time"
+)
+
+// LockoutConfig represents the account lockout policy applied on failed logins
+type LockoutConfig struct {
+	// Threshold is the number of consecutive failed logins starting a temporary lockout, 0 disables the lockout
+	Threshold int
+	// BaseDuration is the duration in seconds of the first lockout, it doubles on every following lockout
+	BaseDuration int
+	// MaxDuration caps the duration in seconds of a temporary lockout, a temporary lockout never exceeds a year
+	MaxDuration int
+	// PermanentAfter is the number of lockouts after which the account is locked until an admin unlocks it, 0 disables it
+	PermanentAfter int
+}
+
+// ceiling of a temporary lockout, applied when no lower maximum is configured so that the
+// doubling can't overflow
+const maxLockoutDuration = 365 * 24 * time.Hour
+
+// lockoutDuration returns the duration of the n-th consecutive lockout
+func (config LockoutConfig) lockoutDuration(lockout int) time.Duration {
+	duration := time.Duration(config.BaseDuration) * time.Second
+	maxDuration := time.Duration(config.MaxDuration) * time.Second
+	if maxDuration <= 0 || maxDuration > maxLockoutDuration {
+		maxDuration = maxLockoutDuration
+	}
+
+	for i := 1; i < lockout && duration < maxDuration; i++ {
+		duration *= 2
+	}
+
+	if duration > maxDuration {
+		return maxDuration
+	}
+
+	return duration
+}
+
+// registerFailedLogin counts a failed login and locks the account once the threshold is reached
+func (service *AuthService) registerFailedLogin(userID int) {
+	attempts, lockouts, err := service.userRepo.IncrementFailedLogins(userID)
+	if err != nil {
+		log.Printf("failed login registration failed: %v", err)
+		return
+	}
+
+	policy := service.lockoutConfig
+	if policy.Threshold <= 0 || attempts < policy.Threshold {
+		return
+	}
+
+	lockout := lockouts + 1
+	permanent := policy.PermanentAfter > 0 && lockout >= policy.PermanentAfter
+	lockedUntil := time.Now().Add(policy.lockoutDuration(lockout))
+
+	if err := service.userRepo.LockOut(userID, lockedUntil, permanent); err != nil {
+		log.Printf("user lockout failed: %v", err)
+		return
+	}
+
+	if permanent {
+		log.Printf("user %d locked permanently after %d lockouts", userID, lockout)
+	} else {
+		log.Printf("user %d locked until %v after %d failed logins", userID, lockedUntil, attempts)
+	}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   LockoutConfig
+		lockout  int
+		duration time.Duration
+	}{
+		{"first lockout", LockoutConfig{BaseDuration: 60, MaxDuration: 3600}, 1, time.Minute},
+		{"doubled", LockoutConfig{BaseDuration: 60, MaxDuration: 3600}, 3, 4 * time.Minute},
+		{"capped by the maximum", LockoutConfig{BaseDuration: 60, MaxDuration: 3600}, 10, time.Hour},
+		{"no maximum", LockoutConfig{BaseDuration: 60}, 5, 16 * time.Minute},
+		{"no maximum after many lockouts", LockoutConfig{BaseDuration: 60}, 100, maxLockoutDuration},
+		{"maximum beyond the ceiling", LockoutConfig{BaseDuration: 60, MaxDuration: 1 << 40}, 100, maxLockoutDuration},
+		{"no base duration", LockoutConfig{MaxDuration: 3600}, 100, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if duration := tt.config.lockoutDuration(tt.lockout); duration != tt.duration {
+				t.Errorf("lockoutDuration(%d) = %v, want %v", tt.lockout, duration, tt.duration)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/notifier"
+	"user-authentication/pkg/securetoken"
+)
+
+// default lifetime of a magic link token when none is configured
+const defaultMagicLinkTokenExpiry = 10 * time.Minute
+
+var (
+	ErrMagicLinkDisabled = errors.New("magic link login is disabled")
+	ErrInvalidMagicLink  = errors.New("invalid or expired magic link")
+)
+
+// MagicLinkConfig represents the passwordless email link login settings
+type MagicLinkConfig struct {
+	// Enabled turns the magic link login on, it is off by default
+	Enabled bool
+	// TokenExpiry is the lifetime in minutes of a magic link token
+	TokenExpiry int
+	// CallbackURL is the page the magic link points to, the token is appended as the token query parameter
+	CallbackURL string
+}
+
+// tokenTTL returns the lifetime of a magic link token
+func (config MagicLinkConfig) tokenTTL() time.Duration {
+	if config.TokenExpiry <= 0 {
+		return defaultMagicLinkTokenExpiry
+	}
+
+	return time.Duration(config.TokenExpiry) * time.Minute
+}
+
+// MagicLinkServicePort represents the magic link service port
+type MagicLinkServicePort interface {
+	Request(email string) error
+}
+
+// MagicLinkService sends single-use login links by email, the links are exchanged for
+// tokens by AuthService.LoginWithMagicLink
+type MagicLinkService struct {
+	config        MagicLinkConfig
+	userRepo      user.UserRepoPort
+	userTokenRepo UserTokenRepoPort
+	notifier      notifier.Notifier
+}
+
+// NewMagicLinkService creates a new magic link service
+func NewMagicLinkService(
+	config MagicLinkConfig,
+	userRepo user.UserRepoPort,
+	userTokenRepo UserTokenRepoPort,
+	notifier notifier.Notifier,
+) *MagicLinkService {
+	return &MagicLinkService{
+		config:        config,
+		userRepo:      userRepo,
+		userTokenRepo: userTokenRepo,
+		notifier:      notifier,
+	}
+}
+
+// Request sends a login link to the account of the email. Unknown emails and inactive or
+// locked accounts are silently ignored so that the caller cannot enumerate accounts.
+func (service *MagicLinkService) Request(email string) error {
+	if !service.config.Enabled {
+		return ErrMagicLinkDisabled
+	}
+
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return user.ErrInvalidUserDetails
+	}
+
+	// the account lookup, the token store and the delivery only run for existing accounts,
+	// they're done in the background so that the response is the same for every email
+	runInBackground("magic link request", func(ctx context.Context) error {
+		return service.sendLoginLink(ctx, email)
+	})
+
+	return nil
+}
+
+// sendLoginLink stores a new login token for the account of the email and sends its link
+func (service *MagicLinkService) sendLoginLink(ctx context.Context, email string) error {
+	dbUser, err := service.userRepo.GetByEmail(email)
+	if err != nil {
+		log.Printf("magic link requested for unknown email: %v", err)
+		return nil
+	}
+
+	if err := CheckAccountStatus(dbUser); err != nil {
+		log.Printf("magic link requested for user %d: %v", dbUser.ID, err)
+		return nil
+	}
+
+	magicLinkTokenString, err := securetoken.Generate(securetoken.DefaultTokenBytes)
+	if err != nil {
+		return fmt.Errorf("magic link token creation: %w", err)
+	}
+
+	// only the latest link is valid
+	if err := service.userTokenRepo.DeleteByUser(dbUser.ID, PURPOSE_MAGIC_LINK); err != nil {
+		log.Printf("previous magic link tokens delete failed: %v", err)
+	}
+
+	_, err = service.userTokenRepo.Create(UserToken{
+		UserID:    dbUser.ID,
+		Purpose:   PURPOSE_MAGIC_LINK,
+		TokenHash: securetoken.Hash(magicLinkTokenString),
+		Email:     dbUser.Email,
+		ExpiresAt: time.Now().Add(service.config.tokenTTL()),
+	})
+	if err != nil {
+		return fmt.Errorf("magic link token store for user %d: %w", dbUser.ID, err)
+	}
+
+	return service.notifier.Send(ctx, notifier.Message{
+		To:      dbUser.Email,
+		Subject: "Your login link",
+		Body: fmt.Sprintf(
+			"A login link was requested for your account %s.\n\nLog in: %s\n\nThe link can be used once and expires in %v. If you did not request it, ignore this message.",
+			dbUser.Username, tokenLink(service.config.CallbackURL, magicLinkTokenString), service.config.tokenTTL(),
+		),
+	})
+}
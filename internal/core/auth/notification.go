@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"user-authentication/pkg/notifier"
+)
+
+// timeout of the delivery of a notification
+const notificationTimeout = 30 * time.Second
+
+// sendInBackground delivers the message without blocking the request, failures are only logged
+func sendInBackground(n notifier.Notifier, msg notifier.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+
+		if err := n.Send(ctx, msg); err != nil {
+			log.Printf("notification %q to %s failed: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// runInBackground runs the work of a request answered the same whether the account exists
+// or not, so that neither its duration nor its failures reach the caller. Failures are only logged.
+func runInBackground(task string, work func(ctx context.Context) error) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), notificationTimeout)
+		defer cancel()
+
+		if err := work(ctx); err != nil {
+			log.Printf("%s failed: %v", task, err)
+		}
+	}()
+}
+
+// tokenLink returns the link of the page carrying the single-use token as the token
+// query parameter, the bare token is returned when no page is configured
+func tokenLink(pageURL string, tokenString string) string {
+	if pageURL == "" {
+		return tokenString
+	}
+
+	separator := "?"
+	if strings.Contains(pageURL, "?") {
+		separator = "&"
+	}
+
+	return pageURL + separator + "token=" + url.QueryEscape(tokenString)
+}
@@ -0,0 +1,226 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/notifier"
+	"user-authentication/pkg/password"
+	"user-authentication/pkg/securetoken"
+)
+
+// default lifetime of a password reset token when none is configured
+const defaultPasswordResetTokenExpiry = 30 * time.Minute
+
+var (
+	ErrInvalidResetToken = errors.New("invalid or expired password reset token")
+	ErrPasswordReset     = errors.New("password reset failed")
+)
+
+// PasswordResetConfig represents the password reset settings
+type PasswordResetConfig struct {
+	// TokenExpiry is the lifetime in minutes of a reset token
+	TokenExpiry int
+	// ResetURL is the page the reset link points to, the token is appended as the token query parameter
+	ResetURL string
+}
+
+// tokenTTL returns the lifetime of a reset token
+func (config PasswordResetConfig) tokenTTL() time.Duration {
+	if config.TokenExpiry <= 0 {
+		return defaultPasswordResetTokenExpiry
+	}
+
+	return time.Duration(config.TokenExpiry) * time.Minute
+}
+
+// PasswordResetServicePort represents the password reset service port
+type PasswordResetServicePort interface {
+	Forgot(email string) error
+	Reset(resetTokenString string, newPassword string) error
+}
+
+// PasswordResetService lets users who forgot their password set a new one through a
+// single-use token delivered by the notifier
+type PasswordResetService struct {
+	config        PasswordResetConfig
+	userRepo      user.UserRepoPort
+	userTokenRepo UserTokenRepoPort
+	authService   AuthServicePort
+	notifier      notifier.Notifier
+	hasher        password.Hasher
+	policy        *password.Policy
+	history       user.PasswordHistoryServicePort
+}
+
+// NewPasswordResetService creates a new password reset service
+func NewPasswordResetService(
+	config PasswordResetConfig,
+	userRepo user.UserRepoPort,
+	userTokenRepo UserTokenRepoPort,
+	authService AuthServicePort,
+	notifier notifier.Notifier,
+	hasher password.Hasher,
+	policy *password.Policy,
+	history user.PasswordHistoryServicePort,
+) *PasswordResetService {
+	return &PasswordResetService{
+		config:        config,
+		userRepo:      userRepo,
+		userTokenRepo: userTokenRepo,
+		authService:   authService,
+		notifier:      notifier,
+		hasher:        hasher,
+		policy:        policy,
+		history:       history,
+	}
+}
+
+// Forgot sends a password reset link to the account of the email. Unknown emails and
+// inactive accounts are silently ignored so that the caller cannot enumerate accounts.
+func (service *PasswordResetService) Forgot(email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return user.ErrInvalidUserDetails
+	}
+
+	// the account lookup, the token store and the delivery only run for existing accounts,
+	// they're done in the background so that the response is the same for every email
+	runInBackground("password reset request", func(ctx context.Context) error {
+		return service.sendResetLink(ctx, email)
+	})
+
+	return nil
+}
+
+// sendResetLink stores a new reset token for the account of the email and sends its link
+func (service *PasswordResetService) sendResetLink(ctx context.Context, email string) error {
+	dbUser, err := service.userRepo.GetByEmail(email)
+	if err != nil {
+		log.Printf("password reset requested for unknown email: %v", err)
+		return nil
+	}
+
+	if !dbUser.IsActive {
+		log.Printf("password reset requested for inactive user %d", dbUser.ID)
+		return nil
+	}
+
+	resetTokenString, err := securetoken.Generate(securetoken.DefaultTokenBytes)
+	if err != nil {
+		return fmt.Errorf("password reset token creation: %w", err)
+	}
+
+	// only the latest reset link is valid
+	if err := service.userTokenRepo.DeleteByUser(dbUser.ID, PURPOSE_PASSWORD_RESET); err != nil {
+		log.Printf("previous password reset tokens delete failed: %v", err)
+	}
+
+	_, err = service.userTokenRepo.Create(UserToken{
+		UserID:    dbUser.ID,
+		Purpose:   PURPOSE_PASSWORD_RESET,
+		TokenHash: securetoken.Hash(resetTokenString),
+		ExpiresAt: time.Now().Add(service.config.tokenTTL()),
+	})
+	if err != nil {
+		return fmt.Errorf("password reset token store for user %d: %w", dbUser.ID, err)
+	}
+
+	return service.notifier.Send(ctx, notifier.Message{
+		To:      dbUser.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"A password reset was requested for your account %s.\n\nReset your password: %s\n\nThe link expires in %v. If you did not request it, ignore this message.",
+			dbUser.Username, tokenLink(service.config.ResetURL, resetTokenString), service.config.tokenTTL(),
+		),
+	})
+}
+
+// Reset consumes the reset token and sets the new password, every session of the user
+// is revoked since the old password may have been compromised
+func (service *PasswordResetService) Reset(resetTokenString string, newPassword string) error {
+	if resetTokenString == "" {
+		return ErrInvalidResetToken
+	}
+	if newPassword == "" {
+		return user.ErrInvalidUserPwd
+	}
+
+	// the token is only consumed once the new password is accepted, the user can fix it and retry
+	tokenHash := securetoken.Hash(resetTokenString)
+	resetToken, err := service.userTokenRepo.Get(tokenHash, PURPOSE_PASSWORD_RESET)
+	if err != nil {
+		log.Printf("password reset token fetch failed: %v", err)
+		return ErrInvalidResetToken
+	}
+
+	dbUser, err := service.userRepo.GetByID(resetToken.UserID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return ErrPasswordReset
+	}
+
+	if err := service.policy.Check(newPassword, dbUser.Username, dbUser.Email); err != nil {
+		if !errors.Is(err, password.ErrPolicyViolation) {
+			log.Printf("password policy check failed: %v", err)
+			return ErrPasswordReset
+		}
+		return err
+	}
+
+	currentHash, err := service.userRepo.GetPasswordHash(resetToken.UserID)
+	if err != nil {
+		log.Printf("password hash fetch failed: %v", err)
+		return ErrPasswordReset
+	}
+	if service.hasher.Verify(newPassword, currentHash) {
+		return user.ErrPasswordUnchanged
+	}
+	if err := service.history.CheckReuse(resetToken.UserID, newPassword); err != nil {
+		if errors.Is(err, user.ErrPasswordReused) {
+			return err
+		}
+		return ErrPasswordReset
+	}
+
+	resetToken, err = service.userTokenRepo.Consume(tokenHash, PURPOSE_PASSWORD_RESET)
+	if err != nil {
+		log.Printf("password reset token consume failed: %v", err)
+		return ErrInvalidResetToken
+	}
+
+	hashedPwd, err := service.hasher.Hash(newPassword)
+	if err != nil {
+		log.Printf("error hashing password %v", err)
+		return ErrPasswordReset
+	}
+
+	if err := service.userRepo.UpdatePassword(resetToken.UserID, hashedPwd); err != nil {
+		log.Printf("password update failed: %v", err)
+		return ErrPasswordReset
+	}
+
+	if err := service.history.Prune(resetToken.UserID); err != nil {
+		log.Printf("password history prune failed for user %d: %v", resetToken.UserID, err)
+	}
+
+	if err := service.userTokenRepo.DeleteByUser(resetToken.UserID, PURPOSE_PASSWORD_RESET); err != nil {
+		log.Printf("password reset tokens delete failed: %v", err)
+	}
+
+	// proving control of the mailbox clears a temporary lockout, an admin lock stays
+	if err := service.userRepo.ResetFailedLogins(resetToken.UserID); err != nil {
+		log.Printf("failed login reset failed: %v", err)
+	}
+
+	if err := service.authService.RevokeSessions(resetToken.UserID); err != nil {
+		log.Printf("sessions revoke after password reset failed for user %d: %v", resetToken.UserID, err)
+		return ErrPasswordReset
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// SCOPE_PASSWORD_CHANGE is the scope of the access token issued on a login with an expired
+// password, it only lets the user change the password
+const SCOPE_PASSWORD_CHANGE = "password_change"
+
+// Principal represents the authenticated caller of a request
+type Principal struct {
+	UserID      int      `json:"user_id"`
+	Username    string   `json:"username"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions"`
+	TokenID     string   `json:"token_id"`
+	// SessionID is the refresh token family of the login, empty for API keys and limited tokens
+	SessionID string    `json:"session_id,omitempty"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	// ClientID and Scope are set when the token was issued to an OAuth client
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// APIKeyID is set when the request was authenticated with a personal API key
+	APIKeyID int `json:"api_key_id,omitempty"`
+}
+
+// IsClient reports whether the token was issued to an OAuth client acting on its own behalf
+// through the client credentials grant, such a principal carries no user
+func (principal Principal) IsClient() bool {
+	return principal.UserID == 0 && principal.ClientID != ""
+}
+
+// IsDelegated reports whether the token was issued to an OAuth client on behalf of a user,
+// such a principal only holds the permissions of the user granted by its scope
+func (principal Principal) IsDelegated() bool {
+	return principal.UserID != 0 && principal.ClientID != ""
+}
+
+// IsAPIKey reports whether the principal was authenticated with an API key instead of a token
+func (principal Principal) IsAPIKey() bool {
+	return principal.APIKeyID != 0
+}
+
+// IsPasswordChangeOnly reports whether the token was issued on a login with an expired
+// password, first-party tokens carry no other scope
+func (principal Principal) IsPasswordChangeOnly() bool {
+	return principal.ClientID == "" && principal.HasScope(SCOPE_PASSWORD_CHANGE)
+}
+
+// HasScope reports whether the scope was granted to the token
+func (principal Principal) HasScope(scope string) bool {
+	for _, s := range strings.Fields(principal.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasPermission reports whether one of the principal roles grants the permission
+func (principal Principal) HasPermission(permission string) bool {
+	for _, p := range principal.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+
+	return false
+}
+
+// principalContextKey is the context.Context key of the principal
+type principalContextKey struct{}
+
+// WithPrincipal returns a copy of the context carrying the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the principal stored in the context
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(Principal)
+
+	return principal, ok
+}
@@ -0,0 +1,29 @@
+package auth
+
+import "time"
+
+// RefreshToken represents a stored refresh token, only the hash of the token is persisted
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	FamilyID  string
+	ParentID  int
+	// ClientID is the OAuth client the token was issued to, empty for first-party logins
+	ClientID string
+	// Scope is the scope granted to the OAuth client
+	Scope     string
+	ExpiresAt time.Time
+	RevokedAt time.Time
+	CreatedAt time.Time
+}
+
+// IsRevoked reports whether the refresh token was already rotated or revoked
+func (token RefreshToken) IsRevoked() bool {
+	return !token.RevokedAt.IsZero()
+}
+
+// IsExpired reports whether the refresh token is past its expiry
+func (token RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(token.ExpiresAt)
+}
@@ -0,0 +1,10 @@
+package auth
+
+type RefreshTokenRepoPort interface {
+	Create(token RefreshToken) (RefreshToken, error)
+	GetByHash(tokenHash string) (RefreshToken, error)
+	Revoke(tokenID int) (bool, error)
+	RevokeFamily(familyID string) error
+	RevokeByUser(userID int, keepFamilyID string) error
+	RevokeByClient(clientID string) error
+}
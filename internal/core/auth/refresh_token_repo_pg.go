@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"user-authentication/internal/postgres"
+)
+
+const (
+	rtTable        = "refresh_tokens"
+	rtColID        = "id"
+	rtColTokenHash = "token_hash"
+	rtColFamilyID  = "family_id"
+	rtColUserID    = "user_id"
+	rtColClientID  = "client_id"
+	rtColRevokedAt = "revoked_at"
+)
+
+type repoRefreshToken struct {
+	ID        int            `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    int            `gorm:"column:user_id"`
+	TokenHash string         `gorm:"column:token_hash"`
+	FamilyID  string         `gorm:"column:family_id"`
+	ParentID  sql.NullInt64  `gorm:"column:parent_id"`
+	ClientID  sql.NullString `gorm:"column:client_id"`
+	Scope     sql.NullString `gorm:"column:scope"`
+	ExpiresAt time.Time      `gorm:"column:expires_at"`
+	RevokedAt sql.NullTime   `gorm:"column:revoked_at"`
+	CreatedAt time.Time      `gorm:"column:created_at"`
+}
+
+func (repoRefreshToken) TableName() string {
+	return rtTable
+}
+
+// RefreshTokenRepo represents the refresh token repository
+type RefreshTokenRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewRefreshTokenRepo(pgClient *postgres.PGClient) *RefreshTokenRepo {
+	return &RefreshTokenRepo{
+		pgClient: pgClient,
+	}
+}
+
+func toRepoRefreshToken(token RefreshToken) repoRefreshToken {
+	return repoRefreshToken{
+		ID:        token.ID,
+		UserID:    token.UserID,
+		TokenHash: token.TokenHash,
+		FamilyID:  token.FamilyID,
+		ParentID:  sql.NullInt64{Int64: int64(token.ParentID), Valid: token.ParentID > 0},
+		ClientID:  sql.NullString{String: token.ClientID, Valid: token.ClientID != ""},
+		Scope:     sql.NullString{String: token.Scope, Valid: token.ClientID != ""},
+		ExpiresAt: token.ExpiresAt,
+		RevokedAt: sql.NullTime{Time: token.RevokedAt, Valid: !token.RevokedAt.IsZero()},
+		CreatedAt: time.Now(),
+	}
+}
+
+func toEntityRefreshToken(token repoRefreshToken) RefreshToken {
+	return RefreshToken{
+		ID:        token.ID,
+		UserID:    token.UserID,
+		TokenHash: token.TokenHash,
+		FamilyID:  token.FamilyID,
+		ParentID:  int(token.ParentID.Int64),
+		ClientID:  token.ClientID.String,
+		Scope:     token.Scope.String,
+		ExpiresAt: token.ExpiresAt,
+		RevokedAt: token.RevokedAt.Time,
+		CreatedAt: token.CreatedAt,
+	}
+}
+
+func (repo *RefreshTokenRepo) Create(token RefreshToken) (RefreshToken, error) {
+
+	rToken := toRepoRefreshToken(token)
+	if err := repo.pgClient.DB.Create(&rToken).Error; err != nil {
+		return RefreshToken{}, err
+	}
+
+	return toEntityRefreshToken(rToken), nil
+}
+
+func (repo *RefreshTokenRepo) GetByHash(tokenHash string) (RefreshToken, error) {
+
+	var rToken repoRefreshToken
+	err := repo.pgClient.DB.Where(rtColTokenHash+" = ?", tokenHash).First(&rToken).Error
+	if err != nil {
+		return RefreshToken{}, fmt.Errorf("refresh token fetch failed: %v", err)
+	}
+
+	return toEntityRefreshToken(rToken), nil
+}
+
+// Revoke marks a single refresh token as revoked, it reports false when the token
+// was already revoked so that concurrent rotations of the same token can be detected
+func (repo *RefreshTokenRepo) Revoke(tokenID int) (bool, error) {
+
+	res := repo.pgClient.DB.Model(&repoRefreshToken{}).
+		Where(rtColID+" = ? AND "+rtColRevokedAt+" IS NULL", tokenID).
+		Update(rtColRevokedAt, time.Now())
+	if res.Error != nil {
+		return false, fmt.Errorf("refresh token revoke failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// RevokeFamily revokes every active refresh token that shares the family ID
+func (repo *RefreshTokenRepo) RevokeFamily(familyID string) error {
+
+	res := repo.pgClient.DB.Model(&repoRefreshToken{}).
+		Where(rtColFamilyID+" = ? AND "+rtColRevokedAt+" IS NULL", familyID).
+		Update(rtColRevokedAt, time.Now())
+	if res.Error != nil {
+		return fmt.Errorf("refresh token family revoke failed: %v", res.Error)
+	}
+
+	return nil
+}
+
+// RevokeByUser revokes every active refresh token of the user, except the ones of the
+// kept family when given
+func (repo *RefreshTokenRepo) RevokeByUser(userID int, keepFamilyID string) error {
+
+	query := repo.pgClient.DB.Model(&repoRefreshToken{}).
+		Where(rtColUserID+" = ? AND "+rtColRevokedAt+" IS NULL", userID)
+	if keepFamilyID != "" {
+		query = query.Where(rtColFamilyID+" <> ?", keepFamilyID)
+	}
+
+	res := query.Update(rtColRevokedAt, time.Now())
+	if res.Error != nil {
+		return fmt.Errorf("user refresh tokens revoke failed: %v", res.Error)
+	}
+
+	return nil
+}
+
+// RevokeByClient revokes every active refresh token issued to the client
+func (repo *RefreshTokenRepo) RevokeByClient(clientID string) error {
+
+	res := repo.pgClient.DB.Model(&repoRefreshToken{}).
+		Where(rtColClientID+" = ? AND "+rtColRevokedAt+" IS NULL", clientID).
+		Update(rtColRevokedAt, time.Now())
+	if res.Error != nil {
+		return fmt.Errorf("client refresh tokens revoke failed: %v", res.Error)
+	}
+
+	return nil
+}
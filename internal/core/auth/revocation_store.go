@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// default interval of the background sync when none is configured
+const defaultRevocationSyncInterval = 30 * time.Second
+
+// RevocationStorePort represents the access token revocation store port
+type RevocationStorePort interface {
+	Revoke(jti string, userID int, expiresAt time.Time) error
+	RevokeAllForUser(userID int, cutoff TokenCutoff) error
+	RevokeAllForClient(clientID string, cutoff time.Time, expiresAt time.Time) error
+	IsRevoked(jti string, userID int, clientID string, sessionID string, issuedAt time.Time) bool
+}
+
+// RevocationStore keeps the revoked access tokens in memory, backed by the revoked
+// token repository. Entries written by other replicas are picked up on the next sync.
+type RevocationStore struct {
+	repo       RevokedTokenRepoPort
+	mu         sync.RWMutex
+	revoked    map[string]time.Time
+	validAfter map[int]TokenCutoff
+	// clientValidAfter holds the cutoffs of the deleted or rotated clients
+	clientValidAfter map[string]time.Time
+}
+
+// NewRevocationStore creates a new revocation store, Load must be called before use
+func NewRevocationStore(repo RevokedTokenRepoPort) *RevocationStore {
+	return &RevocationStore{
+		repo:             repo,
+		revoked:          map[string]time.Time{},
+		validAfter:       map[int]TokenCutoff{},
+		clientValidAfter: map[string]time.Time{},
+	}
+}
+
+// Load replaces the in-memory cache with the active entries of the repository
+func (store *RevocationStore) Load() error {
+	tokens, err := store.repo.GetActive()
+	if err != nil {
+		return err
+	}
+
+	validAfter, err := store.repo.GetTokensValidAfter()
+	if err != nil {
+		return err
+	}
+
+	clientValidAfter, err := store.repo.GetClientTokensValidAfter()
+	if err != nil {
+		return err
+	}
+
+	revoked := make(map[string]time.Time, len(tokens))
+	for _, t := range tokens {
+		revoked[t.JTI] = t.ExpiresAt
+	}
+
+	store.mu.Lock()
+	store.revoked = revoked
+	store.validAfter = validAfter
+	store.clientValidAfter = clientValidAfter
+	store.mu.Unlock()
+
+	return nil
+}
+
+// Revoke revokes a single access token until its expiry
+func (store *RevocationStore) Revoke(jti string, userID int, expiresAt time.Time) error {
+	err := store.repo.Create(RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	store.revoked[jti] = expiresAt
+	store.mu.Unlock()
+
+	return nil
+}
+
+// RevokeAllForUser revokes every access token of the user issued before the cutoff, except the
+// kept session and token. The iat claim only has a second precision, so tokens issued within the
+// second of the cutoff are revoked as well, the kept ones are exempted by their session or jti.
+func (store *RevocationStore) RevokeAllForUser(userID int, cutoff TokenCutoff) error {
+	if err := store.repo.SetTokensValidAfter(userID, cutoff); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	store.validAfter[userID] = cutoff
+	store.mu.Unlock()
+
+	return nil
+}
+
+// RevokeAllForClient revokes every access token issued to the client before the cutoff, on its own
+// behalf or for a user. The cutoff is kept until expiresAt, when the last of these tokens has expired.
+func (store *RevocationStore) RevokeAllForClient(clientID string, cutoff time.Time, expiresAt time.Time) error {
+	if err := store.repo.SetClientTokensValidAfter(clientID, cutoff, expiresAt); err != nil {
+		return err
+	}
+
+	store.mu.Lock()
+	store.clientValidAfter[clientID] = cutoff
+	store.mu.Unlock()
+
+	return nil
+}
+
+// IsRevoked reports whether the access token was revoked individually, by a logout from all
+// devices, or along with every token of its client
+func (store *RevocationStore) IsRevoked(jti string, userID int, clientID string, sessionID string, issuedAt time.Time) bool {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	if _, ok := store.revoked[jti]; ok {
+		return true
+	}
+
+	if cutoff, ok := store.validAfter[userID]; ok && cutoff.Revokes(jti, sessionID, issuedAt) {
+		return true
+	}
+
+	if cutoff, ok := store.clientValidAfter[clientID]; ok && clientID != "" && issuedAt.Before(cutoff) {
+		return true
+	}
+
+	return false
+}
+
+// purgeExpired removes the expired entries from the repository and the cache
+func (store *RevocationStore) purgeExpired() {
+	purged, err := store.repo.DeleteExpired()
+	if err != nil {
+		log.Printf("revoked access tokens purge failed: %v", err)
+		return
+	}
+	if purged > 0 {
+		log.Printf("purged %d expired revoked access tokens", purged)
+	}
+
+	now := time.Now()
+	store.mu.Lock()
+	for jti, expiresAt := range store.revoked {
+		if !now.Before(expiresAt) {
+			delete(store.revoked, jti)
+		}
+	}
+	store.mu.Unlock()
+}
+
+// StartSync periodically purges expired entries and reloads the cache in the background,
+// the returned function stops the sync
+func (store *RevocationStore) StartSync(interval time.Duration) func() {
+	if interval <= 0 {
+		interval = defaultRevocationSyncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				store.purgeExpired()
+				if err := store.Load(); err != nil {
+					log.Printf("revocation store sync failed: %v", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
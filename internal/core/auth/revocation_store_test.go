@@ -0,0 +1,81 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRevokeAllForUser(t *testing.T) {
+	store := NewRevocationStore(&fakeRevokedTokenRepo{})
+
+	// the cutoff falls in the middle of a second, like a logout from all devices would
+	at := time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+	if err := store.RevokeAllForUser(42, TokenCutoff{At: at, KeepSessionID: "kept-session", KeepTokenID: "kept-jti"}); err != nil {
+		t.Fatalf("RevokeAllForUser() failed: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		jti       string
+		userID    int
+		sessionID string
+		issuedAt  time.Time
+		revoked   bool
+	}{
+		{"issued a second before", "jti", 42, "session", at.Add(-time.Second).Truncate(time.Second), true},
+		{"issued earlier in the same second", "jti", 42, "session", at.Truncate(time.Second), true},
+		{"issued the next second", "jti", 42, "session", at.Add(time.Second).Truncate(time.Second), false},
+		{"kept session", "jti", 42, "kept-session", at.Truncate(time.Second), false},
+		{"kept token", "kept-jti", 42, "", at.Truncate(time.Second), false},
+		{"other user", "jti", 7, "session", at.Truncate(time.Second), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if revoked := store.IsRevoked(tt.jti, tt.userID, "", tt.sessionID, tt.issuedAt); revoked != tt.revoked {
+				t.Errorf("IsRevoked() = %v, want %v", revoked, tt.revoked)
+			}
+		})
+	}
+}
+
+func TestRevokeAllForClient(t *testing.T) {
+	repo := &fakeRevokedTokenRepo{}
+	store := NewRevocationStore(repo)
+
+	at := time.Date(2026, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+	if err := store.RevokeAllForClient("nightly-export", at, at.Add(time.Hour)); err != nil {
+		t.Fatalf("RevokeAllForClient() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		userID   int
+		clientID string
+		issuedAt time.Time
+		revoked  bool
+	}{
+		{"client token issued before", 0, "nightly-export", at.Add(-time.Minute), true},
+		{"delegated token issued before", 42, "nightly-export", at.Add(-time.Minute), true},
+		{"issued earlier in the same second", 0, "nightly-export", at.Truncate(time.Second), true},
+		{"issued the next second", 0, "nightly-export", at.Add(time.Second).Truncate(time.Second), false},
+		{"other client", 0, "dashboard", at.Add(-time.Minute), false},
+		{"first-party token", 42, "", at.Add(-time.Minute), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if revoked := store.IsRevoked("jti", tt.userID, tt.clientID, "session", tt.issuedAt); revoked != tt.revoked {
+				t.Errorf("IsRevoked() = %v, want %v", revoked, tt.revoked)
+			}
+		})
+	}
+
+	// a reload from the repository keeps the cutoff
+	if err := store.Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !store.IsRevoked("jti", 0, "nightly-export", "", at.Add(-time.Minute)) {
+		t.Error("IsRevoked() after reload = false, want true")
+	}
+}
@@ -0,0 +1,33 @@
+package auth
+
+import "time"
+
+// RevokedToken represents an access token revoked before its expiry, UserID is 0 for the
+// tokens of a client acting on its own behalf
+type RevokedToken struct {
+	JTI       string
+	UserID    int
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
+// TokenCutoff revokes the access tokens of a user issued before At, except the kept ones
+type TokenCutoff struct {
+	At time.Time
+	// KeepSessionID is the session whose tokens stay valid, empty when every session ended
+	KeepSessionID string
+	// KeepTokenID is the access token that stays valid, e.g. of a caller without a session
+	KeepTokenID string
+}
+
+// Revokes reports whether the cutoff revokes the access token
+func (cutoff TokenCutoff) Revokes(jti string, sessionID string, issuedAt time.Time) bool {
+	if !issuedAt.Before(cutoff.At) {
+		return false
+	}
+	if cutoff.KeepSessionID != "" && sessionID == cutoff.KeepSessionID {
+		return false
+	}
+
+	return cutoff.KeepTokenID == "" || jti != cutoff.KeepTokenID
+}
@@ -0,0 +1,13 @@
+package auth
+
+import "time"
+
+type RevokedTokenRepoPort interface {
+	Create(token RevokedToken) error
+	GetActive() ([]RevokedToken, error)
+	DeleteExpired() (int64, error)
+	SetTokensValidAfter(userID int, cutoff TokenCutoff) error
+	GetTokensValidAfter() (map[int]TokenCutoff, error)
+	SetClientTokensValidAfter(clientID string, validAfter time.Time, expiresAt time.Time) error
+	GetClientTokensValidAfter() (map[string]time.Time, error)
+}
@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm/clause"
+)
+
+const (
+	ratTable        = "revoked_access_tokens"
+	ratColExpiresAt = "expires_at"
+
+	ctcTable               = "client_token_cutoffs"
+	ctcColClientID         = "client_id"
+	ctcColTokensValidAfter = "tokens_valid_after"
+	ctcColExpiresAt        = "expires_at"
+
+	uaTable                      = "user_accounts"
+	uaColID                      = "id"
+	uaColTokensValidAfter        = "tokens_valid_after"
+	uaColTokensValidAfterSession = "tokens_valid_after_keep_session"
+	uaColTokensValidAfterToken   = "tokens_valid_after_keep_token"
+)
+
+type repoRevokedToken struct {
+	JTI       string        `gorm:"column:jti;primaryKey"`
+	UserID    sql.NullInt64 `gorm:"column:user_id"`
+	ExpiresAt time.Time     `gorm:"column:expires_at"`
+	RevokedAt time.Time     `gorm:"column:revoked_at"`
+}
+
+func (repoRevokedToken) TableName() string {
+	return ratTable
+}
+
+// repoUserCutoff is the projection of user_accounts used for logout from all devices
+type repoUserCutoff struct {
+	ID               int          `gorm:"column:id"`
+	TokensValidAfter sql.NullTime `gorm:"column:tokens_valid_after"`
+	KeepSessionID    string       `gorm:"column:tokens_valid_after_keep_session"`
+	KeepTokenID      string       `gorm:"column:tokens_valid_after_keep_token"`
+}
+
+// repoClientCutoff is the cutoff of the access tokens of a deleted or rotated client
+type repoClientCutoff struct {
+	ClientID         string    `gorm:"column:client_id;primaryKey"`
+	TokensValidAfter time.Time `gorm:"column:tokens_valid_after"`
+	ExpiresAt        time.Time `gorm:"column:expires_at"`
+}
+
+func (repoClientCutoff) TableName() string {
+	return ctcTable
+}
+
+// RevokedTokenRepo represents the revoked access token repository
+type RevokedTokenRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewRevokedTokenRepo(pgClient *postgres.PGClient) *RevokedTokenRepo {
+	return &RevokedTokenRepo{
+		pgClient: pgClient,
+	}
+}
+
+// toRepoRevokedToken maps the revoked token, the tokens of a client without a user are stored
+// with a NULL user_id since the column references user_accounts
+func toRepoRevokedToken(token RevokedToken) repoRevokedToken {
+	return repoRevokedToken{
+		JTI:       token.JTI,
+		UserID:    sql.NullInt64{Int64: int64(token.UserID), Valid: token.UserID != 0},
+		ExpiresAt: token.ExpiresAt,
+		RevokedAt: time.Now(),
+	}
+}
+
+func toEntityRevokedToken(token repoRevokedToken) RevokedToken {
+	return RevokedToken{
+		JTI:       token.JTI,
+		UserID:    int(token.UserID.Int64),
+		ExpiresAt: token.ExpiresAt,
+		RevokedAt: token.RevokedAt,
+	}
+}
+
+func (repo *RevokedTokenRepo) Create(token RevokedToken) error {
+
+	rToken := toRepoRevokedToken(token)
+	// revoking an already revoked token is a no-op
+	err := repo.pgClient.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&rToken).Error
+	if err != nil {
+		return fmt.Errorf("access token revoke failed: %v", err)
+	}
+
+	return nil
+}
+
+func (repo *RevokedTokenRepo) GetActive() ([]RevokedToken, error) {
+
+	var rTokens []repoRevokedToken
+	err := repo.pgClient.DB.Where(ratColExpiresAt+" > ?", time.Now()).Find(&rTokens).Error
+	if err != nil {
+		return []RevokedToken{}, fmt.Errorf("revoked access tokens fetch failed: %v", err)
+	}
+
+	tokens := make([]RevokedToken, 0, len(rTokens))
+	for _, t := range rTokens {
+		tokens = append(tokens, toEntityRevokedToken(t))
+	}
+
+	return tokens, nil
+}
+
+func (repo *RevokedTokenRepo) DeleteExpired() (int64, error) {
+
+	now := time.Now()
+	res := repo.pgClient.DB.Where(ratColExpiresAt+" <= ?", now).Delete(&repoRevokedToken{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("expired revoked access tokens purge failed: %v", res.Error)
+	}
+
+	// the client cutoffs are purged along, once every token they revoke has expired
+	clientRes := repo.pgClient.DB.Where(ctcColExpiresAt+" <= ?", now).Delete(&repoClientCutoff{})
+	if clientRes.Error != nil {
+		return res.RowsAffected, fmt.Errorf("expired client token cutoffs purge failed: %v", clientRes.Error)
+	}
+
+	return res.RowsAffected + clientRes.RowsAffected, nil
+}
+
+func (repo *RevokedTokenRepo) SetTokensValidAfter(userID int, cutoff TokenCutoff) error {
+
+	res := repo.pgClient.DB.Table(uaTable).
+		Where(uaColID+" = ?", userID).
+		Updates(map[string]interface{}{
+			uaColTokensValidAfter:        cutoff.At,
+			uaColTokensValidAfterSession: cutoff.KeepSessionID,
+			uaColTokensValidAfterToken:   cutoff.KeepTokenID,
+		})
+	if res.Error != nil {
+		return fmt.Errorf("tokens valid after update failed: %v", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+
+	return nil
+}
+
+func (repo *RevokedTokenRepo) GetTokensValidAfter() (map[int]TokenCutoff, error) {
+
+	var cutoffs []repoUserCutoff
+	err := repo.pgClient.DB.Table(uaTable).
+		Select(uaColID, uaColTokensValidAfter, uaColTokensValidAfterSession, uaColTokensValidAfterToken).
+		Where(uaColTokensValidAfter + " IS NOT NULL").
+		Find(&cutoffs).Error
+	if err != nil {
+		return map[int]TokenCutoff{}, fmt.Errorf("tokens valid after fetch failed: %v", err)
+	}
+
+	validAfter := make(map[int]TokenCutoff, len(cutoffs))
+	for _, c := range cutoffs {
+		validAfter[c.ID] = TokenCutoff{
+			At:            c.TokensValidAfter.Time,
+			KeepSessionID: c.KeepSessionID,
+			KeepTokenID:   c.KeepTokenID,
+		}
+	}
+
+	return validAfter, nil
+}
+
+func (repo *RevokedTokenRepo) SetClientTokensValidAfter(clientID string, validAfter time.Time, expiresAt time.Time) error {
+
+	cutoff := repoClientCutoff{
+		ClientID:         clientID,
+		TokensValidAfter: validAfter,
+		ExpiresAt:        expiresAt,
+	}
+	err := repo.pgClient.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: ctcColClientID}},
+		DoUpdates: clause.AssignmentColumns([]string{ctcColTokensValidAfter, ctcColExpiresAt}),
+	}).Create(&cutoff).Error
+	if err != nil {
+		return fmt.Errorf("client tokens valid after update failed: %v", err)
+	}
+
+	return nil
+}
+
+func (repo *RevokedTokenRepo) GetClientTokensValidAfter() (map[string]time.Time, error) {
+
+	var cutoffs []repoClientCutoff
+	err := repo.pgClient.DB.Where(ctcColExpiresAt+" > ?", time.Now()).Find(&cutoffs).Error
+	if err != nil {
+		return map[string]time.Time{}, fmt.Errorf("client tokens valid after fetch failed: %v", err)
+	}
+
+	validAfter := make(map[string]time.Time, len(cutoffs))
+	for _, c := range cutoffs {
+		validAfter[c.ClientID] = c.TokensValidAfter
+	}
+
+	return validAfter, nil
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"log"
+	"strconv"
+	"time"
+	"user-authentication/pkg/securetoken"
+)
+
+// TokenInfo represents the state of a token as reported by the introspection endpoint, RFC 7662
+type TokenInfo struct {
+	Active    bool   `json:"active"`
+	Subject   string `json:"sub,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+}
+
+// IntrospectToken reports whether an access or refresh token can still be used. Besides the
+// checks of the token itself, the revocation state and the account of the user are taken
+// into account, so that tokens of deactivated or locked accounts are reported inactive. Any
+// failure reports the token inactive.
+func (service *AuthService) IntrospectToken(tokenString string) TokenInfo {
+	if tokenString == "" {
+		return TokenInfo{}
+	}
+
+	// access tokens are validated locally, including the revocation list
+	if principal, err := service.ValidateAccessToken(tokenString); err == nil {
+		info := TokenInfo{
+			Active:    true,
+			Subject:   strconv.Itoa(principal.UserID),
+			Scope:     principal.Scope,
+			ClientID:  principal.ClientID,
+			Username:  principal.Username,
+			TokenType: tokenTypeBearer,
+			ExpiresAt: principal.ExpiresAt.Unix(),
+			IssuedAt:  principal.IssuedAt.Unix(),
+		}
+		if principal.IsClient() {
+			info.Subject = principal.ClientID
+			return info
+		}
+		if !service.accountActive(principal.UserID) {
+			return TokenInfo{}
+		}
+
+		return info
+	}
+
+	storedToken, err := service.refreshTokenRepo.GetByHash(securetoken.Hash(tokenString))
+	if err != nil {
+		// unknown tokens are inactive, the lookup error is not reported to the caller
+		return TokenInfo{}
+	}
+	if storedToken.IsRevoked() || storedToken.IsExpired(time.Now()) {
+		return TokenInfo{}
+	}
+
+	dbUser, err := service.userRepo.GetByID(storedToken.UserID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return TokenInfo{}
+	}
+	if !dbUser.IsActive || dbUser.IsLocked {
+		return TokenInfo{}
+	}
+
+	return TokenInfo{
+		Active:    true,
+		Subject:   strconv.Itoa(dbUser.ID),
+		Scope:     storedToken.Scope,
+		ClientID:  storedToken.ClientID,
+		Username:  dbUser.Username,
+		ExpiresAt: storedToken.ExpiresAt.Unix(),
+		IssuedAt:  storedToken.CreatedAt.Unix(),
+	}
+}
+
+// accountActive reports whether the account of the user is neither deactivated nor locked
+func (service *AuthService) accountActive(userID int) bool {
+	dbUser, err := service.userRepo.GetByID(userID)
+	if err != nil {
+		log.Printf("user fetch failed: %v", err)
+		return false
+	}
+
+	return dbUser.IsActive && !dbUser.IsLocked
+}
+
+// RevokeClientToken revokes an access or refresh token issued to the OAuth client, RFC 7009.
+// Revoking a refresh token ends its whole session. Invalid tokens and tokens of other clients
+// are ignored so that the caller learns nothing about them.
+func (service *AuthService) RevokeClientToken(tokenString string, clientID string) error {
+	if tokenString == "" || clientID == "" {
+		return nil
+	}
+
+	if principal, err := service.ValidateAccessToken(tokenString); err == nil {
+		if principal.ClientID != clientID {
+			log.Printf("access token of client %q revoked by client %q ignored", principal.ClientID, clientID)
+			return nil
+		}
+
+		if err := service.revocationStore.Revoke(principal.TokenID, principal.UserID, principal.ExpiresAt); err != nil {
+			log.Printf("access token revoke failed: %v", err)
+			return ErrTokenRevocation
+		}
+
+		return nil
+	}
+
+	storedToken, err := service.refreshTokenRepo.GetByHash(securetoken.Hash(tokenString))
+	if err != nil {
+		return nil
+	}
+	if storedToken.ClientID != clientID {
+		log.Printf("refresh token of client %q revoked by client %q ignored", storedToken.ClientID, clientID)
+		return nil
+	}
+
+	if err := service.refreshTokenRepo.RevokeFamily(storedToken.FamilyID); err != nil {
+		log.Printf("refresh token family revoke failed: %v", err)
+		return ErrTokenRevocation
+	}
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"user-authentication/pkg/jwt"
+)
+
+// fakeRevokedTokenRepo keeps the revoked tokens in memory, stored as the postgres rows
+type fakeRevokedTokenRepo struct {
+	rows          []repoRevokedToken
+	clientCutoffs map[string]time.Time
+}
+
+func (repo *fakeRevokedTokenRepo) Create(token RevokedToken) error {
+	repo.rows = append(repo.rows, toRepoRevokedToken(token))
+	return nil
+}
+
+func (repo *fakeRevokedTokenRepo) GetActive() ([]RevokedToken, error) {
+	tokens := make([]RevokedToken, 0, len(repo.rows))
+	for _, row := range repo.rows {
+		tokens = append(tokens, toEntityRevokedToken(row))
+	}
+	return tokens, nil
+}
+
+func (repo *fakeRevokedTokenRepo) DeleteExpired() (int64, error) {
+	return 0, nil
+}
+
+func (repo *fakeRevokedTokenRepo) SetTokensValidAfter(userID int, cutoff TokenCutoff) error {
+	return nil
+}
+
+func (repo *fakeRevokedTokenRepo) GetTokensValidAfter() (map[int]TokenCutoff, error) {
+	return map[int]TokenCutoff{}, nil
+}
+
+func (repo *fakeRevokedTokenRepo) SetClientTokensValidAfter(clientID string, validAfter time.Time, expiresAt time.Time) error {
+	if repo.clientCutoffs == nil {
+		repo.clientCutoffs = map[string]time.Time{}
+	}
+	repo.clientCutoffs[clientID] = validAfter
+	return nil
+}
+
+func (repo *fakeRevokedTokenRepo) GetClientTokensValidAfter() (map[string]time.Time, error) {
+	validAfter := make(map[string]time.Time, len(repo.clientCutoffs))
+	for clientID, cutoff := range repo.clientCutoffs {
+		validAfter[clientID] = cutoff
+	}
+	return validAfter, nil
+}
+
+func TestRevokeClientCredentialsToken(t *testing.T) {
+	repo := &fakeRevokedTokenRepo{}
+	service := &AuthService{
+		config:          jwt.AuthTokenConfig{SecretKey: []byte("test-secret-of-at-least-32-bytes!")},
+		revocationStore: NewRevocationStore(repo),
+	}
+
+	tokens, err := service.IssueClientToken("nightly-export", "users:read")
+	if err != nil {
+		t.Fatalf("IssueClientToken() failed: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		clientID string
+		revoked  bool
+	}{
+		{"other client", "dashboard", false},
+		{"owning client", "nightly-export", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.RevokeClientToken(tokens.AccessToken, tt.clientID); err != nil {
+				t.Fatalf("RevokeClientToken() failed: %v", err)
+			}
+
+			_, err := service.ValidateAccessToken(tokens.AccessToken)
+			if revoked := errors.Is(err, ErrAccessTokenRevoked); revoked != tt.revoked {
+				t.Errorf("ValidateAccessToken() error = %v, want revoked %v", err, tt.revoked)
+			}
+		})
+	}
+
+	// the token has no user, the row must not reference one
+	if len(repo.rows) != 1 {
+		t.Fatalf("revoked %d tokens, want 1", len(repo.rows))
+	}
+	if repo.rows[0].UserID.Valid {
+		t.Errorf("revoked client token stored with user_id %d, want NULL", repo.rows[0].UserID.Int64)
+	}
+	if !repo.rows[0].ExpiresAt.After(time.Now()) {
+		t.Errorf("revoked client token stored with expiry %v in the past", repo.rows[0].ExpiresAt)
+	}
+
+	// a reload from the repository keeps the token revoked
+	if err := service.revocationStore.(*RevocationStore).Load(); err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if _, err := service.ValidateAccessToken(tokens.AccessToken); !errors.Is(err, ErrAccessTokenRevoked) {
+		t.Errorf("ValidateAccessToken() after reload error = %v, want %v", err, ErrAccessTokenRevoked)
+	}
+}
+
+func TestToRepoRevokedTokenUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID int
+		valid  bool
+	}{
+		{"user token", 42, true},
+		{"client token", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := toRepoRevokedToken(RevokedToken{JTI: "jti", UserID: tt.userID})
+			if row.UserID.Valid != tt.valid || int(row.UserID.Int64) != tt.userID {
+				t.Errorf("toRepoRevokedToken() user_id = %v, want %d valid %v", row.UserID, tt.userID, tt.valid)
+			}
+			if entity := toEntityRevokedToken(row); entity.UserID != tt.userID {
+				t.Errorf("toEntityRevokedToken() UserID = %d, want %d", entity.UserID, tt.userID)
+			}
+		})
+	}
+}
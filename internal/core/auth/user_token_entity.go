@@ -0,0 +1,25 @@
+package auth
+
+import "time"
+
+// purposes of the single-use user tokens
+const (
+	PURPOSE_PASSWORD_RESET     = "password_reset"
+	PURPOSE_EMAIL_VERIFICATION = "email_verification"
+	PURPOSE_MFA_CHALLENGE      = "mfa_challenge"
+	PURPOSE_MAGIC_LINK         = "magic_link"
+)
+
+// UserToken represents a stored single-use token sent to a user, e.g. in a password reset
+// link, only the hash of the token is persisted
+type UserToken struct {
+	ID        int
+	UserID    int
+	Purpose   string
+	TokenHash string
+	// Email is the address the token was sent to, when it was sent by email
+	Email     string
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	CreatedAt time.Time
+}
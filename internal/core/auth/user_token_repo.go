@@ -0,0 +1,8 @@
+package auth
+
+type UserTokenRepoPort interface {
+	Create(token UserToken) (UserToken, error)
+	Get(tokenHash string, purpose string) (UserToken, error)
+	Consume(tokenHash string, purpose string) (UserToken, error)
+	DeleteByUser(userID int, purpose string) error
+}
@@ -0,0 +1,136 @@
+package auth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+)
+
+const (
+	utTable        = "user_tokens"
+	utColUserID    = "user_id"
+	utColPurpose   = "purpose"
+	utColTokenHash = "token_hash"
+	utColExpiresAt = "expires_at"
+	utColUsedAt    = "used_at"
+)
+
+var ErrUserTokenNotFound = errors.New("user token not found, expired or already used")
+
+type repoUserToken struct {
+	ID        int          `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    int          `gorm:"column:user_id"`
+	Purpose   string       `gorm:"column:purpose"`
+	TokenHash string       `gorm:"column:token_hash"`
+	Email     string       `gorm:"column:email"`
+	ExpiresAt time.Time    `gorm:"column:expires_at"`
+	UsedAt    sql.NullTime `gorm:"column:used_at"`
+	CreatedAt time.Time    `gorm:"column:created_at"`
+}
+
+func (repoUserToken) TableName() string {
+	return utTable
+}
+
+// UserTokenRepo represents the single-use user token repository
+type UserTokenRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewUserTokenRepo(pgClient *postgres.PGClient) *UserTokenRepo {
+	return &UserTokenRepo{
+		pgClient: pgClient,
+	}
+}
+
+func toRepoUserToken(token UserToken) repoUserToken {
+	return repoUserToken{
+		ID:        token.ID,
+		UserID:    token.UserID,
+		Purpose:   token.Purpose,
+		TokenHash: token.TokenHash,
+		Email:     token.Email,
+		ExpiresAt: token.ExpiresAt,
+		UsedAt:    sql.NullTime{Time: token.UsedAt, Valid: !token.UsedAt.IsZero()},
+		CreatedAt: time.Now(),
+	}
+}
+
+func toEntityUserToken(token repoUserToken) UserToken {
+	return UserToken{
+		ID:        token.ID,
+		UserID:    token.UserID,
+		Purpose:   token.Purpose,
+		TokenHash: token.TokenHash,
+		Email:     token.Email,
+		ExpiresAt: token.ExpiresAt,
+		UsedAt:    token.UsedAt.Time,
+		CreatedAt: token.CreatedAt,
+	}
+}
+
+func (repo *UserTokenRepo) Create(token UserToken) (UserToken, error) {
+
+	rToken := toRepoUserToken(token)
+	if err := repo.pgClient.DB.Create(&rToken).Error; err != nil {
+		return UserToken{}, fmt.Errorf("user token create failed: %v", err)
+	}
+
+	return toEntityUserToken(rToken), nil
+}
+
+// Get returns an unused and unexpired token without consuming it
+func (repo *UserTokenRepo) Get(tokenHash string, purpose string) (UserToken, error) {
+
+	var rToken repoUserToken
+	err := repo.pgClient.DB.
+		Where(utColTokenHash+" = ? AND "+utColPurpose+" = ? AND "+utColUsedAt+" IS NULL AND "+utColExpiresAt+" > ?",
+			tokenHash, purpose, time.Now()).
+		First(&rToken).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return UserToken{}, ErrUserTokenNotFound
+	}
+	if err != nil {
+		return UserToken{}, fmt.Errorf("user token fetch failed: %v", err)
+	}
+
+	return toEntityUserToken(rToken), nil
+}
+
+// Consume atomically marks an unused and unexpired token as used and returns it,
+// a token can therefore only be consumed once even by concurrent requests
+func (repo *UserTokenRepo) Consume(tokenHash string, purpose string) (UserToken, error) {
+
+	var rTokens []repoUserToken
+	err := repo.pgClient.DB.Raw(
+		"UPDATE "+utTable+" SET "+utColUsedAt+" = ? WHERE "+utColTokenHash+" = ? AND "+utColPurpose+" = ? "+
+			"AND "+utColUsedAt+" IS NULL AND "+utColExpiresAt+" > ? RETURNING *",
+		time.Now(), tokenHash, purpose, time.Now(),
+	).Scan(&rTokens).Error
+	if err != nil {
+		return UserToken{}, fmt.Errorf("user token consume failed: %v", err)
+	}
+
+	if len(rTokens) == 0 {
+		return UserToken{}, ErrUserTokenNotFound
+	}
+
+	return toEntityUserToken(rTokens[0]), nil
+}
+
+// DeleteByUser deletes every token of the user issued for the purpose
+func (repo *UserTokenRepo) DeleteByUser(userID int, purpose string) error {
+
+	err := repo.pgClient.DB.
+		Where(utColUserID+" = ? AND "+utColPurpose+" = ?", userID, purpose).
+		Delete(&repoUserToken{}).Error
+	if err != nil {
+		return fmt.Errorf("user tokens delete failed: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+package mfa
+
+import "time"
+
+// MFA represents the TOTP enrollment of a user, the secret is stored encrypted
+type MFA struct {
+	UserID          int
+	SecretEncrypted string
+	EnabledAt       *time.Time
+	LastUsedCounter int64
+	CreatedAt       time.Time
+}
+
+// IsEnabled reports whether the enrollment was confirmed
+func (m MFA) IsEnabled() bool {
+	return m.EnabledAt != nil
+}
+
+// Enrollment represents a pending enrollment shown to the user once
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
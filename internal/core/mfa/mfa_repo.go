@@ -0,0 +1,10 @@
+package mfa
+
+type MFARepoPort interface {
+	Get(userID int) (MFA, error)
+	SavePending(m MFA) error
+	Enable(userID int, counter int64, recoveryCodeHashes []string) (bool, error)
+	UseCounter(userID int, counter int64) (bool, error)
+	UseRecoveryCode(userID int, codeHash string) (bool, error)
+	Delete(userID int) error
+}
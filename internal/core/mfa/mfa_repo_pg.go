@@ -0,0 +1,189 @@
+package mfa
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	mfaTable              = "user_mfa"
+	mfaColUserID          = "user_id"
+	mfaColEnabledAt       = "enabled_at"
+	mfaColLastUsedCounter = "last_used_counter"
+	rcTable               = "mfa_recovery_codes"
+	rcColUserID           = "user_id"
+	rcColCodeHash         = "code_hash"
+	rcColUsedAt           = "used_at"
+)
+
+type repoMFA struct {
+	UserID          int          `gorm:"column:user_id;primaryKey"`
+	SecretEncrypted string       `gorm:"column:secret_encrypted"`
+	EnabledAt       sql.NullTime `gorm:"column:enabled_at"`
+	LastUsedCounter int64        `gorm:"column:last_used_counter"`
+	CreatedAt       time.Time    `gorm:"column:created_at"`
+}
+
+func (repoMFA) TableName() string {
+	return mfaTable
+}
+
+type repoRecoveryCode struct {
+	ID        int          `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID    int          `gorm:"column:user_id"`
+	CodeHash  string       `gorm:"column:code_hash"`
+	UsedAt    sql.NullTime `gorm:"column:used_at"`
+	CreatedAt time.Time    `gorm:"column:created_at"`
+}
+
+func (repoRecoveryCode) TableName() string {
+	return rcTable
+}
+
+// MFARepo represents the multi-factor authentication repository
+type MFARepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewMFARepo(pgClient *postgres.PGClient) *MFARepo {
+	return &MFARepo{
+		pgClient: pgClient,
+	}
+}
+
+func toEntityMFA(m repoMFA) MFA {
+	entity := MFA{
+		UserID:          m.UserID,
+		SecretEncrypted: m.SecretEncrypted,
+		LastUsedCounter: m.LastUsedCounter,
+		CreatedAt:       m.CreatedAt,
+	}
+	if m.EnabledAt.Valid {
+		entity.EnabledAt = &m.EnabledAt.Time
+	}
+
+	return entity
+}
+
+func (repo *MFARepo) Get(userID int) (MFA, error) {
+
+	var rMFA repoMFA
+	err := repo.pgClient.DB.Where(mfaColUserID+" = ?", userID).First(&rMFA).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return MFA{}, ErrMFANotEnrolled
+	}
+	if err != nil {
+		return MFA{}, fmt.Errorf("mfa fetch failed: %v", err)
+	}
+
+	return toEntityMFA(rMFA), nil
+}
+
+// SavePending stores a pending enrollment, replacing a previous pending one, an
+// enabled enrollment is never replaced
+func (repo *MFARepo) SavePending(m MFA) error {
+
+	res := repo.pgClient.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: mfaColUserID}},
+		DoUpdates: clause.AssignmentColumns([]string{"secret_encrypted", mfaColLastUsedCounter, "created_at"}),
+		Where:     clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: mfaTable + "." + mfaColEnabledAt + " IS NULL"}}},
+	}).Create(&repoMFA{
+		UserID:          m.UserID,
+		SecretEncrypted: m.SecretEncrypted,
+		CreatedAt:       time.Now(),
+	})
+	if res.Error != nil {
+		return fmt.Errorf("mfa enrollment save failed: %v", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return ErrMFAAlreadyEnabled
+	}
+
+	return nil
+}
+
+// Enable confirms a pending enrollment with the counter of its first code and stores the
+// recovery codes, it reports false when the enrollment was not pending anymore
+func (repo *MFARepo) Enable(userID int, counter int64, recoveryCodeHashes []string) (bool, error) {
+
+	enabled := false
+	err := repo.pgClient.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&repoMFA{}).
+			Where(mfaColUserID+" = ? AND "+mfaColEnabledAt+" IS NULL", userID).
+			Updates(map[string]any{mfaColEnabledAt: time.Now(), mfaColLastUsedCounter: counter})
+		if res.Error != nil {
+			return fmt.Errorf("mfa enable failed: %v", res.Error)
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+
+		if err := tx.Where(rcColUserID+" = ?", userID).Delete(&repoRecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("recovery codes delete failed: %v", err)
+		}
+
+		codes := make([]repoRecoveryCode, 0, len(recoveryCodeHashes))
+		for _, codeHash := range recoveryCodeHashes {
+			codes = append(codes, repoRecoveryCode{UserID: userID, CodeHash: codeHash, CreatedAt: time.Now()})
+		}
+		if err := tx.Create(&codes).Error; err != nil {
+			return fmt.Errorf("recovery codes create failed: %v", err)
+		}
+
+		enabled = true
+
+		return nil
+	})
+
+	return enabled, err
+}
+
+// UseCounter records the time step of an accepted code, it reports false when the
+// step or a later one was already used so that a code cannot be replayed
+func (repo *MFARepo) UseCounter(userID int, counter int64) (bool, error) {
+
+	res := repo.pgClient.DB.Model(&repoMFA{}).
+		Where(mfaColUserID+" = ? AND "+mfaColLastUsedCounter+" < ?", userID, counter).
+		Update(mfaColLastUsedCounter, counter)
+	if res.Error != nil {
+		return false, fmt.Errorf("mfa counter update failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// UseRecoveryCode marks an unused recovery code as used, it reports false when no such code exists
+func (repo *MFARepo) UseRecoveryCode(userID int, codeHash string) (bool, error) {
+
+	res := repo.pgClient.DB.Model(&repoRecoveryCode{}).
+		Where(rcColUserID+" = ? AND "+rcColCodeHash+" = ? AND "+rcColUsedAt+" IS NULL", userID, codeHash).
+		Update(rcColUsedAt, time.Now())
+	if res.Error != nil {
+		return false, fmt.Errorf("recovery code update failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// Delete removes the enrollment and the recovery codes of the user
+func (repo *MFARepo) Delete(userID int) error {
+
+	return repo.pgClient.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(rcColUserID+" = ?", userID).Delete(&repoRecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("recovery codes delete failed: %v", err)
+		}
+
+		if err := tx.Where(mfaColUserID+" = ?", userID).Delete(&repoMFA{}).Error; err != nil {
+			return fmt.Errorf("mfa delete failed: %v", err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,274 @@
+package mfa
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/secretbox"
+	"user-authentication/pkg/securetoken"
+	"user-authentication/pkg/totp"
+)
+
+const (
+	// default issuer shown by authenticator apps when none is configured
+	defaultIssuer = "user-authentication"
+	// number of recovery codes issued at enrollment
+	recoveryCodeCount = 10
+	// number of characters of a recovery code, grouped in two halves
+	recoveryCodeLength = 10
+)
+
+// second factors a code is verified as
+const (
+	FACTOR_TOTP          = "totp"
+	FACTOR_RECOVERY_CODE = "recovery_code"
+)
+
+// alphabet of the recovery codes, without characters that are easily confused
+const recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+
+var (
+	ErrMFANotEnrolled    = errors.New("mfa: not enrolled")
+	ErrMFAAlreadyEnabled = errors.New("mfa: already enabled")
+	ErrMFANotPending     = errors.New("mfa: no pending enrollment")
+	ErrInvalidMFACode    = errors.New("mfa: invalid code")
+	ErrMFASecret         = errors.New("mfa: secret unavailable")
+)
+
+// MFAConfig represents the multi-factor authentication settings
+type MFAConfig struct {
+	// Issuer is the name authenticator apps show for the account
+	Issuer string
+	// EncryptionKey encrypts the TOTP secrets at rest, it is left out of the JSON logs
+	EncryptionKey []byte `json:"-"`
+}
+
+// String hides the encryption key when the configuration is logged
+func (config MFAConfig) String() string {
+	return fmt.Sprintf("MFAConfig{issuer: %s, encryptionKey: [redacted]}", config.Issuer)
+}
+
+// MFAServicePort represents the multi-factor authentication service port
+type MFAServicePort interface {
+	Enroll(userID int) (Enrollment, error)
+	Confirm(userID int, code string) ([]string, error)
+	Disable(userID int, code string) error
+	IsEnabled(userID int) (bool, error)
+	Verify(userID int, code string) (string, error)
+}
+
+// MFAService manages the TOTP enrollments and verifies the second factor codes
+type MFAService struct {
+	config   MFAConfig
+	repo     MFARepoPort
+	userRepo user.UserRepoPort
+	box      *secretbox.Box
+}
+
+// NewMFAService creates a new multi-factor authentication service
+func NewMFAService(config MFAConfig, repo MFARepoPort, userRepo user.UserRepoPort) (*MFAService, error) {
+	box, err := secretbox.NewBox(config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Issuer == "" {
+		config.Issuer = defaultIssuer
+	}
+
+	return &MFAService{
+		config:   config,
+		repo:     repo,
+		userRepo: userRepo,
+		box:      box,
+	}, nil
+}
+
+// Enroll generates a new secret for the user, it is only enabled once confirmed with a first code
+func (service *MFAService) Enroll(userID int) (Enrollment, error) {
+	dbUser, err := service.userRepo.GetByID(userID)
+	if err != nil {
+		return Enrollment{}, err
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		log.Printf("mfa secret generation failed: %v", err)
+		return Enrollment{}, ErrMFASecret
+	}
+
+	secretEncrypted, err := service.box.Seal([]byte(secret))
+	if err != nil {
+		log.Printf("mfa secret encryption failed: %v", err)
+		return Enrollment{}, ErrMFASecret
+	}
+
+	if err := service.repo.SavePending(MFA{UserID: userID, SecretEncrypted: secretEncrypted}); err != nil {
+		return Enrollment{}, err
+	}
+
+	return Enrollment{
+		Secret: secret,
+		URI:    totp.URI(service.config.Issuer, dbUser.Username, secret),
+	}, nil
+}
+
+// Confirm enables the pending enrollment with a first code and returns the recovery
+// codes, they are only shown this once
+func (service *MFAService) Confirm(userID int, code string) ([]string, error) {
+	m, err := service.repo.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.IsEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	counter, ok := service.validate(m, code)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	recoveryCodes, err := generateRecoveryCodes()
+	if err != nil {
+		log.Printf("recovery codes generation failed: %v", err)
+		return nil, ErrMFASecret
+	}
+
+	recoveryCodeHashes := make([]string, 0, len(recoveryCodes))
+	for _, recoveryCode := range recoveryCodes {
+		recoveryCodeHashes = append(recoveryCodeHashes, securetoken.Hash(recoveryCode))
+	}
+
+	enabled, err := service.repo.Enable(userID, counter, recoveryCodeHashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrMFANotPending
+	}
+
+	return recoveryCodes, nil
+}
+
+// Disable removes the enrollment after verifying a code or a recovery code
+func (service *MFAService) Disable(userID int, code string) error {
+	m, err := service.repo.Get(userID)
+	if err != nil {
+		return err
+	}
+
+	// a pending enrollment can be dropped without a code
+	if m.IsEnabled() {
+		if _, err := service.Verify(userID, code); err != nil {
+			return err
+		}
+	}
+
+	return service.repo.Delete(userID)
+}
+
+// IsEnabled reports whether the user has to present a second factor on login
+func (service *MFAService) IsEnabled(userID int) (bool, error) {
+	m, err := service.repo.Get(userID)
+	if errors.Is(err, ErrMFANotEnrolled) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return m.IsEnabled(), nil
+}
+
+// Verify checks a TOTP code, or a recovery code which is then used up, every code is single-use.
+// It returns the factor the code was verified as.
+func (service *MFAService) Verify(userID int, code string) (string, error) {
+	m, err := service.repo.Get(userID)
+	if err != nil {
+		return "", err
+	}
+	if !m.IsEnabled() {
+		return "", ErrMFANotEnrolled
+	}
+
+	code = strings.TrimSpace(code)
+	if counter, ok := service.validate(m, code); ok {
+		used, err := service.repo.UseCounter(userID, counter)
+		if err != nil {
+			return "", err
+		}
+		if !used {
+			log.Printf("mfa code replay rejected for user %d", userID)
+			return "", ErrInvalidMFACode
+		}
+
+		return FACTOR_TOTP, nil
+	}
+
+	used, err := service.repo.UseRecoveryCode(userID, securetoken.Hash(normalizeRecoveryCode(code)))
+	if err != nil {
+		return "", err
+	}
+	if !used {
+		return "", ErrInvalidMFACode
+	}
+
+	log.Printf("recovery code used by user %d", userID)
+
+	return FACTOR_RECOVERY_CODE, nil
+}
+
+// validate checks the TOTP code against the decrypted secret of the enrollment
+func (service *MFAService) validate(m MFA, code string) (int64, bool) {
+	secret, err := service.box.Open(m.SecretEncrypted)
+	if err != nil {
+		log.Printf("mfa secret decryption failed for user %d: %v", m.UserID, err)
+		return 0, false
+	}
+
+	return totp.Validate(string(secret), code, time.Now())
+}
+
+// generateRecoveryCodes returns new random recovery codes formatted as xxxxx-xxxxx
+func generateRecoveryCodes() ([]string, error) {
+	// bytes past the largest multiple of the alphabet size are rejected to avoid a modulo bias
+	maxByte := 256 - 256%len(recoveryCodeAlphabet)
+
+	codes := make([]string, 0, recoveryCodeCount)
+	random := make([]byte, 1)
+	for i := 0; i < recoveryCodeCount; i++ {
+		var code strings.Builder
+		for written := 0; written < recoveryCodeLength; {
+			if _, err := rand.Read(random); err != nil {
+				return nil, err
+			}
+			if int(random[0]) >= maxByte {
+				continue
+			}
+
+			if written == recoveryCodeLength/2 {
+				code.WriteByte('-')
+			}
+			code.WriteByte(recoveryCodeAlphabet[int(random[0])%len(recoveryCodeAlphabet)])
+			written++
+		}
+		codes = append(codes, code.String())
+	}
+
+	return codes, nil
+}
+
+// normalizeRecoveryCode accepts recovery codes typed without the dash or in upper case
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	if len(code) != recoveryCodeLength {
+		return code
+	}
+
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+}
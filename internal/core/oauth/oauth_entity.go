@@ -0,0 +1,117 @@
+package oauth
+
+import (
+	"strings"
+	"time"
+)
+
+// RESPONSE_TYPE_CODE is the only response type of the authorization endpoint
+const RESPONSE_TYPE_CODE = "code"
+
+// grant types of the token endpoint
+const (
+	GRANT_TYPE_AUTHORIZATION_CODE = "authorization_code"
+	GRANT_TYPE_REFRESH_TOKEN      = "refresh_token"
+	GRANT_TYPE_CLIENT_CREDENTIALS = "client_credentials"
+)
+
+// Client represents a registered OAuth client
+type Client struct {
+	ID           int      `json:"-"`
+	ClientID     string   `json:"client_id"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	// Confidential clients authenticate with a secret, only its hash is stored
+	Confidential bool      `json:"confidential"`
+	SecretHash   string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// HasRedirectURI reports whether the redirect URI is registered, URIs are compared exactly
+func (client Client) HasRedirectURI(redirectURI string) bool {
+	for _, uri := range client.RedirectURIs {
+		if uri == redirectURI {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AuthorizationCode represents an issued authorization code, only its hash is persisted
+type AuthorizationCode struct {
+	ID            int
+	CodeHash      string
+	ClientID      string
+	UserID        int
+	RedirectURI   string
+	Scope         string
+	CodeChallenge string
+	// Nonce, AuthTime and AMR are echoed in the ID token of OpenID Connect requests
+	Nonce     string
+	AuthTime  time.Time
+	AMR       []string
+	ExpiresAt time.Time
+	UsedAt    time.Time
+	CreatedAt time.Time
+}
+
+// AuthorizationRequest represents the parameters of an authorization request
+type AuthorizationRequest struct {
+	ResponseType        string `form:"response_type"`
+	ClientID            string `form:"client_id"`
+	RedirectURI         string `form:"redirect_uri"`
+	Scope               string `form:"scope"`
+	State               string `form:"state"`
+	CodeChallenge       string `form:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method"`
+	Nonce               string `form:"nonce"`
+}
+
+// TokenRequest represents the parameters of a token request
+type TokenRequest struct {
+	GrantType    string `form:"grant_type"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+}
+
+// IntrospectionRequest represents the parameters of a token introspection request, RFC 7662
+type IntrospectionRequest struct {
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// RevocationRequest represents the parameters of a token revocation request, RFC 7009
+type RevocationRequest struct {
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+	Token         string `form:"token"`
+	TokenTypeHint string `form:"token_type_hint"`
+}
+
+// parseScope splits a space separated scope, duplicates are dropped
+func parseScope(scope string) []string {
+	scopes := []string{}
+	seen := map[string]bool{}
+	for _, s := range strings.Fields(scope) {
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+
+	return scopes
+}
+
+// joinScope joins scopes into a space separated scope
+func joinScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
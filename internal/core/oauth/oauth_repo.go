@@ -0,0 +1,12 @@
+package oauth
+
+type OAuthRepoPort interface {
+	CreateClient(client Client) (Client, error)
+	GetClient(clientID string) (Client, error)
+	GetClients() ([]Client, error)
+	DeleteClient(clientID string) (bool, error)
+	UpdateClientSecret(clientID string, secretHash string) (bool, error)
+	CreateAuthorizationCode(code AuthorizationCode) error
+	ConsumeAuthorizationCode(codeHash string) (AuthorizationCode, error)
+	DeleteExpiredAuthorizationCodes() error
+}
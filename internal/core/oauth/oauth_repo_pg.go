@@ -0,0 +1,227 @@
+package oauth
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+)
+
+const (
+	ocTable       = "oauth_clients"
+	ocColClientID = "client_id"
+	ocColSecret   = "secret_hash"
+	ocColCreated  = "created_at"
+
+	acTable       = "oauth_authorization_codes"
+	acColCodeHash = "code_hash"
+	acColExpires  = "expires_at"
+	acColUsedAt   = "used_at"
+)
+
+type repoClient struct {
+	ID           int            `gorm:"column:id;primaryKey;autoIncrement"`
+	ClientID     string         `gorm:"column:client_id"`
+	Name         string         `gorm:"column:name"`
+	RedirectURIs string         `gorm:"column:redirect_uris"`
+	Scopes       string         `gorm:"column:scopes"`
+	SecretHash   sql.NullString `gorm:"column:secret_hash"`
+	CreatedAt    time.Time      `gorm:"column:created_at"`
+}
+
+func (repoClient) TableName() string {
+	return ocTable
+}
+
+type repoAuthorizationCode struct {
+	ID            int          `gorm:"column:id;primaryKey;autoIncrement"`
+	CodeHash      string       `gorm:"column:code_hash"`
+	ClientID      string       `gorm:"column:client_id"`
+	UserID        int          `gorm:"column:user_id"`
+	RedirectURI   string       `gorm:"column:redirect_uri"`
+	Scope         string       `gorm:"column:scope"`
+	CodeChallenge string       `gorm:"column:code_challenge"`
+	Nonce         string       `gorm:"column:nonce"`
+	AuthTime      sql.NullTime `gorm:"column:auth_time"`
+	AMR           string       `gorm:"column:amr"`
+	ExpiresAt     time.Time    `gorm:"column:expires_at"`
+	UsedAt        sql.NullTime `gorm:"column:used_at"`
+	CreatedAt     time.Time    `gorm:"column:created_at"`
+}
+
+func (repoAuthorizationCode) TableName() string {
+	return acTable
+}
+
+// OAuthRepo represents the OAuth clients and authorization codes repository
+type OAuthRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewOAuthRepo(pgClient *postgres.PGClient) *OAuthRepo {
+	return &OAuthRepo{
+		pgClient: pgClient,
+	}
+}
+
+func toRepoClient(client Client) repoClient {
+	return repoClient{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: strings.Join(client.RedirectURIs, " "),
+		Scopes:       joinScope(client.Scopes),
+		SecretHash:   sql.NullString{String: client.SecretHash, Valid: client.SecretHash != ""},
+		CreatedAt:    time.Now(),
+	}
+}
+
+func toEntityClient(client repoClient) Client {
+	return Client{
+		ID:           client.ID,
+		ClientID:     client.ClientID,
+		Name:         client.Name,
+		RedirectURIs: strings.Fields(client.RedirectURIs),
+		Scopes:       parseScope(client.Scopes),
+		Confidential: client.SecretHash.Valid,
+		SecretHash:   client.SecretHash.String,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func (repo *OAuthRepo) CreateClient(client Client) (Client, error) {
+
+	rClient := toRepoClient(client)
+	if err := repo.pgClient.DB.Create(&rClient).Error; err != nil {
+		return Client{}, fmt.Errorf("oauth client create failed: %v", err)
+	}
+
+	return toEntityClient(rClient), nil
+}
+
+func (repo *OAuthRepo) GetClient(clientID string) (Client, error) {
+
+	var rClient repoClient
+	err := repo.pgClient.DB.Where(ocColClientID+" = ?", clientID).First(&rClient).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Client{}, ErrClientNotFound
+	}
+	if err != nil {
+		return Client{}, fmt.Errorf("oauth client fetch failed: %v", err)
+	}
+
+	return toEntityClient(rClient), nil
+}
+
+func (repo *OAuthRepo) GetClients() ([]Client, error) {
+
+	var rClients []repoClient
+	if err := repo.pgClient.DB.Order(ocColCreated).Find(&rClients).Error; err != nil {
+		return []Client{}, fmt.Errorf("oauth clients fetch failed: %v", err)
+	}
+
+	clients := make([]Client, 0, len(rClients))
+	for _, c := range rClients {
+		clients = append(clients, toEntityClient(c))
+	}
+
+	return clients, nil
+}
+
+// DeleteClient deletes the client along with its codes and refresh tokens, it reports
+// false when the client does not exist
+func (repo *OAuthRepo) DeleteClient(clientID string) (bool, error) {
+
+	res := repo.pgClient.DB.Where(ocColClientID+" = ?", clientID).Delete(&repoClient{})
+	if res.Error != nil {
+		return false, fmt.Errorf("oauth client delete failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// UpdateClientSecret replaces the secret of a confidential client, it reports false when
+// no confidential client has the id
+func (repo *OAuthRepo) UpdateClientSecret(clientID string, secretHash string) (bool, error) {
+
+	res := repo.pgClient.DB.Model(&repoClient{}).
+		Where(ocColClientID+" = ? AND "+ocColSecret+" IS NOT NULL", clientID).
+		Update(ocColSecret, secretHash)
+	if res.Error != nil {
+		return false, fmt.Errorf("oauth client secret update failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+func (repo *OAuthRepo) CreateAuthorizationCode(code AuthorizationCode) error {
+
+	rCode := repoAuthorizationCode{
+		CodeHash:      code.CodeHash,
+		ClientID:      code.ClientID,
+		UserID:        code.UserID,
+		RedirectURI:   code.RedirectURI,
+		Scope:         code.Scope,
+		CodeChallenge: code.CodeChallenge,
+		Nonce:         code.Nonce,
+		AuthTime:      sql.NullTime{Time: code.AuthTime, Valid: !code.AuthTime.IsZero()},
+		AMR:           strings.Join(code.AMR, " "),
+		ExpiresAt:     code.ExpiresAt,
+		CreatedAt:     time.Now(),
+	}
+	if err := repo.pgClient.DB.Create(&rCode).Error; err != nil {
+		return fmt.Errorf("authorization code create failed: %v", err)
+	}
+
+	return nil
+}
+
+// ConsumeAuthorizationCode atomically marks an unused, unexpired code as used and returns
+// it, a code can therefore only be exchanged once
+func (repo *OAuthRepo) ConsumeAuthorizationCode(codeHash string) (AuthorizationCode, error) {
+
+	now := time.Now()
+	var rCodes []repoAuthorizationCode
+	err := repo.pgClient.DB.Raw(
+		"UPDATE "+acTable+" SET "+acColUsedAt+" = ? WHERE "+acColCodeHash+" = ? AND "+acColUsedAt+" IS NULL AND "+acColExpires+" > ? RETURNING *",
+		now, codeHash, now,
+	).Scan(&rCodes).Error
+	if err != nil {
+		return AuthorizationCode{}, fmt.Errorf("authorization code consume failed: %v", err)
+	}
+
+	if len(rCodes) == 0 {
+		return AuthorizationCode{}, ErrInvalidGrant
+	}
+
+	return AuthorizationCode{
+		ID:            rCodes[0].ID,
+		CodeHash:      rCodes[0].CodeHash,
+		ClientID:      rCodes[0].ClientID,
+		UserID:        rCodes[0].UserID,
+		RedirectURI:   rCodes[0].RedirectURI,
+		Scope:         rCodes[0].Scope,
+		CodeChallenge: rCodes[0].CodeChallenge,
+		Nonce:         rCodes[0].Nonce,
+		AuthTime:      rCodes[0].AuthTime.Time,
+		AMR:           strings.Fields(rCodes[0].AMR),
+		ExpiresAt:     rCodes[0].ExpiresAt,
+		UsedAt:        rCodes[0].UsedAt.Time,
+		CreatedAt:     rCodes[0].CreatedAt,
+	}, nil
+}
+
+// DeleteExpiredAuthorizationCodes deletes the codes that can no longer be exchanged
+func (repo *OAuthRepo) DeleteExpiredAuthorizationCodes() error {
+
+	err := repo.pgClient.DB.Where(acColExpires+" <= ?", time.Now()).Delete(&repoAuthorizationCode{}).Error
+	if err != nil {
+		return fmt.Errorf("authorization codes purge failed: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,520 @@
+package oauth
+
+import (
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/jwt"
+	"user-authentication/pkg/pkce"
+	"user-authentication/pkg/securetoken"
+)
+
+const (
+	// lifetime of an authorization code, it is exchanged right after the redirect
+	authorizationCodeExpiry = 2 * time.Minute
+	// entropy in bytes of the generated client ids
+	clientIDBytes = 16
+	// entropy in bytes of the generated client secrets
+	clientSecretBytes = 32
+	// maximum length of a client name
+	maxClientNameLength = 100
+)
+
+// errors of the OAuth endpoints, the handler maps them to the error codes of RFC 6749
+var (
+	ErrClientNotFound          = errors.New("oauth: client not found")
+	ErrInvalidClientDetails    = errors.New("oauth: invalid client details")
+	ErrPublicClient            = errors.New("oauth: public clients have no secret")
+	ErrInvalidClient           = errors.New("unknown client or invalid client credentials")
+	ErrUnauthorizedClient      = errors.New("the client is not allowed to use the grant type or endpoint")
+	ErrInvalidRedirectURI      = errors.New("redirect_uri is not registered for the client")
+	ErrInvalidRequest          = errors.New("missing or invalid request parameter")
+	ErrUnsupportedResponseType = errors.New("only the code response type is supported")
+	ErrPKCERequired            = errors.New("a S256 code_challenge is required")
+	ErrInvalidScope            = errors.New("the requested scope is not allowed for the client")
+	ErrAccessDenied            = errors.New("the user denied the request")
+	ErrUnsupportedGrantType    = errors.New("unsupported grant_type")
+	ErrInvalidGrant            = errors.New("invalid, expired or already used grant")
+	ErrServerError             = errors.New("the request could not be completed")
+)
+
+// OAuthServicePort represents the OAuth authorization server port
+type OAuthServicePort interface {
+	CreateClient(client Client) (Client, string, error)
+	GetClients() ([]Client, error)
+	DeleteClient(clientID string) error
+	RotateClientSecret(clientID string) (string, error)
+	ValidateAuthorization(req AuthorizationRequest) (Client, error)
+	Authorize(req AuthorizationRequest, authentication Authentication) (string, error)
+	Token(req TokenRequest) (auth.AuthTokens, error)
+	Introspect(req IntrospectionRequest) (auth.TokenInfo, error)
+	Revoke(req RevocationRequest) error
+	Discovery() ProviderMetadata
+	UserInfo(principal auth.Principal) (UserInfo, error)
+}
+
+// OAuthService implements the authorization code grant with PKCE for the registered
+// clients and OpenID Connect on top of it, the tokens are issued by the authentication service
+type OAuthService struct {
+	tokenConfig jwt.AuthTokenConfig
+	repo        OAuthRepoPort
+	userRepo    user.UserRepoPort
+	authService auth.AuthServicePort
+}
+
+// NewOAuthService creates a new OAuth authorization server
+func NewOAuthService(tokenConfig jwt.AuthTokenConfig, repo OAuthRepoPort, userRepo user.UserRepoPort, authService auth.AuthServicePort) *OAuthService {
+	return &OAuthService{
+		tokenConfig: tokenConfig,
+		repo:        repo,
+		userRepo:    userRepo,
+		authService: authService,
+	}
+}
+
+// validRedirectURI accepts absolute URIs without fragment. Plain http is only allowed on
+// loopback addresses, and native apps may use a private-use scheme in reverse domain form
+// (RFC 8252), which keeps out schemes like javascript or data.
+func validRedirectURI(redirectURI string) bool {
+	uri, err := url.Parse(redirectURI)
+	if err != nil || uri.Scheme == "" || uri.Fragment != "" || strings.ContainsAny(redirectURI, " #") {
+		return false
+	}
+
+	switch uri.Scheme {
+	case "https":
+		return uri.Host != ""
+	case "http":
+		host := uri.Hostname()
+		return host == "localhost" || host == "127.0.0.1" || host == "::1"
+	default:
+		return strings.Contains(uri.Scheme, ".")
+	}
+}
+
+// validScopeToken checks the characters of a scope token, %x21 / %x23-5B / %x5D-7E
+func validScopeToken(scope string) bool {
+	for i := 0; i < len(scope); i++ {
+		c := scope[i]
+		if c < 0x21 || c == 0x22 || c == 0x5C || c > 0x7E {
+			return false
+		}
+	}
+
+	return scope != ""
+}
+
+// CreateClient registers a new client, its client id is generated. Confidential clients also
+// get a generated secret, which is returned once and only stored hashed.
+func (service *OAuthService) CreateClient(client Client) (Client, string, error) {
+	client.Name = strings.TrimSpace(client.Name)
+	if client.Name == "" || utf8.RuneCountInString(client.Name) > maxClientNameLength {
+		return Client{}, "", ErrInvalidClientDetails
+	}
+
+	// confidential clients may only use the client credentials grant and have no redirect URI
+	if len(client.RedirectURIs) == 0 && !client.Confidential {
+		return Client{}, "", ErrInvalidClientDetails
+	}
+	for _, uri := range client.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return Client{}, "", ErrInvalidClientDetails
+		}
+	}
+
+	for _, scope := range client.Scopes {
+		if !validScopeToken(scope) {
+			return Client{}, "", ErrInvalidClientDetails
+		}
+		if scope == SCOPE_OPENID && !service.tokenConfig.SupportsIDTokens() {
+			return Client{}, "", ErrInvalidClientDetails
+		}
+	}
+	client.Scopes = parseScope(joinScope(client.Scopes))
+
+	clientID, err := securetoken.Generate(clientIDBytes)
+	if err != nil {
+		log.Printf("oauth client id creation failed: %v", err)
+		return Client{}, "", ErrServerError
+	}
+	client.ClientID = clientID
+
+	secret := ""
+	client.SecretHash = ""
+	if client.Confidential {
+		secret, err = securetoken.Generate(clientSecretBytes)
+		if err != nil {
+			log.Printf("oauth client secret creation failed: %v", err)
+			return Client{}, "", ErrServerError
+		}
+		client.SecretHash = securetoken.Hash(secret)
+	}
+
+	client, err = service.repo.CreateClient(client)
+	if err != nil {
+		log.Printf("oauth client store failed: %v", err)
+		return Client{}, "", ErrServerError
+	}
+
+	return client, secret, nil
+}
+
+// GetClients returns the registered clients
+func (service *OAuthService) GetClients() ([]Client, error) {
+	clients, err := service.repo.GetClients()
+	if err != nil {
+		log.Printf("oauth clients fetch failed: %v", err)
+		return []Client{}, ErrServerError
+	}
+
+	return clients, nil
+}
+
+// DeleteClient deletes the client, the sessions of its users end with it and every access
+// token issued to it is revoked
+func (service *OAuthService) DeleteClient(clientID string) error {
+	if _, err := service.repo.GetClient(clientID); err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			return ErrClientNotFound
+		}
+		log.Printf("oauth client fetch failed: %v", err)
+		return ErrServerError
+	}
+
+	// the tokens are revoked first, a failure leaves the client in place to retry
+	if err := service.authService.RevokeClientSessions(clientID); err != nil {
+		return ErrServerError
+	}
+
+	deleted, err := service.repo.DeleteClient(clientID)
+	if err != nil {
+		log.Printf("oauth client delete failed: %v", err)
+		return ErrServerError
+	}
+	if !deleted {
+		return ErrClientNotFound
+	}
+
+	return nil
+}
+
+// RotateClientSecret replaces the secret of a confidential client and returns the new one,
+// the previous secret stops working right away and every token issued to the client is revoked
+func (service *OAuthService) RotateClientSecret(clientID string) (string, error) {
+	client, err := service.repo.GetClient(clientID)
+	if err != nil {
+		if errors.Is(err, ErrClientNotFound) {
+			return "", ErrClientNotFound
+		}
+		log.Printf("oauth client fetch failed: %v", err)
+		return "", ErrServerError
+	}
+	if !client.Confidential {
+		return "", ErrPublicClient
+	}
+
+	secret, err := securetoken.Generate(clientSecretBytes)
+	if err != nil {
+		log.Printf("oauth client secret creation failed: %v", err)
+		return "", ErrServerError
+	}
+
+	updated, err := service.repo.UpdateClientSecret(clientID, securetoken.Hash(secret))
+	if err != nil {
+		log.Printf("oauth client secret store failed: %v", err)
+		return "", ErrServerError
+	}
+	if !updated {
+		return "", ErrClientNotFound
+	}
+
+	// tokens obtained with the previous secret must not outlive it, the client gets new ones
+	// with the new secret
+	if err := service.authService.RevokeClientSessions(clientID); err != nil {
+		return "", ErrServerError
+	}
+
+	return secret, nil
+}
+
+// ValidateAuthorization checks the authorization request and returns the client. An
+// ErrInvalidClient or ErrInvalidRedirectURI must not be redirected to the redirect URI,
+// the other errors are sent back to the client through it.
+func (service *OAuthService) ValidateAuthorization(req AuthorizationRequest) (Client, error) {
+	client, err := service.repo.GetClient(req.ClientID)
+	if err != nil {
+		log.Printf("authorization request of unknown client %q: %v", req.ClientID, err)
+		return Client{}, ErrInvalidClient
+	}
+
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return Client{}, ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != RESPONSE_TYPE_CODE {
+		return client, ErrUnsupportedResponseType
+	}
+
+	if req.CodeChallengeMethod != pkce.METHOD_S256 || !pkce.ValidChallenge(req.CodeChallenge) {
+		return client, ErrPKCERequired
+	}
+
+	scope, err := grantedScope(client, req.Scope)
+	if err != nil {
+		return client, err
+	}
+
+	// without an asymmetric signing key the service can't act as an OpenID provider
+	if hasScope(parseScope(scope), SCOPE_OPENID) && !service.tokenConfig.SupportsIDTokens() {
+		log.Printf("authorization request of client %q refused: openid requires an asymmetric signing key", client.ClientID)
+		return client, ErrInvalidScope
+	}
+
+	if len(req.Nonce) > maxNonceLength {
+		return client, ErrInvalidRequest
+	}
+
+	return client, nil
+}
+
+// grantedScope returns the scope granted to the client, all of its scopes when the
+// request has none
+func grantedScope(client Client, requested string) (string, error) {
+	scopes := parseScope(requested)
+	if len(scopes) == 0 {
+		return joinScope(client.Scopes), nil
+	}
+
+	allowed := map[string]bool{}
+	for _, s := range client.Scopes {
+		allowed[s] = true
+	}
+	for _, s := range scopes {
+		if !allowed[s] {
+			return "", ErrInvalidScope
+		}
+	}
+
+	return joinScope(scopes), nil
+}
+
+// Authorize issues an authorization code to the client once the user authenticated and
+// consented, the code is bound to the client, the redirect URI and the PKCE challenge
+func (service *OAuthService) Authorize(req AuthorizationRequest, authentication Authentication) (string, error) {
+	client, err := service.ValidateAuthorization(req)
+	if err != nil {
+		return "", err
+	}
+
+	scope, err := grantedScope(client, req.Scope)
+	if err != nil {
+		return "", err
+	}
+
+	// codes of abandoned flows are purged lazily
+	if err := service.repo.DeleteExpiredAuthorizationCodes(); err != nil {
+		log.Printf("expired authorization codes purge failed: %v", err)
+	}
+
+	code, err := securetoken.Generate(securetoken.DefaultTokenBytes)
+	if err != nil {
+		log.Printf("authorization code creation failed: %v", err)
+		return "", ErrServerError
+	}
+
+	err = service.repo.CreateAuthorizationCode(AuthorizationCode{
+		CodeHash:      securetoken.Hash(code),
+		ClientID:      req.ClientID,
+		UserID:        authentication.UserID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         scope,
+		CodeChallenge: req.CodeChallenge,
+		Nonce:         req.Nonce,
+		AuthTime:      authentication.AuthTime,
+		AMR:           authentication.Methods,
+		ExpiresAt:     time.Now().Add(authorizationCodeExpiry),
+	})
+	if err != nil {
+		log.Printf("authorization code store failed: %v", err)
+		return "", ErrServerError
+	}
+
+	return code, nil
+}
+
+// Token authenticates the client, runs the grant of the token request and returns the
+// issued tokens
+func (service *OAuthService) Token(req TokenRequest) (auth.AuthTokens, error) {
+	client, err := service.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return auth.AuthTokens{}, err
+	}
+
+	switch req.GrantType {
+	case GRANT_TYPE_AUTHORIZATION_CODE:
+		return service.exchangeCode(client, req)
+	case GRANT_TYPE_REFRESH_TOKEN:
+		return service.refresh(client, req)
+	case GRANT_TYPE_CLIENT_CREDENTIALS:
+		return service.clientCredentials(client, req)
+	case "":
+		return auth.AuthTokens{}, ErrInvalidRequest
+	default:
+		return auth.AuthTokens{}, ErrUnsupportedGrantType
+	}
+}
+
+// authenticateClient returns the client of the token request. Confidential clients must
+// present their secret, public clients must not present one.
+func (service *OAuthService) authenticateClient(clientID string, secret string) (Client, error) {
+	client, err := service.repo.GetClient(clientID)
+	if err != nil {
+		log.Printf("token request of unknown client %q: %v", clientID, err)
+		return Client{}, ErrInvalidClient
+	}
+
+	if !client.Confidential {
+		if secret != "" {
+			return Client{}, ErrInvalidClient
+		}
+		return client, nil
+	}
+
+	// the hashes have a fixed length, comparing them in constant time hides the secret
+	if secret == "" || subtle.ConstantTimeCompare([]byte(securetoken.Hash(secret)), []byte(client.SecretHash)) != 1 {
+		log.Printf("token request of client %q rejected: invalid client secret", clientID)
+		return Client{}, ErrInvalidClient
+	}
+
+	return client, nil
+}
+
+// clientCredentials issues an access token to a confidential client acting on its own
+// behalf, the scope lists the permissions the token grants
+func (service *OAuthService) clientCredentials(client Client, req TokenRequest) (auth.AuthTokens, error) {
+	if !client.Confidential {
+		return auth.AuthTokens{}, ErrUnauthorizedClient
+	}
+
+	scope, err := grantedScope(client, req.Scope)
+	if err != nil {
+		return auth.AuthTokens{}, err
+	}
+
+	tokens, err := service.authService.IssueClientToken(client.ClientID, scope)
+	if err != nil {
+		return auth.AuthTokens{}, tokenError(err)
+	}
+
+	return tokens, nil
+}
+
+// exchangeCode redeems an authorization code, the code verifier proves the caller started
+// the authorization request
+func (service *OAuthService) exchangeCode(client Client, req TokenRequest) (auth.AuthTokens, error) {
+	if req.Code == "" || req.RedirectURI == "" || req.CodeVerifier == "" {
+		return auth.AuthTokens{}, ErrInvalidRequest
+	}
+
+	// the code is used up even when the checks below fail, a leaked code is worthless
+	code, err := service.repo.ConsumeAuthorizationCode(securetoken.Hash(req.Code))
+	if err != nil {
+		log.Printf("authorization code consume failed: %v", err)
+		return auth.AuthTokens{}, ErrInvalidGrant
+	}
+
+	if code.ClientID != client.ClientID || code.RedirectURI != req.RedirectURI {
+		log.Printf("authorization code of client %q presented by client %q", code.ClientID, client.ClientID)
+		return auth.AuthTokens{}, ErrInvalidGrant
+	}
+
+	if !pkce.VerifyS256(req.CodeVerifier, code.CodeChallenge) {
+		log.Printf("authorization code rejected for client %q: code verifier mismatch", client.ClientID)
+		return auth.AuthTokens{}, ErrInvalidGrant
+	}
+
+	tokens, err := service.authService.StartClientSession(code.UserID, client.ClientID, code.Scope)
+	if err != nil {
+		return auth.AuthTokens{}, tokenError(err)
+	}
+
+	// the ID token is only issued on the code exchange, refreshed tokens come without one
+	if hasScope(parseScope(code.Scope), SCOPE_OPENID) {
+		tokens.IDToken, err = service.idToken(code)
+		if err != nil {
+			log.Printf("id token creation failed: %v", err)
+			return auth.AuthTokens{}, ErrServerError
+		}
+	}
+
+	return tokens, nil
+}
+
+// refresh rotates a refresh token issued to the client, narrowing the scope is not
+// supported and the refreshed tokens keep the scope granted by the user
+func (service *OAuthService) refresh(client Client, req TokenRequest) (auth.AuthTokens, error) {
+	if req.RefreshToken == "" {
+		return auth.AuthTokens{}, ErrInvalidRequest
+	}
+
+	tokens, err := service.authService.RefreshClientSession(req.RefreshToken, client.ClientID)
+	if err != nil {
+		return auth.AuthTokens{}, tokenError(err)
+	}
+
+	return tokens, nil
+}
+
+// Introspect reports the state of a token to a resource server, which must authenticate as
+// a confidential client
+func (service *OAuthService) Introspect(req IntrospectionRequest) (auth.TokenInfo, error) {
+	client, err := service.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return auth.TokenInfo{}, err
+	}
+	if !client.Confidential {
+		return auth.TokenInfo{}, ErrUnauthorizedClient
+	}
+
+	if req.Token == "" {
+		return auth.TokenInfo{}, ErrInvalidRequest
+	}
+
+	// the hint is optional, every token type is looked up
+	return service.authService.IntrospectToken(req.Token), nil
+}
+
+// Revoke revokes an access or refresh token issued to the client, unknown tokens are
+// ignored as required by RFC 7009
+func (service *OAuthService) Revoke(req RevocationRequest) error {
+	client, err := service.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	if req.Token == "" {
+		return ErrInvalidRequest
+	}
+
+	if err := service.authService.RevokeClientToken(req.Token, client.ClientID); err != nil {
+		return ErrServerError
+	}
+
+	return nil
+}
+
+// tokenError maps the authentication service errors to the errors of the token endpoint
+func tokenError(err error) error {
+	if errors.Is(err, auth.ErrTokenCreation) || errors.Is(err, auth.ErrRefreshTokenRotation) {
+		return ErrServerError
+	}
+
+	log.Printf("token request rejected: %v", err)
+	return ErrInvalidGrant
+}
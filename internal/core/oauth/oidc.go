@@ -0,0 +1,180 @@
+package oauth
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"user-authentication/internal/core/auth"
+	"user-authentication/pkg/jwt"
+	"user-authentication/pkg/pkce"
+)
+
+// OpenID Connect scopes, openid makes an authorization request an authentication request
+const (
+	SCOPE_OPENID  = "openid"
+	SCOPE_PROFILE = "profile"
+	SCOPE_EMAIL   = "email"
+)
+
+// authentication methods reported in the amr claim, RFC 8176
+const (
+	AMR_PWD = "pwd"
+	AMR_OTP = "otp"
+	AMR_MFA = "mfa"
+)
+
+// paths of the endpoints published in the discovery document
+const (
+	pathAuthorize  = "/oauth/authorize"
+	pathToken      = "/oauth/token"
+	pathUserInfo   = "/userinfo"
+	pathIntrospect = "/oauth/introspect"
+	pathRevoke     = "/oauth/revoke"
+	pathJWKS       = "/.well-known/jwks.json"
+)
+
+// maximum length of the nonce of an authentication request
+const maxNonceLength = 255
+
+var (
+	ErrInsufficientScope = errors.New("the access token was not granted the openid scope")
+	ErrInvalidToken      = errors.New("the access token is no longer valid")
+)
+
+// Authentication represents how and when the user authenticated on the authorization page
+type Authentication struct {
+	UserID   int
+	AuthTime time.Time
+	// Methods are the amr values, e.g. pwd alone, pwd, otp and mfa, or pwd and mfa for a recovery code
+	Methods []string
+}
+
+// ProviderMetadata represents the OpenID Connect discovery document
+type ProviderMetadata struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	ResponseModesSupported            []string `json:"response_modes_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// UserInfo represents the claims returned by the userinfo endpoint
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// Discovery returns the provider metadata, the endpoints are located under the issuer
+func (service *OAuthService) Discovery() ProviderMetadata {
+	issuer := service.tokenConfig.TokenIssuer()
+	baseURL := strings.TrimRight(issuer, "/")
+
+	scopes := []string{SCOPE_PROFILE, SCOPE_EMAIL}
+	if service.tokenConfig.SupportsIDTokens() {
+		scopes = append([]string{SCOPE_OPENID}, scopes...)
+	}
+
+	return ProviderMetadata{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             baseURL + pathAuthorize,
+		TokenEndpoint:                     baseURL + pathToken,
+		UserInfoEndpoint:                  baseURL + pathUserInfo,
+		IntrospectionEndpoint:             baseURL + pathIntrospect,
+		RevocationEndpoint:                baseURL + pathRevoke,
+		JWKSURI:                           baseURL + pathJWKS,
+		ScopesSupported:                   scopes,
+		ResponseTypesSupported:            []string{RESPONSE_TYPE_CODE},
+		ResponseModesSupported:            []string{"query"},
+		GrantTypesSupported:               []string{GRANT_TYPE_AUTHORIZATION_CODE, GRANT_TYPE_REFRESH_TOKEN, GRANT_TYPE_CLIENT_CREDENTIALS},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{service.tokenConfig.TokenSigningAlgorithm()},
+		TokenEndpointAuthMethodsSupported: []string{"none", "client_secret_basic", "client_secret_post"},
+		CodeChallengeMethodsSupported:     []string{pkce.METHOD_S256},
+		ClaimsSupported: []string{
+			"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "amr",
+			"preferred_username", "email", "email_verified",
+		},
+	}
+}
+
+// UserInfo returns the claims of the user the access token was issued for, filtered by
+// the scope granted to the client
+func (service *OAuthService) UserInfo(principal auth.Principal) (UserInfo, error) {
+	if !principal.HasScope(SCOPE_OPENID) {
+		return UserInfo{}, ErrInsufficientScope
+	}
+
+	dbUser, err := service.userRepo.GetByID(principal.UserID)
+	if err != nil {
+		log.Printf("userinfo user fetch failed: %v", err)
+		return UserInfo{}, ErrInvalidToken
+	}
+	if !dbUser.IsActive {
+		return UserInfo{}, ErrInvalidToken
+	}
+
+	info := UserInfo{Subject: strconv.Itoa(dbUser.ID)}
+	if principal.HasScope(SCOPE_PROFILE) {
+		info.PreferredUsername = dbUser.Username
+	}
+	if principal.HasScope(SCOPE_EMAIL) {
+		verified := dbUser.EmailVerifiedAt != nil
+		info.Email = dbUser.Email
+		info.EmailVerified = &verified
+	}
+
+	return info, nil
+}
+
+// idToken signs the ID token of an authorization code granted the openid scope
+func (service *OAuthService) idToken(code AuthorizationCode) (string, error) {
+	dbUser, err := service.userRepo.GetByID(code.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	scopes := parseScope(code.Scope)
+	idToken := jwt.IDToken{
+		Subject:  strconv.Itoa(dbUser.ID),
+		Audience: code.ClientID,
+		Nonce:    code.Nonce,
+		AuthTime: code.AuthTime,
+		AMR:      code.AMR,
+	}
+	if hasScope(scopes, SCOPE_PROFILE) {
+		idToken.PreferredUsername = dbUser.Username
+	}
+	if hasScope(scopes, SCOPE_EMAIL) {
+		verified := dbUser.EmailVerifiedAt != nil
+		idToken.Email = dbUser.Email
+		idToken.EmailVerified = &verified
+	}
+
+	return jwt.NewAuthToken(service.tokenConfig).CreateIDToken(idToken)
+}
+
+// hasScope reports whether the scope is part of the scopes
+func hasScope(scopes []string, scope string) bool {
+	for _, s := range scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
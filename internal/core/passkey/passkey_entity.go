@@ -0,0 +1,35 @@
+package passkey
+
+import "time"
+
+// ceremonies a challenge is issued for
+const (
+	CEREMONY_REGISTRATION   = "registration"
+	CEREMONY_AUTHENTICATION = "authentication"
+)
+
+// Credential represents a WebAuthn credential (passkey) registered by a user
+type Credential struct {
+	ID           int        `json:"id"`
+	UserID       int        `json:"-"`
+	CredentialID []byte     `json:"-"`
+	PublicKey    []byte     `json:"-"`
+	Algorithm    int        `json:"algorithm"`
+	SignCount    uint32     `json:"-"`
+	AAGUID       []byte     `json:"-"`
+	Transports   []string   `json:"transports"`
+	Name         string     `json:"name"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// Challenge represents a pending ceremony, only the hash of the challenge is persisted
+type Challenge struct {
+	ID            int
+	ChallengeHash string
+	Ceremony      string
+	// UserID is the user the ceremony is bound to, 0 for a usernameless login
+	UserID    int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,12 @@
+package passkey
+
+type PasskeyRepoPort interface {
+	CreateCredential(credential Credential) (Credential, error)
+	GetCredential(credentialID []byte) (Credential, error)
+	GetCredentialsByUser(userID int) ([]Credential, error)
+	UpdateSignCount(id int, signCount uint32) (bool, error)
+	DeleteCredential(userID int, id int) (bool, error)
+	CreateChallenge(challenge Challenge) error
+	ConsumeChallenge(challengeHash string, ceremony string) (Challenge, error)
+	DeleteExpiredChallenges() error
+}
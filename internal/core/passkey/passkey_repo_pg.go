@@ -0,0 +1,230 @@
+package passkey
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+)
+
+const (
+	wcTable           = "webauthn_credentials"
+	wcColID           = "id"
+	wcColUserID       = "user_id"
+	wcColCredentialID = "credential_id"
+	wcColSignCount    = "sign_count"
+	wcColLastUsedAt   = "last_used_at"
+	wcColCreatedAt    = "created_at"
+
+	chTable            = "webauthn_challenges"
+	chColChallengeHash = "challenge_hash"
+	chColCeremony      = "ceremony"
+	chColExpiresAt     = "expires_at"
+)
+
+type repoCredential struct {
+	ID           int          `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID       int          `gorm:"column:user_id"`
+	CredentialID []byte       `gorm:"column:credential_id"`
+	PublicKey    []byte       `gorm:"column:public_key"`
+	Algorithm    int          `gorm:"column:algorithm"`
+	SignCount    int64        `gorm:"column:sign_count"`
+	AAGUID       []byte       `gorm:"column:aaguid"`
+	Transports   string       `gorm:"column:transports"`
+	Name         string       `gorm:"column:name"`
+	LastUsedAt   sql.NullTime `gorm:"column:last_used_at"`
+	CreatedAt    time.Time    `gorm:"column:created_at"`
+}
+
+func (repoCredential) TableName() string {
+	return wcTable
+}
+
+type repoChallenge struct {
+	ID            int           `gorm:"column:id;primaryKey;autoIncrement"`
+	ChallengeHash string        `gorm:"column:challenge_hash"`
+	Ceremony      string        `gorm:"column:ceremony"`
+	UserID        sql.NullInt64 `gorm:"column:user_id"`
+	ExpiresAt     time.Time     `gorm:"column:expires_at"`
+	CreatedAt     time.Time     `gorm:"column:created_at"`
+}
+
+func (repoChallenge) TableName() string {
+	return chTable
+}
+
+// PasskeyRepo represents the WebAuthn credential repository
+type PasskeyRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewPasskeyRepo(pgClient *postgres.PGClient) *PasskeyRepo {
+	return &PasskeyRepo{
+		pgClient: pgClient,
+	}
+}
+
+func toRepoCredential(credential Credential) repoCredential {
+	return repoCredential{
+		ID:           credential.ID,
+		UserID:       credential.UserID,
+		CredentialID: credential.CredentialID,
+		PublicKey:    credential.PublicKey,
+		Algorithm:    credential.Algorithm,
+		SignCount:    int64(credential.SignCount),
+		AAGUID:       credential.AAGUID,
+		Transports:   strings.Join(credential.Transports, ","),
+		Name:         credential.Name,
+		CreatedAt:    time.Now(),
+	}
+}
+
+func toEntityCredential(credential repoCredential) Credential {
+	entity := Credential{
+		ID:           credential.ID,
+		UserID:       credential.UserID,
+		CredentialID: credential.CredentialID,
+		PublicKey:    credential.PublicKey,
+		Algorithm:    credential.Algorithm,
+		SignCount:    uint32(credential.SignCount),
+		AAGUID:       credential.AAGUID,
+		Transports:   []string{},
+		Name:         credential.Name,
+		CreatedAt:    credential.CreatedAt,
+	}
+	if credential.Transports != "" {
+		entity.Transports = strings.Split(credential.Transports, ",")
+	}
+	if credential.LastUsedAt.Valid {
+		entity.LastUsedAt = &credential.LastUsedAt.Time
+	}
+
+	return entity
+}
+
+func (repo *PasskeyRepo) CreateCredential(credential Credential) (Credential, error) {
+
+	rCredential := toRepoCredential(credential)
+	if err := repo.pgClient.DB.Create(&rCredential).Error; err != nil {
+		return Credential{}, fmt.Errorf("passkey create failed: %v", err)
+	}
+
+	return toEntityCredential(rCredential), nil
+}
+
+func (repo *PasskeyRepo) GetCredential(credentialID []byte) (Credential, error) {
+
+	var rCredential repoCredential
+	err := repo.pgClient.DB.Where(wcColCredentialID+" = ?", credentialID).First(&rCredential).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Credential{}, ErrCredentialNotFound
+	}
+	if err != nil {
+		return Credential{}, fmt.Errorf("passkey fetch failed: %v", err)
+	}
+
+	return toEntityCredential(rCredential), nil
+}
+
+func (repo *PasskeyRepo) GetCredentialsByUser(userID int) ([]Credential, error) {
+
+	var rCredentials []repoCredential
+	err := repo.pgClient.DB.Where(wcColUserID+" = ?", userID).Order(wcColCreatedAt).Find(&rCredentials).Error
+	if err != nil {
+		return []Credential{}, fmt.Errorf("passkeys fetch failed: %v", err)
+	}
+
+	credentials := make([]Credential, 0, len(rCredentials))
+	for _, c := range rCredentials {
+		credentials = append(credentials, toEntityCredential(c))
+	}
+
+	return credentials, nil
+}
+
+// UpdateSignCount stores the counter of a successful login, it reports false when a
+// concurrent login already stored the same or a later counter
+func (repo *PasskeyRepo) UpdateSignCount(id int, signCount uint32) (bool, error) {
+
+	query := repo.pgClient.DB.Model(&repoCredential{}).Where(wcColID+" = ?", id)
+	// authenticators without a counter always report zero
+	if signCount > 0 {
+		query = query.Where(wcColSignCount+" < ?", int64(signCount))
+	}
+
+	res := query.Updates(map[string]any{wcColSignCount: int64(signCount), wcColLastUsedAt: time.Now()})
+	if res.Error != nil {
+		return false, fmt.Errorf("passkey sign count update failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+// DeleteCredential deletes a credential of the user, it reports false when the user has no such credential
+func (repo *PasskeyRepo) DeleteCredential(userID int, id int) (bool, error) {
+
+	res := repo.pgClient.DB.Where(wcColID+" = ? AND "+wcColUserID+" = ?", id, userID).Delete(&repoCredential{})
+	if res.Error != nil {
+		return false, fmt.Errorf("passkey delete failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
+
+func (repo *PasskeyRepo) CreateChallenge(challenge Challenge) error {
+
+	rChallenge := repoChallenge{
+		ChallengeHash: challenge.ChallengeHash,
+		Ceremony:      challenge.Ceremony,
+		UserID:        sql.NullInt64{Int64: int64(challenge.UserID), Valid: challenge.UserID > 0},
+		ExpiresAt:     challenge.ExpiresAt,
+		CreatedAt:     time.Now(),
+	}
+	if err := repo.pgClient.DB.Create(&rChallenge).Error; err != nil {
+		return fmt.Errorf("webauthn challenge create failed: %v", err)
+	}
+
+	return nil
+}
+
+// ConsumeChallenge atomically deletes an unexpired challenge and returns it, a challenge
+// can therefore only be answered once
+func (repo *PasskeyRepo) ConsumeChallenge(challengeHash string, ceremony string) (Challenge, error) {
+
+	var rChallenges []repoChallenge
+	err := repo.pgClient.DB.Raw(
+		"DELETE FROM "+chTable+" WHERE "+chColChallengeHash+" = ? AND "+chColCeremony+" = ? AND "+chColExpiresAt+" > ? RETURNING *",
+		challengeHash, ceremony, time.Now(),
+	).Scan(&rChallenges).Error
+	if err != nil {
+		return Challenge{}, fmt.Errorf("webauthn challenge consume failed: %v", err)
+	}
+
+	if len(rChallenges) == 0 {
+		return Challenge{}, ErrInvalidChallenge
+	}
+
+	return Challenge{
+		ID:            rChallenges[0].ID,
+		ChallengeHash: rChallenges[0].ChallengeHash,
+		Ceremony:      rChallenges[0].Ceremony,
+		UserID:        int(rChallenges[0].UserID.Int64),
+		ExpiresAt:     rChallenges[0].ExpiresAt,
+		CreatedAt:     rChallenges[0].CreatedAt,
+	}, nil
+}
+
+// DeleteExpiredChallenges deletes the challenges of abandoned ceremonies
+func (repo *PasskeyRepo) DeleteExpiredChallenges() error {
+
+	err := repo.pgClient.DB.Where(chColExpiresAt+" <= ?", time.Now()).Delete(&repoChallenge{}).Error
+	if err != nil {
+		return fmt.Errorf("webauthn challenges purge failed: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,277 @@
+package passkey
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/securetoken"
+	"user-authentication/pkg/webauthn"
+)
+
+const (
+	// default relying party name shown by the authenticators when none is configured
+	defaultRPName = "user-authentication"
+	// name given to a passkey registered without one
+	defaultPasskeyName = "Passkey"
+	// maximum length of a passkey name
+	maxPasskeyNameLength = 100
+)
+
+var (
+	ErrCredentialNotFound       = errors.New("passkey: credential not found")
+	ErrInvalidChallenge         = errors.New("passkey: invalid or expired challenge")
+	ErrPasskeyVerification      = errors.New("passkey: verification failed")
+	ErrPasskeyAlreadyRegistered = errors.New("passkey: credential already registered")
+	ErrInvalidPasskeyName       = errors.New("passkey: invalid name")
+	ErrPasskey                  = errors.New("passkey: operation failed")
+)
+
+// PasskeyConfig represents the WebAuthn relying party settings
+type PasskeyConfig struct {
+	// RPID is the relying party id, the domain the passkeys are scoped to
+	RPID string
+	// RPName is the name authenticators show for the relying party
+	RPName string
+	// Origins are the origins allowed to run the ceremonies
+	Origins []string
+}
+
+// PasskeyServicePort represents the passkey service port
+type PasskeyServicePort interface {
+	BeginRegistration(userID int) (webauthn.CreationOptions, error)
+	FinishRegistration(userID int, name string, resp webauthn.RegistrationResponse) (Credential, error)
+	BeginLogin() (webauthn.RequestOptions, error)
+	FinishLogin(resp webauthn.AssertionResponse) (int, error)
+	Get(userID int) ([]Credential, error)
+	Delete(userID int, id int) error
+}
+
+// PasskeyService registers WebAuthn credentials and verifies the passkey logins
+type PasskeyService struct {
+	repo     PasskeyRepoPort
+	userRepo user.UserRepoPort
+	rp       *webauthn.RelyingParty
+}
+
+// NewPasskeyService creates a new passkey service
+func NewPasskeyService(config PasskeyConfig, repo PasskeyRepoPort, userRepo user.UserRepoPort) *PasskeyService {
+	if config.RPName == "" {
+		config.RPName = defaultRPName
+	}
+
+	return &PasskeyService{
+		repo:     repo,
+		userRepo: userRepo,
+		rp:       webauthn.NewRelyingParty(config.RPID, config.RPName, config.Origins),
+	}
+}
+
+// userHandle returns the WebAuthn user handle of the user, its id as 8 big-endian bytes.
+// It carries no personal information as required by the specification.
+func userHandle(userID int) []byte {
+	handle := make([]byte, 8)
+	binary.BigEndian.PutUint64(handle, uint64(userID))
+	return handle
+}
+
+// descriptors describes the credentials for the allow and exclude lists
+func descriptors(credentials []Credential) []webauthn.CredentialDescriptor {
+	list := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, c := range credentials {
+		list = append(list, webauthn.NewCredentialDescriptor(c.CredentialID, c.Transports))
+	}
+	return list
+}
+
+// newChallenge generates and stores the challenge of a ceremony
+func (service *PasskeyService) newChallenge(ceremony string, userID int) (string, error) {
+	// abandoned ceremonies are purged lazily
+	if err := service.repo.DeleteExpiredChallenges(); err != nil {
+		log.Printf("expired webauthn challenges purge failed: %v", err)
+	}
+
+	challenge, err := webauthn.NewChallenge()
+	if err != nil {
+		log.Printf("webauthn challenge creation failed: %v", err)
+		return "", ErrPasskey
+	}
+
+	err = service.repo.CreateChallenge(Challenge{
+		ChallengeHash: securetoken.Hash(challenge),
+		Ceremony:      ceremony,
+		UserID:        userID,
+		ExpiresAt:     time.Now().Add(webauthn.CeremonyTimeout),
+	})
+	if err != nil {
+		log.Printf("webauthn challenge store failed: %v", err)
+		return "", ErrPasskey
+	}
+
+	return challenge, nil
+}
+
+// BeginRegistration returns the options to create a new passkey for the user, the
+// passkeys already registered are excluded
+func (service *PasskeyService) BeginRegistration(userID int) (webauthn.CreationOptions, error) {
+	dbUser, err := service.userRepo.GetByID(userID)
+	if err != nil {
+		return webauthn.CreationOptions{}, user.ErrInvalidUserID
+	}
+
+	credentials, err := service.repo.GetCredentialsByUser(userID)
+	if err != nil {
+		log.Printf("passkeys fetch failed: %v", err)
+		return webauthn.CreationOptions{}, ErrPasskey
+	}
+
+	challenge, err := service.newChallenge(CEREMONY_REGISTRATION, userID)
+	if err != nil {
+		return webauthn.CreationOptions{}, err
+	}
+
+	entity := webauthn.UserEntity{
+		ID:          webauthn.EncodeBase64URL(userHandle(userID)),
+		Name:        dbUser.Username,
+		DisplayName: dbUser.Username,
+	}
+
+	return service.rp.CreationOptions(challenge, entity, descriptors(credentials)), nil
+}
+
+// FinishRegistration verifies the registration response against a challenge issued to the
+// user and stores the new credential
+func (service *PasskeyService) FinishRegistration(userID int, name string, resp webauthn.RegistrationResponse) (Credential, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+	if utf8.RuneCountInString(name) > maxPasskeyNameLength {
+		return Credential{}, ErrInvalidPasskeyName
+	}
+
+	registration, err := service.rp.VerifyRegistration(resp)
+	if err != nil {
+		log.Printf("passkey registration rejected for user %d: %v", userID, err)
+		return Credential{}, ErrPasskeyVerification
+	}
+
+	challenge, err := service.repo.ConsumeChallenge(securetoken.Hash(registration.Challenge), CEREMONY_REGISTRATION)
+	if err != nil {
+		log.Printf("passkey registration challenge consume failed: %v", err)
+		return Credential{}, ErrInvalidChallenge
+	}
+	if challenge.UserID != userID {
+		return Credential{}, ErrInvalidChallenge
+	}
+
+	if _, err := service.repo.GetCredential(registration.CredentialID); err == nil {
+		return Credential{}, ErrPasskeyAlreadyRegistered
+	}
+
+	credential, err := service.repo.CreateCredential(Credential{
+		UserID:       userID,
+		CredentialID: registration.CredentialID,
+		PublicKey:    registration.PublicKey,
+		Algorithm:    registration.Algorithm,
+		SignCount:    registration.SignCount,
+		AAGUID:       registration.AAGUID,
+		Transports:   registration.Transports,
+		Name:         name,
+	})
+	if err != nil {
+		log.Printf("passkey store failed: %v", err)
+		return Credential{}, ErrPasskey
+	}
+
+	return credential, nil
+}
+
+// BeginLogin returns the options of a usernameless passkey login, any discoverable credential
+// is accepted. The options never list the credentials of an account, so they don't tell which
+// usernames exist or have passkeys.
+func (service *PasskeyService) BeginLogin() (webauthn.RequestOptions, error) {
+	challenge, err := service.newChallenge(CEREMONY_AUTHENTICATION, 0)
+	if err != nil {
+		return webauthn.RequestOptions{}, err
+	}
+
+	return service.rp.RequestOptions(challenge, nil), nil
+}
+
+// FinishLogin verifies the assertion against an issued challenge and the stored credential,
+// and returns the id of the user the passkey belongs to
+func (service *PasskeyService) FinishLogin(resp webauthn.AssertionResponse) (int, error) {
+	assertion, err := service.rp.ParseAssertion(resp)
+	if err != nil {
+		log.Printf("passkey assertion rejected: %v", err)
+		return 0, ErrPasskeyVerification
+	}
+
+	challenge, err := service.repo.ConsumeChallenge(securetoken.Hash(assertion.Challenge), CEREMONY_AUTHENTICATION)
+	if err != nil {
+		log.Printf("passkey login challenge consume failed: %v", err)
+		return 0, ErrInvalidChallenge
+	}
+
+	credential, err := service.repo.GetCredential(assertion.CredentialID)
+	if err != nil {
+		log.Printf("passkey login credential fetch failed: %v", err)
+		return 0, ErrPasskeyVerification
+	}
+
+	// a ceremony started for an account can only be completed with its passkeys
+	if challenge.UserID != 0 && challenge.UserID != credential.UserID {
+		return 0, ErrPasskeyVerification
+	}
+	if len(assertion.UserHandle) > 0 && !bytes.Equal(assertion.UserHandle, userHandle(credential.UserID)) {
+		return 0, ErrPasskeyVerification
+	}
+
+	signCount, err := service.rp.VerifyAssertion(assertion, credential.PublicKey, credential.SignCount)
+	if err != nil {
+		log.Printf("passkey login rejected for user %d: %v", credential.UserID, err)
+		return 0, ErrPasskeyVerification
+	}
+
+	updated, err := service.repo.UpdateSignCount(credential.ID, signCount)
+	if err != nil {
+		log.Printf("passkey sign count update failed: %v", err)
+		return 0, ErrPasskey
+	}
+	if !updated {
+		log.Printf("passkey %d of user %d replayed a sign counter", credential.ID, credential.UserID)
+		return 0, ErrPasskeyVerification
+	}
+
+	return credential.UserID, nil
+}
+
+// Get returns the passkeys registered by the user
+func (service *PasskeyService) Get(userID int) ([]Credential, error) {
+	credentials, err := service.repo.GetCredentialsByUser(userID)
+	if err != nil {
+		log.Printf("passkeys fetch failed: %v", err)
+		return []Credential{}, ErrPasskey
+	}
+
+	return credentials, nil
+}
+
+// Delete removes a passkey of the user
+func (service *PasskeyService) Delete(userID int, id int) error {
+	deleted, err := service.repo.DeleteCredential(userID, id)
+	if err != nil {
+		log.Printf("passkey delete failed: %v", err)
+		return ErrPasskey
+	}
+	if !deleted {
+		return ErrCredentialNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,26 @@
+package rbac
+
+// default roles seeded by the migrations
+const (
+	ROLE_ADMIN = "admin"
+	ROLE_USER  = "user"
+)
+
+// permissions checked by the router
+const (
+	PERMISSION_USERS_READ  = "users:read"
+	PERMISSION_USERS_WRITE = "users:write"
+	PERMISSION_ROLES_READ  = "roles:read"
+	PERMISSION_ROLES_WRITE = "roles:write"
+
+	PERMISSION_CLIENTS_READ  = "clients:read"
+	PERMISSION_CLIENTS_WRITE = "clients:write"
+)
+
+// Role represents a named set of permissions
+type Role struct {
+	ID          int      `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Permissions []string `json:"permissions"`
+}
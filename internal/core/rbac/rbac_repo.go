@@ -0,0 +1,9 @@
+package rbac
+
+type RBACRepoPort interface {
+	GetRoles() ([]Role, error)
+	GetUserRoles(userID int) ([]string, error)
+	AssignRole(userID int, role string) error
+	RemoveRole(userID int, role string) error
+	CountUsersWithRole(role string) (int64, error)
+}
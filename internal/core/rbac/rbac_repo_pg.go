@@ -0,0 +1,180 @@
+package rbac
+
+import (
+	"errors"
+	"fmt"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	roleTable           = "roles"
+	roleColID           = "id"
+	roleColName         = "name"
+	userRoleTable       = "user_roles"
+	userRoleColUserID   = "user_id"
+	userRoleColRoleID   = "role_id"
+	rolePermissionTable = "role_permissions"
+	permissionTable     = "permissions"
+)
+
+type repoRole struct {
+	ID          int    `gorm:"column:id;primaryKey;autoIncrement"`
+	Name        string `gorm:"column:name"`
+	Description string `gorm:"column:description"`
+}
+
+func (repoRole) TableName() string {
+	return roleTable
+}
+
+type repoUserRole struct {
+	UserID    int       `gorm:"column:user_id;primaryKey"`
+	RoleID    int       `gorm:"column:role_id;primaryKey"`
+	CreatedAt time.Time `gorm:"column:created_at"`
+}
+
+func (repoUserRole) TableName() string {
+	return userRoleTable
+}
+
+// repoRolePermission is the projection of a role to permission grant
+type repoRolePermission struct {
+	RoleID         int    `gorm:"column:role_id"`
+	PermissionName string `gorm:"column:permission_name"`
+}
+
+// RBACRepo represents the role based access control repository
+type RBACRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewRBACRepo(pgClient *postgres.PGClient) *RBACRepo {
+	return &RBACRepo{
+		pgClient: pgClient,
+	}
+}
+
+func (repo *RBACRepo) GetRoles() ([]Role, error) {
+
+	var rRoles []repoRole
+	if err := repo.pgClient.DB.Order(roleColID).Find(&rRoles).Error; err != nil {
+		return []Role{}, fmt.Errorf("roles fetch failed: %v", err)
+	}
+
+	var grants []repoRolePermission
+	err := repo.pgClient.DB.Table(rolePermissionTable + " rp").
+		Select("rp.role_id AS role_id, p.name AS permission_name").
+		Joins("JOIN " + permissionTable + " p ON p.id = rp.permission_id").
+		Scan(&grants).Error
+	if err != nil {
+		return []Role{}, fmt.Errorf("role permissions fetch failed: %v", err)
+	}
+
+	permissions := map[int][]string{}
+	for _, g := range grants {
+		permissions[g.RoleID] = append(permissions[g.RoleID], g.PermissionName)
+	}
+
+	roles := make([]Role, 0, len(rRoles))
+	for _, r := range rRoles {
+		rolePermissions := permissions[r.ID]
+		if rolePermissions == nil {
+			rolePermissions = []string{}
+		}
+		roles = append(roles, Role{
+			ID:          r.ID,
+			Name:        r.Name,
+			Description: r.Description,
+			Permissions: rolePermissions,
+		})
+	}
+
+	return roles, nil
+}
+
+func (repo *RBACRepo) GetUserRoles(userID int) ([]string, error) {
+
+	var roles []string
+	err := repo.pgClient.DB.Table(userRoleTable+" ur").
+		Joins("JOIN "+roleTable+" r ON r.id = ur.role_id").
+		Where("ur."+userRoleColUserID+" = ?", userID).
+		Order("r.name").
+		Pluck("r.name", &roles).Error
+	if err != nil {
+		return []string{}, fmt.Errorf("user roles fetch failed: %v", err)
+	}
+
+	return roles, nil
+}
+
+// getRoleID returns the ID of the role with the given name
+func (repo *RBACRepo) getRoleID(role string) (int, error) {
+
+	var rRole repoRole
+	err := repo.pgClient.DB.Where(roleColName+" = ?", role).First(&rRole).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, ErrRoleNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("role fetch failed: %v", err)
+	}
+
+	return rRole.ID, nil
+}
+
+func (repo *RBACRepo) AssignRole(userID int, role string) error {
+
+	roleID, err := repo.getRoleID(role)
+	if err != nil {
+		return err
+	}
+
+	// assigning a role twice is a no-op
+	err = repo.pgClient.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&repoUserRole{
+		UserID:    userID,
+		RoleID:    roleID,
+		CreatedAt: time.Now(),
+	}).Error
+	if err != nil {
+		return fmt.Errorf("role assign failed: %v", err)
+	}
+
+	return nil
+}
+
+func (repo *RBACRepo) RemoveRole(userID int, role string) error {
+
+	roleID, err := repo.getRoleID(role)
+	if err != nil {
+		return err
+	}
+
+	err = repo.pgClient.DB.
+		Where(userRoleColUserID+" = ? AND "+userRoleColRoleID+" = ?", userID, roleID).
+		Delete(&repoUserRole{}).Error
+	if err != nil {
+		return fmt.Errorf("role remove failed: %v", err)
+	}
+
+	return nil
+}
+
+func (repo *RBACRepo) CountUsersWithRole(role string) (int64, error) {
+
+	roleID, err := repo.getRoleID(role)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	err = repo.pgClient.DB.Model(&repoUserRole{}).Where(userRoleColRoleID+" = ?", roleID).Count(&count).Error
+	if err != nil {
+		return 0, fmt.Errorf("role users count failed: %v", err)
+	}
+
+	return count, nil
+}
@@ -0,0 +1,160 @@
+package rbac
+
+import (
+	"errors"
+	"log"
+	"slices"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleNotAssigned   = errors.New("role not assigned to the user")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrAdminExists       = errors.New("an administrator already exists")
+	ErrLastAdministrator = errors.New("cannot remove the last administrator")
+	ErrInvalidRoleUserID = errors.New("invalid user id")
+)
+
+// RBACServicePort represents the role based access control service port
+type RBACServicePort interface {
+	GetRoles() ([]Role, error)
+	GetUserRoles(userID int) ([]string, error)
+	Permissions(roles []string) []string
+	AssignRole(userID int, role string) error
+	RemoveRole(userID int, role string) error
+	AssignDefaultRole(userID int) error
+	BootstrapAdmin(userID int) error
+}
+
+// RBACService resolves roles to permissions, the role permissions are cached in memory
+// since they only change through migrations
+type RBACService struct {
+	repo            RBACRepoPort
+	mu              sync.RWMutex
+	rolePermissions map[string][]string
+}
+
+func NewRBACService(repo RBACRepoPort) *RBACService {
+	return &RBACService{
+		repo:            repo,
+		rolePermissions: map[string][]string{},
+	}
+}
+
+// Load caches the permissions granted to every role
+func (service *RBACService) Load() error {
+	roles, err := service.repo.GetRoles()
+	if err != nil {
+		return err
+	}
+
+	rolePermissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		rolePermissions[role.Name] = role.Permissions
+	}
+
+	service.mu.Lock()
+	service.rolePermissions = rolePermissions
+	service.mu.Unlock()
+
+	return nil
+}
+
+func (service *RBACService) GetRoles() ([]Role, error) {
+	return service.repo.GetRoles()
+}
+
+func (service *RBACService) GetUserRoles(userID int) ([]string, error) {
+	if userID <= 0 {
+		return []string{}, ErrInvalidRoleUserID
+	}
+
+	return service.repo.GetUserRoles(userID)
+}
+
+// Permissions returns the sorted union of the permissions granted to the roles
+func (service *RBACService) Permissions(roles []string) []string {
+	service.mu.RLock()
+	defer service.mu.RUnlock()
+
+	unique := map[string]struct{}{}
+	for _, role := range roles {
+		for _, permission := range service.rolePermissions[role] {
+			unique[permission] = struct{}{}
+		}
+	}
+
+	permissions := make([]string, 0, len(unique))
+	for permission := range unique {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+
+	return permissions
+}
+
+func (service *RBACService) AssignRole(userID int, role string) error {
+	if userID <= 0 {
+		return ErrInvalidRoleUserID
+	}
+	if role == "" {
+		return ErrInvalidRole
+	}
+
+	return service.repo.AssignRole(userID, role)
+}
+
+func (service *RBACService) RemoveRole(userID int, role string) error {
+	if userID <= 0 {
+		return ErrInvalidRoleUserID
+	}
+	if role == "" {
+		return ErrInvalidRole
+	}
+
+	roles, err := service.repo.GetUserRoles(userID)
+	if err != nil {
+		return err
+	}
+	if !slices.Contains(roles, role) {
+		return ErrRoleNotAssigned
+	}
+
+	// the service must always keep at least one administrator
+	if role == ROLE_ADMIN {
+		admins, err := service.repo.CountUsersWithRole(ROLE_ADMIN)
+		if err != nil {
+			return err
+		}
+		if admins <= 1 {
+			return ErrLastAdministrator
+		}
+	}
+
+	return service.repo.RemoveRole(userID, role)
+}
+
+// AssignDefaultRole grants the self-service role to a newly created account
+func (service *RBACService) AssignDefaultRole(userID int) error {
+	return service.AssignRole(userID, ROLE_USER)
+}
+
+// BootstrapAdmin seeds the first administrator, it fails once an administrator exists
+func (service *RBACService) BootstrapAdmin(userID int) error {
+	admins, err := service.repo.CountUsersWithRole(ROLE_ADMIN)
+	if err != nil {
+		return err
+	}
+	if admins > 0 {
+		return ErrAdminExists
+	}
+
+	if err := service.AssignRole(userID, ROLE_ADMIN); err != nil {
+		return err
+	}
+	log.Printf("user %d bootstrapped as the first administrator", userID)
+
+	return nil
+}
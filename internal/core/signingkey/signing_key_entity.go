@@ -0,0 +1,28 @@
+package signingkey
+
+import "time"
+
+// signing key lifecycle statuses
+const (
+	// STATUS_PENDING keys are published for verification but not yet used for signing
+	STATUS_PENDING = "pending"
+	// STATUS_ACTIVE is the single key new tokens are signed with
+	STATUS_ACTIVE = "active"
+	// STATUS_RETIRING keys still verify tokens until their grace period ends
+	STATUS_RETIRING = "retiring"
+	// STATUS_RETIRED keys are no longer accepted
+	STATUS_RETIRED = "retired"
+)
+
+// SigningKey represents the persisted metadata of a token signing key,
+// the private key itself is kept as a PEM file in the key directory
+type SigningKey struct {
+	ID          int        `json:"id"`
+	KeyID       string     `json:"kid"`
+	Algorithm   string     `json:"alg"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ActivatedAt *time.Time `json:"activated_at,omitempty"`
+	RetireAt    *time.Time `json:"retire_at,omitempty"`
+	RetiredAt   *time.Time `json:"retired_at,omitempty"`
+}
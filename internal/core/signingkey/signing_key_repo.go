@@ -0,0 +1,11 @@
+package signingkey
+
+import "time"
+
+type SigningKeyRepoPort interface {
+	Create(key SigningKey) (SigningKey, error)
+	Get() ([]SigningKey, error)
+	GetByStatus(statuses ...string) ([]SigningKey, error)
+	Activate(keyID string, retireAt time.Time) error
+	RetireExpired() (int64, error)
+}
@@ -0,0 +1,157 @@
+package signingkey
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+)
+
+const (
+	skTable          = "signing_keys"
+	skColKeyID       = "kid"
+	skColStatus      = "status"
+	skColActivatedAt = "activated_at"
+	skColRetireAt    = "retire_at"
+	skColRetiredAt   = "retired_at"
+	skColCreatedAt   = "created_at"
+)
+
+type repoSigningKey struct {
+	ID          int          `gorm:"column:id;primaryKey;autoIncrement"`
+	KeyID       string       `gorm:"column:kid"`
+	Algorithm   string       `gorm:"column:algorithm"`
+	Status      string       `gorm:"column:status"`
+	ActivatedAt sql.NullTime `gorm:"column:activated_at"`
+	RetireAt    sql.NullTime `gorm:"column:retire_at"`
+	RetiredAt   sql.NullTime `gorm:"column:retired_at"`
+	CreatedAt   time.Time    `gorm:"column:created_at"`
+}
+
+func (repoSigningKey) TableName() string {
+	return skTable
+}
+
+// SigningKeyRepo represents the signing key repository
+type SigningKeyRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewSigningKeyRepo(pgClient *postgres.PGClient) *SigningKeyRepo {
+	return &SigningKeyRepo{
+		pgClient: pgClient,
+	}
+}
+
+func toRepoSigningKey(key SigningKey) repoSigningKey {
+	return repoSigningKey{
+		ID:        key.ID,
+		KeyID:     key.KeyID,
+		Algorithm: key.Algorithm,
+		Status:    key.Status,
+		CreatedAt: time.Now(),
+	}
+}
+
+func toNullableTime(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+
+	return &t.Time
+}
+
+func toEntitySigningKey(key repoSigningKey) SigningKey {
+	return SigningKey{
+		ID:          key.ID,
+		KeyID:       key.KeyID,
+		Algorithm:   key.Algorithm,
+		Status:      key.Status,
+		CreatedAt:   key.CreatedAt,
+		ActivatedAt: toNullableTime(key.ActivatedAt),
+		RetireAt:    toNullableTime(key.RetireAt),
+		RetiredAt:   toNullableTime(key.RetiredAt),
+	}
+}
+
+func toEntitySigningKeys(repoKeys []repoSigningKey) []SigningKey {
+	keys := make([]SigningKey, 0, len(repoKeys))
+	for _, k := range repoKeys {
+		keys = append(keys, toEntitySigningKey(k))
+	}
+
+	return keys
+}
+
+func (repo *SigningKeyRepo) Create(key SigningKey) (SigningKey, error) {
+
+	rKey := toRepoSigningKey(key)
+	if err := repo.pgClient.DB.Create(&rKey).Error; err != nil {
+		return SigningKey{}, fmt.Errorf("signing key create failed: %v", err)
+	}
+
+	return toEntitySigningKey(rKey), nil
+}
+
+func (repo *SigningKeyRepo) Get() ([]SigningKey, error) {
+
+	var rKeys []repoSigningKey
+	if err := repo.pgClient.DB.Order(skColCreatedAt).Find(&rKeys).Error; err != nil {
+		return []SigningKey{}, fmt.Errorf("signing keys fetch failed: %v", err)
+	}
+
+	return toEntitySigningKeys(rKeys), nil
+}
+
+func (repo *SigningKeyRepo) GetByStatus(statuses ...string) ([]SigningKey, error) {
+
+	var rKeys []repoSigningKey
+	err := repo.pgClient.DB.Where(skColStatus+" IN ?", statuses).Order(skColCreatedAt).Find(&rKeys).Error
+	if err != nil {
+		return []SigningKey{}, fmt.Errorf("signing keys fetch failed: %v", err)
+	}
+
+	return toEntitySigningKeys(rKeys), nil
+}
+
+// Activate promotes a pending key to active, the previously active key starts
+// retiring and keeps verifying tokens until retireAt
+func (repo *SigningKeyRepo) Activate(keyID string, retireAt time.Time) error {
+
+	return repo.pgClient.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&repoSigningKey{}).
+			Where(skColStatus+" = ?", STATUS_ACTIVE).
+			Updates(map[string]any{skColStatus: STATUS_RETIRING, skColRetireAt: retireAt}).Error
+		if err != nil {
+			return fmt.Errorf("signing key demote failed: %v", err)
+		}
+
+		res := tx.Model(&repoSigningKey{}).
+			Where(skColKeyID+" = ? AND "+skColStatus+" = ?", keyID, STATUS_PENDING).
+			Updates(map[string]any{skColStatus: STATUS_ACTIVE, skColActivatedAt: time.Now()})
+		if res.Error != nil {
+			return fmt.Errorf("signing key activate failed: %v", res.Error)
+		}
+
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("pending signing key not found: %s", keyID)
+		}
+
+		return nil
+	})
+}
+
+// RetireExpired retires the retiring keys past their grace period
+func (repo *SigningKeyRepo) RetireExpired() (int64, error) {
+
+	res := repo.pgClient.DB.Model(&repoSigningKey{}).
+		Where(skColStatus+" = ? AND "+skColRetireAt+" <= ?", STATUS_RETIRING, time.Now()).
+		Updates(map[string]any{skColStatus: STATUS_RETIRED, skColRetiredAt: time.Now()})
+	if res.Error != nil {
+		return 0, fmt.Errorf("signing keys retire failed: %v", res.Error)
+	}
+
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,270 @@
+package signingkey
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+	"user-authentication/pkg/jwt"
+)
+
+// default interval of the background keyring sync when none is configured
+const defaultKeyRingSyncInterval = time.Minute
+
+var (
+	ErrNoActiveKey    = errors.New("signing key: no active key")
+	ErrInvalidKeyID   = errors.New("signing key: invalid key id")
+	ErrKeyFileMissing = errors.New("signing key: private key file missing")
+	ErrKeyNotPending  = errors.New("signing key: no pending key with this id")
+	ErrKeyNotSynced   = errors.New("signing key: pending key not yet loaded by every replica")
+)
+
+// SigningKeyServicePort represents the signing key service port
+type SigningKeyServicePort interface {
+	Generate() (SigningKey, error)
+	Promote(keyID string) error
+	Rotate() (SigningKey, error)
+	RetireExpired() (int64, error)
+	Get() ([]SigningKey, error)
+}
+
+// SigningKeyService manages the lifecycle of the token signing keys and keeps the
+// in-memory keyring in sync with the persisted keys
+type SigningKeyService struct {
+	repo         SigningKeyRepoPort
+	keyRing      *jwt.KeyRing
+	keyDir       string
+	algorithm    string
+	gracePeriod  time.Duration
+	syncInterval time.Duration
+}
+
+// NewSigningKeyService creates a new signing key service, keys are generated with the
+// algorithm and their private keys are written to keyDir which every replica must share.
+// syncInterval is the keyring sync interval of the replicas, a pending key is promoted
+// only once every replica had a sync to load it.
+func NewSigningKeyService(
+	repo SigningKeyRepoPort,
+	keyRing *jwt.KeyRing,
+	keyDir string,
+	algorithm string,
+	gracePeriod time.Duration,
+	syncInterval time.Duration,
+) *SigningKeyService {
+	if syncInterval <= 0 {
+		syncInterval = defaultKeyRingSyncInterval
+	}
+
+	return &SigningKeyService{
+		repo:         repo,
+		keyRing:      keyRing,
+		keyDir:       keyDir,
+		algorithm:    algorithm,
+		gracePeriod:  gracePeriod,
+		syncInterval: syncInterval,
+	}
+}
+
+// Generate creates a new pending key, it is published for verification right away
+// so that caches of the JWKS know about it before it starts signing tokens
+func (service *SigningKeyService) Generate() (SigningKey, error) {
+	key, err := jwt.GenerateSigningKey(service.algorithm)
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	privateKeyPEM, err := key.PrivateKeyPEM()
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	if err := os.MkdirAll(service.keyDir, 0o700); err != nil {
+		return SigningKey{}, fmt.Errorf("signing key: creating key directory failed: %w", err)
+	}
+
+	if err := os.WriteFile(service.keyFile(key.KeyID), privateKeyPEM, 0o600); err != nil {
+		return SigningKey{}, fmt.Errorf("signing key: writing key file failed: %w", err)
+	}
+
+	return service.repo.Create(SigningKey{
+		KeyID:     key.KeyID,
+		Algorithm: key.Algorithm,
+		Status:    STATUS_PENDING,
+	})
+}
+
+// Promote makes a pending key the signing key, the current one retires after the grace period.
+// The key must have been pending for a full sync interval, otherwise replicas that didn't
+// load it yet would reject the tokens it signs.
+func (service *SigningKeyService) Promote(keyID string) error {
+	if keyID == "" {
+		return ErrInvalidKeyID
+	}
+
+	pendingKeys, err := service.repo.GetByStatus(STATUS_PENDING)
+	if err != nil {
+		return err
+	}
+
+	var pendingKey *SigningKey
+	for i := range pendingKeys {
+		if pendingKeys[i].KeyID == keyID {
+			pendingKey = &pendingKeys[i]
+		}
+	}
+	if pendingKey == nil {
+		return fmt.Errorf("%w: %s", ErrKeyNotPending, keyID)
+	}
+
+	if publishedFor := time.Since(pendingKey.CreatedAt); publishedFor < service.syncInterval {
+		return fmt.Errorf("%w: retry in %v", ErrKeyNotSynced, (service.syncInterval - publishedFor).Round(time.Second))
+	}
+
+	return service.activate(keyID)
+}
+
+// Rotate generates a new pending key, waits a full sync interval for every replica to load
+// it and promotes it
+func (service *SigningKeyService) Rotate() (SigningKey, error) {
+	key, err := service.Generate()
+	if err != nil {
+		return SigningKey{}, err
+	}
+
+	log.Printf("signing key %s published, promoting it in %v", key.KeyID, service.syncInterval)
+	time.Sleep(service.syncInterval)
+
+	if err := service.Promote(key.KeyID); err != nil {
+		return SigningKey{}, err
+	}
+
+	log.Printf("signing key %s promoted, previous key retiring in %v", key.KeyID, service.gracePeriod)
+
+	return key, nil
+}
+
+// activate makes the key the signing key and reloads the keyring
+func (service *SigningKeyService) activate(keyID string) error {
+	if err := service.repo.Activate(keyID, time.Now().Add(service.gracePeriod)); err != nil {
+		return err
+	}
+
+	return service.LoadKeyRing()
+}
+
+// RetireExpired retires the keys past their grace period, tokens signed by them stop verifying
+func (service *SigningKeyService) RetireExpired() (int64, error) {
+	retired, err := service.repo.RetireExpired()
+	if err != nil {
+		return 0, err
+	}
+
+	if retired > 0 {
+		log.Printf("retired %d signing keys", retired)
+	}
+
+	return retired, nil
+}
+
+// Get returns the metadata of every signing key
+func (service *SigningKeyService) Get() ([]SigningKey, error) {
+	return service.repo.Get()
+}
+
+// EnsureActiveKey bootstraps the keyring with a first key when none is active
+func (service *SigningKeyService) EnsureActiveKey() error {
+	activeKeys, err := service.repo.GetByStatus(STATUS_ACTIVE)
+	if err != nil {
+		return err
+	}
+
+	if len(activeKeys) > 0 {
+		return service.LoadKeyRing()
+	}
+
+	// no token was signed yet, so the first key is activated without waiting for the replicas
+	key, err := service.Generate()
+	if err == nil {
+		err = service.activate(key.KeyID)
+	}
+	if err != nil {
+		// another replica may have bootstrapped the keyring concurrently
+		log.Printf("signing key bootstrap failed, reloading keyring: %v", err)
+	}
+
+	return service.LoadKeyRing()
+}
+
+// LoadKeyRing replaces the keyring with the pending, active and retiring keys
+func (service *SigningKeyService) LoadKeyRing() error {
+	keys, err := service.repo.GetByStatus(STATUS_PENDING, STATUS_ACTIVE, STATUS_RETIRING)
+	if err != nil {
+		return err
+	}
+
+	var signingKey *jwt.SigningKey
+	verifyKeys := make([]*jwt.SigningKey, 0, len(keys))
+	for _, key := range keys {
+		loadedKey, err := jwt.LoadSigningKeyFile(key.Algorithm, service.keyFile(key.KeyID), key.KeyID)
+		if err != nil {
+			log.Printf("signing key %s load failed: %v", key.KeyID, err)
+			if key.Status == STATUS_ACTIVE {
+				return fmt.Errorf("%w: %s", ErrKeyFileMissing, key.KeyID)
+			}
+			continue
+		}
+
+		if key.Status == STATUS_ACTIVE {
+			signingKey = loadedKey
+		} else {
+			verifyKeys = append(verifyKeys, loadedKey)
+		}
+	}
+
+	if signingKey == nil {
+		return ErrNoActiveKey
+	}
+
+	service.keyRing.Replace(signingKey, verifyKeys...)
+
+	return nil
+}
+
+// StartSync periodically retires expired keys and reloads the keyring in the background,
+// the returned function stops the sync
+func (service *SigningKeyService) StartSync(interval time.Duration) func() {
+	if interval <= 0 {
+		interval = service.syncInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := service.RetireExpired(); err != nil {
+					log.Printf("signing keys retire failed: %v", err)
+				}
+				if err := service.LoadKeyRing(); err != nil {
+					log.Printf("keyring sync failed: %v", err)
+				}
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// keyFile returns the path of the private key file of the key
+func (service *SigningKeyService) keyFile(keyID string) string {
+	return filepath.Join(service.keyDir, keyID+".pem")
+}
@@ -0,0 +1,58 @@
+package user
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrPasswordExpiryDisabled = errors.New("password expiry is not enabled")
+
+// PasswordExpiryConfig represents the password expiry settings
+type PasswordExpiryConfig struct {
+	// MaxAge is the maximum age in days of a password, 0 disables the expiry
+	MaxAge int
+	// WarningPeriod is the number of days before the expiry the logins warn about it
+	WarningPeriod int
+}
+
+// Enabled reports whether the passwords expire
+func (config PasswordExpiryConfig) Enabled() bool {
+	return config.MaxAge > 0
+}
+
+// ExpiresAt returns the time the password of the user expires, false when the passwords
+// don't expire
+func (config PasswordExpiryConfig) ExpiresAt(u User) (time.Time, bool) {
+	if !config.Enabled() || u.PasswordModifiedAt == nil {
+		return time.Time{}, false
+	}
+
+	return u.PasswordModifiedAt.AddDate(0, 0, config.MaxAge), true
+}
+
+// IsExpired reports whether the password of the user is past its maximum age
+func (config PasswordExpiryConfig) IsExpired(u User, now time.Time) bool {
+	expiresAt, ok := config.ExpiresAt(u)
+
+	return ok && !now.Before(expiresAt)
+}
+
+// ExpiryWarning returns the expiry of a password expiring within the warning period, nil otherwise
+func (config PasswordExpiryConfig) ExpiryWarning(u User, now time.Time) *time.Time {
+	expiresAt, ok := config.ExpiresAt(u)
+	if !ok || !now.Before(expiresAt) || now.AddDate(0, 0, config.WarningPeriod).Before(expiresAt) {
+		return nil
+	}
+
+	return &expiresAt
+}
+
+// PasswordExpiry represents an account of the password expiry report
+type PasswordExpiry struct {
+	UserID             int       `json:"user_id"`
+	Username           string    `json:"username"`
+	Email              string    `json:"email"`
+	PasswordModifiedAt time.Time `json:"password_modified_at"`
+	ExpiresAt          time.Time `json:"expires_at"`
+	Expired            bool      `json:"expired"`
+}
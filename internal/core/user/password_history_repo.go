@@ -0,0 +1,6 @@
+package user
+
+type PasswordHistoryRepoPort interface {
+	GetRecent(userID int, limit int) ([]string, error)
+	Prune(userID int, keep int) error
+}
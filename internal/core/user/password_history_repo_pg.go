@@ -0,0 +1,74 @@
+package user
+
+import (
+	"fmt"
+	"time"
+	"user-authentication/internal/postgres"
+)
+
+const (
+	phTable        = "password_history"
+	phColID        = "id"
+	phColUserID    = "user_id"
+	phColCreatedAt = "created_at"
+)
+
+type repoPasswordHistory struct {
+	ID           int       `gorm:"column:id;primaryKey;autoIncrement"`
+	UserID       int       `gorm:"column:user_id"`
+	PasswordHash string    `gorm:"column:password_hash"`
+	CreatedAt    time.Time `gorm:"column:created_at"`
+}
+
+func (repoPasswordHistory) TableName() string {
+	return phTable
+}
+
+// PasswordHistoryRepo represents the password history repository, the rows are added by
+// UserRepo.UpdatePassword along with the password change
+type PasswordHistoryRepo struct {
+	pgClient *postgres.PGClient
+}
+
+func NewPasswordHistoryRepo(pgClient *postgres.PGClient) *PasswordHistoryRepo {
+	return &PasswordHistoryRepo{
+		pgClient: pgClient,
+	}
+}
+
+// GetRecent returns the hashes of the last replaced passwords of the user, newest first
+func (repo *PasswordHistoryRepo) GetRecent(userID int, limit int) ([]string, error) {
+
+	var rEntries []repoPasswordHistory
+	err := repo.pgClient.DB.Where(phColUserID+" = ?", userID).
+		Order(phColCreatedAt + " DESC, " + phColID + " DESC").
+		Limit(limit).
+		Find(&rEntries).Error
+	if err != nil {
+		return []string{}, fmt.Errorf("password history fetch failed: %v", err)
+	}
+
+	hashes := make([]string, 0, len(rEntries))
+	for _, e := range rEntries {
+		hashes = append(hashes, e.PasswordHash)
+	}
+
+	return hashes, nil
+}
+
+// Prune deletes every history row of the user but the newest keep ones
+func (repo *PasswordHistoryRepo) Prune(userID int, keep int) error {
+
+	newest := repo.pgClient.DB.Model(&repoPasswordHistory{}).Select(phColID).
+		Where(phColUserID+" = ?", userID).
+		Order(phColCreatedAt + " DESC, " + phColID + " DESC").
+		Limit(keep)
+
+	err := repo.pgClient.DB.Where(phColUserID+" = ? AND "+phColID+" NOT IN (?)", userID, newest).
+		Delete(&repoPasswordHistory{}).Error
+	if err != nil {
+		return fmt.Errorf("password history prune failed: %v", err)
+	}
+
+	return nil
+}
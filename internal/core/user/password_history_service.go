@@ -0,0 +1,79 @@
+package user
+
+import (
+	"errors"
+	"log"
+	"user-authentication/pkg/password"
+)
+
+var (
+	ErrPasswordReused  = errors.New("new password must differ from the recently used passwords")
+	ErrPasswordHistory = errors.New("password history check failed")
+)
+
+// PasswordHistoryConfig represents the password reuse settings
+type PasswordHistoryConfig struct {
+	// Size is the number of last passwords, the current one included, a new password must
+	// differ from, 0 or 1 only rejects the current password
+	Size int
+}
+
+// previous returns the number of replaced passwords kept in the history
+func (config PasswordHistoryConfig) previous() int {
+	if config.Size <= 1 {
+		return 0
+	}
+
+	return config.Size - 1
+}
+
+// PasswordHistoryServicePort represents the password history service port
+type PasswordHistoryServicePort interface {
+	CheckReuse(userID int, newPassword string) error
+	Prune(userID int) error
+}
+
+// PasswordHistoryService keeps the users from cycling back to their replaced passwords, the
+// current password is rejected by the password change and reset flows themselves
+type PasswordHistoryService struct {
+	config PasswordHistoryConfig
+	repo   PasswordHistoryRepoPort
+	hasher password.Hasher
+}
+
+// NewPasswordHistoryService creates a new password history service
+func NewPasswordHistoryService(config PasswordHistoryConfig, repo PasswordHistoryRepoPort, hasher password.Hasher) *PasswordHistoryService {
+	return &PasswordHistoryService{
+		config: config,
+		repo:   repo,
+		hasher: hasher,
+	}
+}
+
+// CheckReuse rejects a new password matching one of the replaced passwords in the history
+func (service *PasswordHistoryService) CheckReuse(userID int, newPassword string) error {
+	if service.config.previous() == 0 {
+		return nil
+	}
+
+	hashes, err := service.repo.GetRecent(userID, service.config.previous())
+	if err != nil {
+		log.Printf("password history fetch failed: %v", err)
+		return ErrPasswordHistory
+	}
+
+	for _, hash := range hashes {
+		if service.hasher.Verify(newPassword, hash) {
+			log.Printf("password change rejected for user %d: password reused", userID)
+			return ErrPasswordReused
+		}
+	}
+
+	return nil
+}
+
+// Prune deletes the history rows of the user past the configured size, called after every
+// password change
+func (service *PasswordHistoryService) Prune(userID int) error {
+	return service.repo.Prune(userID, service.config.previous())
+}
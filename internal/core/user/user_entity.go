@@ -1,5 +1,7 @@
 package user
 
+import "time"
+
 // User represents the requried user details
 type User struct {
 	ID           int    `json:"id"`
@@ -9,4 +11,34 @@ type User struct {
 	PasswordHash string `json:"password_hash"`
 	IsActive     bool   `json:"is_active"`
 	IsLocked     bool   `json:"is_locked"`
+
+	// set once the user proved control of the email
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+
+	// last time the password was set, the password age is counted from it
+	PasswordModifiedAt *time.Time `json:"password_modified_at,omitempty"`
+
+	// login failure tracking used by the account lockout
+	FailedLoginAttempts int        `json:"-"`
+	LockoutCount        int        `json:"-"`
+	LockedUntil         *time.Time `json:"locked_until,omitempty"`
+}
+
+// IsTemporarilyLocked reports whether a temporary lockout is still running
+func (u User) IsTemporarilyLocked(now time.Time) bool {
+	return u.LockedUntil != nil && now.Before(*u.LockedUntil)
+}
+
+// IsEmailVerified reports whether the user proved control of the email
+func (u User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Caller identifies the user of a request and the session the request was made from
+type Caller struct {
+	UserID int
+	// TokenID is the access token of the request, empty for API keys
+	TokenID string
+	// SessionID is the login session of the request, empty when the token has none
+	SessionID string
 }
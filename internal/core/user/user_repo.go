@@ -1,9 +1,21 @@
 package user
 
+import "time"
+
 type UserRepoPort interface {
 	Create(user User) (User, error)
 	GetByID(userID int) (User, error)
 	Get() ([]User, error)
 	GetByUsername(username string) (User, error)
+	GetByEmail(email string) (User, error)
 	Update(user User) (User, error)
+	GetPasswordHash(userID int) (string, error)
+	UpdatePassword(userID int, passwordHash string) error
+	RehashPassword(userID int, currentHash string, passwordHash string) error
+	IncrementFailedLogins(userID int) (attempts int, lockouts int, err error)
+	LockOut(userID int, lockedUntil time.Time, permanent bool) error
+	ResetFailedLogins(userID int) error
+	Unlock(userID int) error
+	MarkEmailVerified(userID int, email string) (bool, error)
+	GetByPasswordModifiedBefore(before time.Time) ([]User, error)
 }
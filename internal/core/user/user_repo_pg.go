@@ -1,9 +1,13 @@
 package user
 
 import (
+	"database/sql"
 	"fmt"
 	"time"
 	"user-authentication/internal/postgres"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 const (
@@ -14,6 +18,15 @@ const (
 	uaColPwdHash  = "password_hash"
 	uaColIsActive = "is_active"
 	uaColIsLocked = "is_locked"
+
+	uaColPwdModifiedAt = "password_modified_at"
+	uaColModifiedAt    = "modified_at"
+
+	uaColEmailVerifiedAt = "email_verified_at"
+
+	uaColFailedLoginAttempts = "failed_login_attempts"
+	uaColLockoutCount        = "lockout_count"
+	uaColLockedUntil         = "locked_until"
 )
 
 type repoUser struct {
@@ -26,6 +39,12 @@ type repoUser struct {
 	PasswordModifiedAt time.Time `gorm:"column:password_modified_at"`
 	CreatedAt          time.Time `gorm:"column:created_at"`
 	ModifiedAt         time.Time `gorm:"column:modified_at"`
+
+	EmailVerifiedAt sql.NullTime `gorm:"column:email_verified_at"`
+
+	FailedLoginAttempts int          `gorm:"column:failed_login_attempts"`
+	LockoutCount        int          `gorm:"column:lockout_count"`
+	LockedUntil         sql.NullTime `gorm:"column:locked_until"`
 }
 
 func (repoUser) TableName() string {
@@ -60,13 +79,26 @@ func toRepoUser(u User) repoUser {
 }
 
 func toEntityUser(u repoUser) User {
-	return User{
-		ID:       u.ID,
-		Username: u.Username,
-		Email:    u.Email,
-		IsActive: u.IsActive,
-		IsLocked: u.IsLocked,
+	user := User{
+		ID:                  u.ID,
+		Username:            u.Username,
+		Email:               u.Email,
+		IsActive:            u.IsActive,
+		IsLocked:            u.IsLocked,
+		FailedLoginAttempts: u.FailedLoginAttempts,
+		LockoutCount:        u.LockoutCount,
 	}
+	if u.LockedUntil.Valid {
+		user.LockedUntil = &u.LockedUntil.Time
+	}
+	if u.EmailVerifiedAt.Valid {
+		user.EmailVerifiedAt = &u.EmailVerifiedAt.Time
+	}
+	if !u.PasswordModifiedAt.IsZero() {
+		user.PasswordModifiedAt = &u.PasswordModifiedAt
+	}
+
+	return user
 }
 
 func toEntityUsers(repoUsers []repoUser) []User {
@@ -91,8 +123,10 @@ func (repo *UserRepo) Create(user User) (User, error) {
 
 func (repo *UserRepo) GetByID(userID int) (User, error) {
 
+	// the failed login counters are selected so that the passwordless logins reset them
 	var rUser repoUser
-	err := repo.pgClient.DB.Select(uaColID, uaColUserName, uaColEmail, uaColIsActive, uaColIsLocked).
+	err := repo.pgClient.DB.Select(uaColID, uaColUserName, uaColEmail, uaColIsActive, uaColIsLocked, uaColLockedUntil, uaColEmailVerifiedAt, uaColPwdModifiedAt,
+		uaColFailedLoginAttempts, uaColLockoutCount).
 		First(&rUser, userID).Error
 	if err != nil {
 		return User{}, fmt.Errorf("user fetch failed: %v", err)
@@ -110,13 +144,29 @@ func (repo *UserRepo) GetByUsername(username string) (User, error) {
 		return User{}, fmt.Errorf("user fetch failed: %v", err)
 	}
 
+	// the password hash is only exposed on username lookups, used by the login flow
+	user := toEntityUser(rUser)
+	user.PasswordHash = rUser.PasswordHash
+
+	return user, nil
+}
+
+func (repo *UserRepo) GetByEmail(email string) (User, error) {
+	var rUser repoUser
+	err := repo.pgClient.DB.Select(uaColID, uaColUserName, uaColEmail, uaColIsActive, uaColIsLocked, uaColLockedUntil, uaColEmailVerifiedAt, uaColPwdModifiedAt,
+		uaColFailedLoginAttempts, uaColLockoutCount).
+		Where(uaColEmail+" = ?", email).First(&rUser).Error
+	if err != nil {
+		return User{}, fmt.Errorf("user fetch failed: %v", err)
+	}
+
 	return toEntityUser(rUser), nil
 }
 
 func (repo *UserRepo) Get() ([]User, error) {
 
 	var users []repoUser
-	res := repo.pgClient.DB.Select(uaColID, uaColUserName, uaColEmail, uaColIsActive, uaColIsLocked).Find(&users)
+	res := repo.pgClient.DB.Select(uaColID, uaColUserName, uaColEmail, uaColIsActive, uaColIsLocked, uaColEmailVerifiedAt, uaColPwdModifiedAt).Find(&users)
 	if res.Error != nil {
 		return []User{}, fmt.Errorf("users fetch failed: %v", res.Error)
 	}
@@ -130,11 +180,15 @@ func (repo *UserRepo) Get() ([]User, error) {
 
 func (repo *UserRepo) Update(user User) (User, error) {
 
-	rUser := toRepoUser(user)
-
+	// a changed email has to be verified again
 	res := repo.pgClient.DB.Model(&repoUser{}).Where(&repoUser{
 		ID: user.ID,
-	}).Select("username", "email").Updates(rUser)
+	}).Updates(map[string]any{
+		uaColUserName:        user.Username,
+		uaColEmail:           user.Email,
+		uaColEmailVerifiedAt: gorm.Expr("CASE WHEN "+uaColEmail+" = ? THEN "+uaColEmailVerifiedAt+" ELSE NULL END", user.Email),
+		uaColModifiedAt:      time.Now(),
+	})
 	if res.Error != nil {
 		return User{}, res.Error
 	}
@@ -145,3 +199,159 @@ func (repo *UserRepo) Update(user User) (User, error) {
 
 	return user, nil
 }
+
+// GetByPasswordModifiedBefore returns the active users whose password was last set before
+// the time, oldest passwords first
+func (repo *UserRepo) GetByPasswordModifiedBefore(before time.Time) ([]User, error) {
+
+	var users []repoUser
+	err := repo.pgClient.DB.Select(uaColID, uaColUserName, uaColEmail, uaColIsActive, uaColIsLocked, uaColPwdModifiedAt).
+		Where(uaColPwdModifiedAt+" < ? AND "+uaColIsActive+" = ?", before, true).
+		Order(uaColPwdModifiedAt).
+		Find(&users).Error
+	if err != nil {
+		return []User{}, fmt.Errorf("users fetch failed: %v", err)
+	}
+
+	return toEntityUsers(users), nil
+}
+
+// GetPasswordHash returns the password hash of the user, used to re-verify the current password
+func (repo *UserRepo) GetPasswordHash(userID int) (string, error) {
+
+	var rUser repoUser
+	err := repo.pgClient.DB.Select(uaColID, uaColPwdHash).First(&rUser, userID).Error
+	if err != nil {
+		return "", fmt.Errorf("user fetch failed: %v", err)
+	}
+
+	return rUser.PasswordHash, nil
+}
+
+// UpdatePassword replaces the password hash and stamps the password modification time, the
+// replaced hash is recorded in the password history
+func (repo *UserRepo) UpdatePassword(userID int, passwordHash string) error {
+
+	now := time.Now()
+	return repo.pgClient.DB.Transaction(func(tx *gorm.DB) error {
+		// the row lock serializes concurrent changes, every replaced hash is recorded once
+		var rUser repoUser
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select(uaColID, uaColPwdHash).First(&rUser, userID).Error
+		if err != nil {
+			return fmt.Errorf("user not found: %v", err)
+		}
+
+		entry := repoPasswordHistory{UserID: userID, PasswordHash: rUser.PasswordHash, CreatedAt: now}
+		if err := tx.Create(&entry).Error; err != nil {
+			return fmt.Errorf("password history create failed: %v", err)
+		}
+
+		err = tx.Model(&repoUser{}).Where(uaColID+" = ?", userID).Updates(map[string]any{
+			uaColPwdHash:       passwordHash,
+			uaColPwdModifiedAt: now,
+			uaColModifiedAt:    now,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("user password update failed: %v", err)
+		}
+
+		return nil
+	})
+}
+
+// RehashPassword replaces the hash of the unchanged password, e.g. with stronger parameters.
+// The password modification time is kept and a concurrent password change is not overwritten.
+func (repo *UserRepo) RehashPassword(userID int, currentHash string, passwordHash string) error {
+
+	err := repo.pgClient.DB.Model(&repoUser{}).
+		Where(uaColID+" = ? AND "+uaColPwdHash+" = ?", userID, currentHash).
+		Update(uaColPwdHash, passwordHash).Error
+	if err != nil {
+		return fmt.Errorf("user password rehash failed: %v", err)
+	}
+
+	return nil
+}
+
+// IncrementFailedLogins atomically counts a failed login and returns the updated counters
+func (repo *UserRepo) IncrementFailedLogins(userID int) (int, int, error) {
+
+	var rUser repoUser
+	err := repo.pgClient.DB.Raw(
+		"UPDATE "+uaTable+" SET "+uaColFailedLoginAttempts+" = "+uaColFailedLoginAttempts+" + 1 WHERE "+uaColID+" = ? "+
+			"RETURNING "+uaColFailedLoginAttempts+", "+uaColLockoutCount, userID,
+	).Scan(&rUser).Error
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed login count update failed: %v", err)
+	}
+
+	return rUser.FailedLoginAttempts, rUser.LockoutCount, nil
+}
+
+// LockOut starts a temporary lockout, or a permanent one by setting is_locked
+func (repo *UserRepo) LockOut(userID int, lockedUntil time.Time, permanent bool) error {
+
+	// concurrent failures reaching the threshold only count as a single lockout
+	res := repo.pgClient.DB.Model(&repoUser{}).
+		Where(uaColID+" = ? AND "+uaColFailedLoginAttempts+" > 0", userID).
+		Updates(map[string]any{
+			uaColFailedLoginAttempts: 0,
+			uaColLockoutCount:        gorm.Expr(uaColLockoutCount + " + 1"),
+			uaColLockedUntil:         lockedUntil,
+			uaColIsLocked:            permanent,
+		})
+	if res.Error != nil {
+		return fmt.Errorf("user lockout failed: %v", res.Error)
+	}
+
+	return nil
+}
+
+// ResetFailedLogins clears the failed login counters after a successful login
+func (repo *UserRepo) ResetFailedLogins(userID int) error {
+
+	res := repo.pgClient.DB.Model(&repoUser{}).Where(uaColID+" = ?", userID).Updates(map[string]any{
+		uaColFailedLoginAttempts: 0,
+		uaColLockoutCount:        0,
+		uaColLockedUntil:         nil,
+	})
+	if res.Error != nil {
+		return fmt.Errorf("failed login reset failed: %v", res.Error)
+	}
+
+	return nil
+}
+
+// Unlock lifts temporary and permanent lockouts
+func (repo *UserRepo) Unlock(userID int) error {
+
+	res := repo.pgClient.DB.Model(&repoUser{}).Where(uaColID+" = ?", userID).Updates(map[string]any{
+		uaColFailedLoginAttempts: 0,
+		uaColLockoutCount:        0,
+		uaColLockedUntil:         nil,
+		uaColIsLocked:            false,
+	})
+	if res.Error != nil {
+		return fmt.Errorf("user unlock failed: %v", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("user not found: %d", userID)
+	}
+
+	return nil
+}
+
+// MarkEmailVerified stamps the email verification time as long as the user still has the
+// given email, it reports false when the email changed or was already verified
+func (repo *UserRepo) MarkEmailVerified(userID int, email string) (bool, error) {
+
+	res := repo.pgClient.DB.Model(&repoUser{}).
+		Where(uaColID+" = ? AND "+uaColEmail+" = ? AND "+uaColEmailVerifiedAt+" IS NULL", userID, email).
+		Update(uaColEmailVerifiedAt, time.Now())
+	if res.Error != nil {
+		return false, fmt.Errorf("email verification update failed: %v", res.Error)
+	}
+
+	return res.RowsAffected == 1, nil
+}
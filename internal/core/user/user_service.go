@@ -3,6 +3,8 @@ package user
 import (
 	"errors"
 	"log"
+	"time"
+	"user-authentication/internal/core/rbac"
 	"user-authentication/pkg/password"
 )
 
@@ -11,21 +13,53 @@ type UserServicePort interface {
 	Update(user User) (User, error)
 	GetByID(userID int) (User, error)
 	Get() ([]User, error)
+	Unlock(userID int) error
+	ChangePassword(caller Caller, currentPassword string, newPassword string, revokeOtherSessions bool) error
+	PasswordExpiryReport(withinDays int) ([]PasswordExpiry, error)
+}
+
+// SessionRevokerPort ends the sessions of a user, implemented by the authentication service
+type SessionRevokerPort interface {
+	RevokeOtherSessions(userID int, tokenID string, sessionID string) error
 }
 
 type UserService struct {
-	UserRepo UserRepoPort
+	UserRepo        UserRepoPort
+	RBACService     rbac.RBACServicePort
+	SessionRevoker  SessionRevokerPort
+	PasswordHasher  password.Hasher
+	PasswordPolicy  *password.Policy
+	PasswordHistory PasswordHistoryServicePort
+	PasswordExpiry  PasswordExpiryConfig
 }
 
 var (
-	ErrInvalidUserDetails = errors.New("invalid user details")
-	ErrInvalidUserID      = errors.New("invalid user id")
-	ErrInvalidUserPwd     = errors.New("invalid user password")
+	ErrInvalidUserDetails  = errors.New("invalid user details")
+	ErrInvalidUserID       = errors.New("invalid user id")
+	ErrInvalidUserPwd      = errors.New("invalid user password")
+	ErrIncorrectCurrentPwd = errors.New("incorrect current password")
+	ErrPasswordUnchanged   = errors.New("new password must differ from the current password")
+	ErrPasswordChange      = errors.New("password change failed")
+	ErrUserCreation        = errors.New("user creation failed")
 )
 
-func NewUserService(userRepo UserRepoPort) *UserService {
+func NewUserService(
+	userRepo UserRepoPort,
+	rbacService rbac.RBACServicePort,
+	sessionRevoker SessionRevokerPort,
+	passwordHasher password.Hasher,
+	passwordPolicy *password.Policy,
+	passwordHistory PasswordHistoryServicePort,
+	passwordExpiry PasswordExpiryConfig,
+) *UserService {
 	return &UserService{
-		UserRepo: userRepo,
+		UserRepo:        userRepo,
+		RBACService:     rbacService,
+		SessionRevoker:  sessionRevoker,
+		PasswordHasher:  passwordHasher,
+		PasswordPolicy:  passwordPolicy,
+		PasswordHistory: passwordHistory,
+		PasswordExpiry:  passwordExpiry,
 	}
 }
 
@@ -34,7 +68,15 @@ func (service *UserService) Create(user User) (User, error) {
 		return User{}, ErrInvalidUserDetails
 	}
 
-	hashedPwd, err := password.HashPassword(user.Password)
+	if err := service.PasswordPolicy.Check(user.Password, user.Username, user.Email); err != nil {
+		if !errors.Is(err, password.ErrPolicyViolation) {
+			log.Printf("password policy check failed: %v", err)
+			return User{}, ErrUserCreation
+		}
+		return User{}, err
+	}
+
+	hashedPwd, err := service.PasswordHasher.Hash(user.Password)
 	if err != nil {
 		log.Printf("error hashing password %v", err)
 
@@ -42,12 +84,20 @@ func (service *UserService) Create(user User) (User, error) {
 	}
 
 	user.PasswordHash = hashedPwd
+	user.IsActive = true
+	user.IsLocked = false
 
 	user, err = service.UserRepo.Create(user)
 	if err != nil {
 		return User{}, err
 	}
 
+	if err := service.RBACService.AssignDefaultRole(user.ID); err != nil {
+		log.Printf("default role assign failed: %v", err)
+
+		return User{}, err
+	}
+
 	return user, nil
 }
 
@@ -85,3 +135,125 @@ func (service *UserService) Update(user User) (User, error) {
 
 	return user, nil
 }
+
+// Unlock lifts the temporary and permanent lockouts of the user
+func (service *UserService) Unlock(userID int) error {
+	if userID <= 0 {
+		return ErrInvalidUserID
+	}
+
+	return service.UserRepo.Unlock(userID)
+}
+
+// ChangePassword sets a new password after re-verifying the current one, revokeOtherSessions
+// ends every session of the user except the one of the caller
+func (service *UserService) ChangePassword(caller Caller, currentPassword string, newPassword string, revokeOtherSessions bool) error {
+	userID := caller.UserID
+	if userID <= 0 {
+		return ErrInvalidUserID
+	}
+	if currentPassword == "" || newPassword == "" {
+		return ErrInvalidUserPwd
+	}
+
+	dbUser, err := service.UserRepo.GetByID(userID)
+	if err != nil {
+		return err
+	}
+
+	currentHash, err := service.UserRepo.GetPasswordHash(userID)
+	if err != nil {
+		return err
+	}
+
+	// the current password is verified first, the policy and the breach lookup only run for
+	// callers who know it
+	if !service.PasswordHasher.Verify(currentPassword, currentHash) {
+		log.Printf("password change rejected for user %d: wrong current password", userID)
+		return ErrIncorrectCurrentPwd
+	}
+
+	if err := service.PasswordPolicy.Check(newPassword, dbUser.Username, dbUser.Email); err != nil {
+		if !errors.Is(err, password.ErrPolicyViolation) {
+			log.Printf("password policy check failed: %v", err)
+			return ErrPasswordChange
+		}
+		return err
+	}
+
+	if service.PasswordHasher.Verify(newPassword, currentHash) {
+		return ErrPasswordUnchanged
+	}
+
+	if err := service.PasswordHistory.CheckReuse(userID, newPassword); err != nil {
+		if errors.Is(err, ErrPasswordReused) {
+			return err
+		}
+		return ErrPasswordChange
+	}
+
+	hashedPwd, err := service.PasswordHasher.Hash(newPassword)
+	if err != nil {
+		log.Printf("error hashing password %v", err)
+		return ErrPasswordChange
+	}
+
+	if err := service.UserRepo.UpdatePassword(userID, hashedPwd); err != nil {
+		log.Printf("password update failed: %v", err)
+		return ErrPasswordChange
+	}
+
+	if err := service.PasswordHistory.Prune(userID); err != nil {
+		log.Printf("password history prune failed for user %d: %v", userID, err)
+	}
+
+	if revokeOtherSessions {
+		if err := service.SessionRevoker.RevokeOtherSessions(userID, caller.TokenID, caller.SessionID); err != nil {
+			log.Printf("sessions revoke after password change failed for user %d: %v", userID, err)
+			return ErrPasswordChange
+		}
+	}
+
+	return nil
+}
+
+// PasswordExpiryReport lists the active accounts whose password expires within the number of
+// days, the expired ones included. A zero number of days uses the warning period.
+func (service *UserService) PasswordExpiryReport(withinDays int) ([]PasswordExpiry, error) {
+	if !service.PasswordExpiry.Enabled() {
+		return []PasswordExpiry{}, ErrPasswordExpiryDisabled
+	}
+	if withinDays < 0 {
+		return []PasswordExpiry{}, ErrInvalidUserDetails
+	}
+	if withinDays == 0 {
+		withinDays = service.PasswordExpiry.WarningPeriod
+	}
+
+	// the passwords expiring before the horizon were set before the cutoff
+	now := time.Now()
+	cutoff := now.AddDate(0, 0, withinDays-service.PasswordExpiry.MaxAge)
+	users, err := service.UserRepo.GetByPasswordModifiedBefore(cutoff)
+	if err != nil {
+		log.Printf("password expiry report failed: %v", err)
+		return []PasswordExpiry{}, err
+	}
+
+	report := make([]PasswordExpiry, 0, len(users))
+	for _, u := range users {
+		expiresAt, ok := service.PasswordExpiry.ExpiresAt(u)
+		if !ok {
+			continue
+		}
+		report = append(report, PasswordExpiry{
+			UserID:             u.ID,
+			Username:           u.Username,
+			Email:              u.Email,
+			PasswordModifiedAt: *u.PasswordModifiedAt,
+			ExpiresAt:          expiresAt,
+			Expired:            !now.Before(expiresAt),
+		})
+	}
+
+	return report, nil
+}
@@ -0,0 +1,155 @@
+package user
+
+import (
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"user-authentication/pkg/password"
+)
+
+// fakeUserRepo keeps a single user in memory, the methods the tests don't use panic
+type fakeUserRepo struct {
+	UserRepoPort
+	user         User
+	passwordHash string
+}
+
+func (repo *fakeUserRepo) GetByID(userID int) (User, error) {
+	if userID != repo.user.ID {
+		return User{}, errors.New("user not found")
+	}
+	return repo.user, nil
+}
+
+func (repo *fakeUserRepo) GetPasswordHash(userID int) (string, error) {
+	return repo.passwordHash, nil
+}
+
+func (repo *fakeUserRepo) UpdatePassword(userID int, passwordHash string) error {
+	repo.passwordHash = passwordHash
+	return nil
+}
+
+// fakePasswordHistoryRepo returns the replaced password hashes of every user
+type fakePasswordHistoryRepo struct {
+	hashes []string
+}
+
+func (repo *fakePasswordHistoryRepo) GetRecent(userID int, limit int) ([]string, error) {
+	return repo.hashes, nil
+}
+
+func (repo *fakePasswordHistoryRepo) Prune(userID int, keep int) error {
+	return nil
+}
+
+// writeBreachFile writes a SHA1 hash list of the passwords, sorted like the corpus
+func writeBreachFile(t *testing.T, lines ...string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "breached.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatalf("breach file write failed: %v", err)
+	}
+
+	return path
+}
+
+// sha1Line returns the hash list line of the password
+func sha1Line(pwd string) string {
+	digest, _ := password.Digest(password.HASH_SHA1, pwd)
+	return strings.ToUpper(hex.EncodeToString(digest)) + ":1"
+}
+
+func TestChangePassword(t *testing.T) {
+	const currentPassword = "current-password-1"
+
+	hasher, err := password.NewBcryptHasher(4)
+	if err != nil {
+		t.Fatalf("NewBcryptHasher() failed: %v", err)
+	}
+	hash := func(pwd string) string {
+		encodedHash, err := hasher.Hash(pwd)
+		if err != nil {
+			t.Fatalf("Hash() failed: %v", err)
+		}
+		return encodedHash
+	}
+	currentHash := hash(currentPassword)
+	history := []string{hash("previous-password-1")}
+
+	policy, err := password.NewPolicy(password.PolicyConfig{
+		MinLength:  12,
+		BreachFile: writeBreachFile(t, sha1Line("breached-password-1")),
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+
+	// every lookup of this corpus fails, the line being longer than a lookup reads
+	brokenPolicy, err := password.NewPolicy(password.PolicyConfig{
+		BreachFile: writeBreachFile(t, strings.Repeat("0", 40)+":"+strings.Repeat("1", 1024), strings.Repeat("F", 40)+":1"),
+	})
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+
+	tests := []struct {
+		name            string
+		policy          *password.Policy
+		currentPassword string
+		newPassword     string
+		err             error
+		rule            string
+	}{
+		{"changed", policy, currentPassword, "new-password-1", nil, ""},
+		{"too short", policy, currentPassword, "short-1", password.ErrPolicyViolation, password.RULE_MIN_LENGTH},
+		{"breached", policy, currentPassword, "breached-password-1", password.ErrPolicyViolation, password.RULE_BREACHED_PASSWORD},
+		{"current password again", policy, currentPassword, currentPassword, ErrPasswordUnchanged, ""},
+		{"password of the history", policy, currentPassword, "previous-password-1", ErrPasswordReused, ""},
+		{"failed breach lookup", brokenPolicy, currentPassword, "new-password-1", ErrPasswordChange, ""},
+		{"wrong current password", policy, "wrong-password-1", "new-password-1", ErrIncorrectCurrentPwd, ""},
+		// the current password is checked before the policy, a caller who doesn't know it
+		// learns nothing about the new password and never reaches the breach corpus
+		{"wrong current password and too short", policy, "wrong-password-1", "short-1", ErrIncorrectCurrentPwd, ""},
+		{"wrong current password and breached", policy, "wrong-password-1", "breached-password-1", ErrIncorrectCurrentPwd, ""},
+		{"wrong current password and failing lookup", brokenPolicy, "wrong-password-1", "new-password-1", ErrIncorrectCurrentPwd, ""},
+		{"missing current password", policy, "", "new-password-1", ErrInvalidUserPwd, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeUserRepo{
+				user:         User{ID: 42, Username: "alice", Email: "alice@example.com", IsActive: true},
+				passwordHash: currentHash,
+			}
+			service := &UserService{
+				UserRepo:        repo,
+				PasswordHasher:  hasher,
+				PasswordPolicy:  tt.policy,
+				PasswordHistory: NewPasswordHistoryService(PasswordHistoryConfig{Size: 5}, &fakePasswordHistoryRepo{hashes: history}, hasher),
+			}
+
+			err := service.ChangePassword(Caller{UserID: 42}, tt.currentPassword, tt.newPassword, false)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ChangePassword() error = %v, want %v", err, tt.err)
+			}
+
+			var policyErr *password.PolicyError
+			if tt.rule != "" && (!errors.As(err, &policyErr) || policyErr.Violations[0].Rule != tt.rule) {
+				t.Errorf("ChangePassword() error = %v, want the %s rule violated", err, tt.rule)
+			}
+
+			// the password only changes when every check passed
+			if changed := repo.passwordHash != currentHash; changed != (tt.err == nil) {
+				t.Errorf("ChangePassword() changed the password = %v, want %v", changed, tt.err == nil)
+			}
+			if tt.err == nil && !hasher.Verify(tt.newPassword, repo.passwordHash) {
+				t.Error("ChangePassword() stored a hash not matching the new password")
+			}
+		})
+	}
+}
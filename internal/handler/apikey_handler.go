@@ -0,0 +1,148 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+	"user-authentication/internal/core/apikey"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandlerPort represents the API key handler port
+type APIKeyHandlerPort interface {
+	Create(c *gin.Context)
+	Get(c *gin.Context)
+	Delete(c *gin.Context)
+	ValidateAPIKey(key string) (auth.Principal, error)
+}
+
+// APIKeyHandler represents the API key handler
+type APIKeyHandler struct {
+	apiKeyService apikey.APIKeyServicePort
+}
+
+// NewAPIKeyHandler creates a new API key handler to be used by the router
+func NewAPIKeyHandler(apiKeyService apikey.APIKeyServicePort) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// createAPIKeyRequest represents the request body of the API key creation endpoint
+type createAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Create generates a new API key for the current user, the key is only returned once
+func (handler *APIKeyHandler) Create(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	// a leaked key must not be able to mint keys outliving it
+	if principal.IsAPIKey() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be created with an API key"})
+		return
+	}
+
+	// nor may an OAuth client turn its grant into a key outliving it
+	if principal.ClientID != "" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be created with an OAuth client token"})
+		return
+	}
+
+	var req createAPIKeyRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	apiKey, key, err := handler.apiKeyService.Create(principal.UserID, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		c.JSON(apiKeyErrorStatus(err), gin.H{
+			"error":  "Failed to create API key",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created successfully, it will not be shown again",
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+// Get lists the API keys of the current user
+func (handler *APIKeyHandler) Get(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	keys, err := handler.apiKeyService.Get(principal.UserID)
+	if err != nil {
+		c.JSON(apiKeyErrorStatus(err), gin.H{
+			"error":  "Failed to fetch API keys",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+// Delete revokes an API key of the current user
+func (handler *APIKeyHandler) Delete(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := handler.apiKeyService.Delete(principal.UserID, id); err != nil {
+		c.JSON(apiKeyErrorStatus(err), gin.H{
+			"error":  "Failed to revoke API key",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
+
+// ValidateAPIKey resolves the API key of the Authorization header to its principal
+func (handler *APIKeyHandler) ValidateAPIKey(key string) (auth.Principal, error) {
+	return handler.apiKeyService.Authenticate(key)
+}
+
+// apiKeyErrorStatus maps the API key errors to the http status returned to the client
+func apiKeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, apikey.ErrAPIKeyNotFound), errors.Is(err, user.ErrInvalidUserID):
+		return http.StatusNotFound
+	case errors.Is(err, apikey.ErrInvalidAPIKeyDetails):
+		return http.StatusBadRequest
+	case errors.Is(err, apikey.ErrAPIKeyLimit):
+		return http.StatusConflict
+	case errors.Is(err, apikey.ErrInvalidAPIKey):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -1,8 +1,10 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/passkey"
 	"user-authentication/internal/core/user"
 
 	"github.com/gin-gonic/gin"
@@ -11,8 +13,13 @@ import (
 // AuthHandlerPort represents the authentication handler port
 type AuthHandlerPort interface {
 	Login(c *gin.Context)
+	VerifyMFA(c *gin.Context)
 	Signup(c *gin.Context)
-	ValidateAccessToken(accessTokenString string) (bool, error)
+	Refresh(c *gin.Context)
+	Logout(c *gin.Context)
+	LogoutAll(c *gin.Context)
+	JWKS(c *gin.Context)
+	ValidateAccessToken(accessTokenString string) (auth.Principal, error)
 }
 
 // AuthHandler represents the authenctication handler
@@ -40,27 +47,214 @@ func (handler *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	accessToken, err := handler.authService.Login(user)
+	result, err := handler.authService.Login(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		c.JSON(loginErrorStatus(err), gin.H{
 			"error":  "Failed to login",
 			"detail": err.Error(),
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"message": "Login successfully", "token": accessToken})
+	writeLoginResult(c, result)
+}
+
+// writeLoginResult writes the tokens of a successful login, or the MFA challenge when a
+// second factor is required
+func writeLoginResult(c *gin.Context, result auth.LoginResult) {
+	// the second factor is verified through the MFA verify endpoint
+	if result.MFARequired {
+		c.JSON(http.StatusOK, gin.H{
+			"message":        "MFA required",
+			"mfa_required":   true,
+			"mfa_token":      result.MFAChallenge,
+			"mfa_expires_in": result.MFAExpiresIn,
+		})
+		return
+	}
+
+	// the access token only calls the password change endpoint, no refresh token is issued
+	if result.PasswordExpired {
+		c.JSON(http.StatusOK, gin.H{
+			"message":          "Password expired",
+			"password_expired": true,
+			"token":            result.Tokens.AccessToken,
+			"token_type":       result.Tokens.TokenType,
+			"expires_in":       result.Tokens.ExpiresIn,
+		})
+		return
+	}
+
+	body := gin.H{
+		"message":       "Login successfully",
+		"token":         result.Tokens.AccessToken,
+		"refresh_token": result.Tokens.RefreshToken,
+		"token_type":    result.Tokens.TokenType,
+		"expires_in":    result.Tokens.ExpiresIn,
+	}
+	if result.PasswordExpiresAt != nil {
+		body["password_expires_at"] = result.PasswordExpiresAt
+	}
+
+	c.JSON(http.StatusCreated, body)
+}
+
+// verifyMFARequest represents the request body of the MFA verify endpoint
+type verifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// VerifyMFA exchanges the MFA challenge of the login and a TOTP or recovery code for the tokens
+func (handler *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req verifyMFARequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	result, err := handler.authService.VerifyMFA(req.MFAToken, req.Code)
+	if err != nil {
+		c.JSON(loginErrorStatus(err), gin.H{
+			"error":  "Failed to verify MFA code",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	writeLoginResult(c, result)
+}
+
+// loginErrorStatus maps the login errors to the http status returned to the client
+func loginErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrAccountLocked), errors.Is(err, auth.ErrAccountTempLocked):
+		return http.StatusLocked
+	case errors.Is(err, auth.ErrUserInactive), errors.Is(err, auth.ErrEmailNotVerified),
+		errors.Is(err, auth.ErrPasswordExpired):
+		return http.StatusForbidden
+	case errors.Is(err, auth.ErrIncorrectPwd), errors.Is(err, auth.ErrIncorrectMFACode),
+		errors.Is(err, auth.ErrInvalidMFAChallenge), errors.Is(err, passkey.ErrPasskeyVerification),
+		errors.Is(err, passkey.ErrInvalidChallenge), errors.Is(err, auth.ErrInvalidMagicLink):
+		return http.StatusUnauthorized
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// refreshRequest represents the request body of the refresh token endpoint
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// Refresh rotates the refresh token and issues a new access token
+func (handler *AuthHandler) Refresh(c *gin.Context) {
+	var req refreshRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	tokens, err := handler.authService.Refresh(req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"error":  "Failed to refresh token",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":       "Token refreshed successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"token_type":    tokens.TokenType,
+		"expires_in":    tokens.ExpiresIn,
+	})
+}
+
+// logoutRequest represents the request body of the logout endpoint
+type logoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// Logout revokes the current access token and the refresh token session if given
+func (handler *AuthHandler) Logout(c *gin.Context) {
+	var req logoutRequest
+
+	// the refresh token is optional, an empty body only revokes the access token
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Invalid request body",
+				"detail": err.Error(),
+			})
+			return
+		}
+	}
+
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	err := handler.authService.Logout(principal, req.RefreshToken)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to logout",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logout successfully"})
+}
+
+// LogoutAll revokes every access and refresh token of the current user
+func (handler *AuthHandler) LogoutAll(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	err := handler.authService.LogoutAll(principal)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to logout from all sessions",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logout from all sessions successfully"})
+}
 
+// JWKS publishes the public keys used to verify the access tokens
+func (handler *AuthHandler) JWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, handler.authService.JWKS())
 }
 
 // ValidateAccessToken validates the accesstoken on the Authorization Header
-func (handler *AuthHandler) ValidateAccessToken(accessTokenString string) (bool, error) {
-	isValid, err := handler.authService.ValidateAccessToken(accessTokenString)
+func (handler *AuthHandler) ValidateAccessToken(accessTokenString string) (auth.Principal, error) {
+	principal, err := handler.authService.ValidateAccessToken(accessTokenString)
 	if err != nil {
-		return isValid, err
+		return auth.Principal{}, err
 	}
 
-	return isValid, nil
+	return principal, nil
 }
 
 func (handler *AuthHandler) Signup(c *gin.Context) {
@@ -78,10 +272,7 @@ func (handler *AuthHandler) Signup(c *gin.Context) {
 	// Call service layer to create user
 	user, err := handler.authService.Create(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":  "Failed to create user",
-			"detail": err.Error(),
-		})
+		c.JSON(createUserErrorStatus(err), passwordErrorBody("Failed to create user", err))
 		return
 	}
 
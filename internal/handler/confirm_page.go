@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// confirmPage asks the user to confirm the action of an emailed link. Mail scanners and
+// browsers open links with GET, so the single-use token is only consumed by the form POST.
+var confirmPage = template.Must(template.New("confirm").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="referrer" content="no-referrer">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; max-width: 24rem; margin: 4rem auto; padding: 0 1rem; }
+button { display: block; width: 100%; margin-top: 0.5rem; padding: 0.5rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+{{if .Token}}
+<p>{{.Message}}</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="token" value="{{.Token}}">
+<button type="submit">{{.Button}}</button>
+</form>
+{{else}}
+<p class="error">The link is invalid, request a new one.</p>
+{{end}}
+</body>
+</html>
+`))
+
+// confirmPageData represents the data rendered by the confirm page
+type confirmPageData struct {
+	Title   string
+	Message string
+	// Action is the path the token is posted to
+	Action string
+	Token  string
+	Button string
+}
+
+// linkTokenRequest represents the request body posting the token of an emailed link,
+// sent as a form by the confirm page or as JSON
+type linkTokenRequest struct {
+	Token string `form:"token" json:"token" binding:"required"`
+}
+
+// renderConfirmPage writes the confirm page, it must not be framed by other sites and the
+// token in its URL must not leak through the referrer
+func renderConfirmPage(c *gin.Context, data confirmPageData) {
+	status := http.StatusOK
+	if data.Token == "" {
+		status = http.StatusBadRequest
+	}
+
+	var page bytes.Buffer
+	if err := confirmPage.Execute(&page, data); err != nil {
+		log.Printf("confirm page render failed: %v", err)
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("Referrer-Policy", "no-referrer")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; form-action 'self'; frame-ancestors 'none'")
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
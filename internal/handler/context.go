@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"user-authentication/internal/core/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContextKeyPrincipal is the gin context key holding the authenticated principal
+const ContextKeyPrincipal = "principal"
+
+// SetPrincipal stores the principal in the gin context and the request context.Context
+func SetPrincipal(c *gin.Context, principal auth.Principal) {
+	c.Set(ContextKeyPrincipal, principal)
+	c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+}
+
+// GetPrincipal returns the principal set by the authentication middleware
+func GetPrincipal(c *gin.Context) (auth.Principal, bool) {
+	value, ok := c.Get(ContextKeyPrincipal)
+	if !ok {
+		return auth.Principal{}, false
+	}
+
+	principal, ok := value.(auth.Principal)
+
+	return principal, ok
+}
@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user-authentication/internal/core/auth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// EmailVerificationHandlerPort represents the email verification handler port
+type EmailVerificationHandlerPort interface {
+	ConfirmPage(c *gin.Context)
+	Verify(c *gin.Context)
+	Resend(c *gin.Context)
+}
+
+// EmailVerificationHandler represents the email verification handler
+type EmailVerificationHandler struct {
+	emailVerificationService auth.EmailVerificationServicePort
+}
+
+// NewEmailVerificationHandler creates a new email verification handler to be used by the router
+func NewEmailVerificationHandler(emailVerificationService auth.EmailVerificationServicePort) *EmailVerificationHandler {
+	return &EmailVerificationHandler{
+		emailVerificationService: emailVerificationService,
+	}
+}
+
+// ConfirmPage renders the page of the verification link, the email is verified once the
+// user confirms it so that link scanners don't use up the token
+func (handler *EmailVerificationHandler) ConfirmPage(c *gin.Context) {
+	renderConfirmPage(c, confirmPageData{
+		Title:   "Verify your email",
+		Message: "Confirm the email address of your account.",
+		Action:  c.Request.URL.Path,
+		Token:   c.Query("token"),
+		Button:  "Verify email",
+	})
+}
+
+// Verify marks the email of the user as verified with the token of the verification link
+func (handler *EmailVerificationHandler) Verify(c *gin.Context) {
+	var req linkTokenRequest
+
+	// Bind the form or JSON body to struct
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	err := handler.emailVerificationService.Verify(req.Token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, auth.ErrInvalidVerificationToken) {
+			status = http.StatusBadRequest
+		}
+
+		c.JSON(status, gin.H{
+			"error":  "Failed to verify email",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// resendVerificationRequest represents the request body of the resend verification endpoint
+type resendVerificationRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// Resend sends a new verification link, the response is the same whether the account exists or not
+func (handler *EmailVerificationHandler) Resend(c *gin.Context) {
+	var req resendVerificationRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	if err := handler.emailVerificationService.Resend(req.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Failed to resend the verification email",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an unverified account exists for this email, a verification link has been sent",
+	})
+}
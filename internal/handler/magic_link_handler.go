@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MagicLinkHandlerPort represents the magic link handler port
+type MagicLinkHandlerPort interface {
+	Request(c *gin.Context)
+	ConfirmPage(c *gin.Context)
+	Callback(c *gin.Context)
+}
+
+// MagicLinkHandler represents the magic link handler
+type MagicLinkHandler struct {
+	magicLinkService auth.MagicLinkServicePort
+	authService      auth.AuthServicePort
+}
+
+// NewMagicLinkHandler creates a new magic link handler to be used by the router
+func NewMagicLinkHandler(magicLinkService auth.MagicLinkServicePort, authService auth.AuthServicePort) *MagicLinkHandler {
+	return &MagicLinkHandler{
+		magicLinkService: magicLinkService,
+		authService:      authService,
+	}
+}
+
+// magicLinkRequest represents the request body of the magic link endpoint
+type magicLinkRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// Request sends a login link, the response is the same whether the account exists or not
+func (handler *MagicLinkHandler) Request(c *gin.Context) {
+	var req magicLinkRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	if err := handler.magicLinkService.Request(req.Email); err != nil {
+		c.JSON(magicLinkErrorStatus(err), gin.H{
+			"error":  "Failed to request a magic link",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account exists for this email, a login link has been sent",
+	})
+}
+
+// ConfirmPage renders the page of the magic link, the user is logged in once they confirm it
+// so that link scanners don't use up the token
+func (handler *MagicLinkHandler) ConfirmPage(c *gin.Context) {
+	renderConfirmPage(c, confirmPageData{
+		Title:   "Log in",
+		Message: "Continue to log in to your account.",
+		Action:  c.Request.URL.Path,
+		Token:   c.Query("token"),
+		Button:  "Log in",
+	})
+}
+
+// Callback exchanges the token of the magic link for the tokens
+func (handler *MagicLinkHandler) Callback(c *gin.Context) {
+	var req linkTokenRequest
+
+	// Bind the form or JSON body to struct
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	result, err := handler.authService.LoginWithMagicLink(req.Token)
+	if err != nil {
+		c.JSON(loginErrorStatus(err), gin.H{
+			"error":  "Failed to login with magic link",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	writeLoginResult(c, result)
+}
+
+// magicLinkErrorStatus maps the magic link request errors to the http status returned to the client
+func magicLinkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrMagicLinkDisabled):
+		return http.StatusNotFound
+	case errors.Is(err, user.ErrInvalidUserDetails):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user-authentication/internal/core/mfa"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MFAHandlerPort represents the multi-factor authentication handler port
+type MFAHandlerPort interface {
+	Enroll(c *gin.Context)
+	Confirm(c *gin.Context)
+	Disable(c *gin.Context)
+}
+
+// MFAHandler represents the multi-factor authentication handler
+type MFAHandler struct {
+	mfaService mfa.MFAServicePort
+}
+
+// NewMFAHandler creates a new multi-factor authentication handler to be used by the router
+func NewMFAHandler(mfaService mfa.MFAServicePort) *MFAHandler {
+	return &MFAHandler{
+		mfaService: mfaService,
+	}
+}
+
+// mfaCodeRequest represents the request body of the MFA endpoints requiring a code
+type mfaCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// Enroll starts the TOTP enrollment of the current user
+func (handler *MFAHandler) Enroll(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	enrollment, err := handler.mfaService.Enroll(principal.UserID)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{
+			"error":  "Failed to enroll MFA",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "MFA enrollment started, confirm it with a code from the authenticator app",
+		"enrollment": enrollment,
+	})
+}
+
+// Confirm enables the pending TOTP enrollment of the current user with a first code
+func (handler *MFAHandler) Confirm(c *gin.Context) {
+	var req mfaCodeRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	recoveryCodes, err := handler.mfaService.Confirm(principal.UserID, req.Code)
+	if err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{
+			"error":  "Failed to confirm MFA",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "MFA enabled, store the recovery codes safely, they are only shown once",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// Disable removes the TOTP enrollment of the current user after verifying a code
+func (handler *MFAHandler) Disable(c *gin.Context) {
+	var req mfaCodeRequest
+
+	// the code is optional for a pending enrollment
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Invalid request body",
+				"detail": err.Error(),
+			})
+			return
+		}
+	}
+
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	if err := handler.mfaService.Disable(principal.UserID, req.Code); err != nil {
+		c.JSON(mfaErrorStatus(err), gin.H{
+			"error":  "Failed to disable MFA",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "MFA disabled successfully"})
+}
+
+// mfaErrorStatus maps the MFA errors to the http status returned to the client
+func mfaErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidMFACode):
+		return http.StatusUnauthorized
+	case errors.Is(err, mfa.ErrMFANotEnrolled):
+		return http.StatusNotFound
+	case errors.Is(err, mfa.ErrMFAAlreadyEnabled), errors.Is(err, mfa.ErrMFANotPending):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,473 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/mfa"
+	"user-authentication/internal/core/oauth"
+	"user-authentication/internal/core/user"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OAuthHandlerPort represents the OAuth authorization server handler port
+type OAuthHandlerPort interface {
+	Authorize(c *gin.Context)
+	AuthorizeSubmit(c *gin.Context)
+	Token(c *gin.Context)
+	Introspect(c *gin.Context)
+	Revoke(c *gin.Context)
+	Discovery(c *gin.Context)
+	UserInfo(c *gin.Context)
+	CreateClient(c *gin.Context)
+	GetClients(c *gin.Context)
+	DeleteClient(c *gin.Context)
+	RotateClientSecret(c *gin.Context)
+}
+
+// OAuthHandler represents the OAuth authorization server handler
+type OAuthHandler struct {
+	oauthService oauth.OAuthServicePort
+	authService  auth.AuthServicePort
+}
+
+// NewOAuthHandler creates a new OAuth authorization server handler to be used by the router
+func NewOAuthHandler(oauthService oauth.OAuthServicePort, authService auth.AuthServicePort) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		authService:  authService,
+	}
+}
+
+// Authorize validates the authorization request and shows the login and consent page
+func (handler *OAuthHandler) Authorize(c *gin.Context) {
+	var req oauth.AuthorizationRequest
+	_ = c.ShouldBindQuery(&req)
+
+	client, ok := handler.validateAuthorization(c, req)
+	if !ok {
+		return
+	}
+
+	renderAuthorizePage(c, http.StatusOK, authorizePageData{
+		Client:  &client,
+		Scopes:  requestedScopes(client, req),
+		Request: req,
+	})
+}
+
+// AuthorizeSubmit authenticates the user of the login page and redirects back to the
+// client with an authorization code, or with access_denied when the user declined
+func (handler *OAuthHandler) AuthorizeSubmit(c *gin.Context) {
+	var req oauth.AuthorizationRequest
+	_ = c.ShouldBind(&req)
+
+	client, ok := handler.validateAuthorization(c, req)
+	if !ok {
+		return
+	}
+
+	if c.PostForm("action") != "allow" {
+		redirectAuthorizationError(c, req, oauth.ErrAccessDenied)
+		return
+	}
+
+	page := authorizePageData{
+		Client:  &client,
+		Scopes:  requestedScopes(client, req),
+		Request: req,
+	}
+
+	// the authentication is recorded for the auth_time and amr claims of the ID token
+	authentication := oauth.Authentication{AuthTime: time.Now()}
+	if mfaToken := c.PostForm("mfa_token"); mfaToken != "" {
+		// second step, the challenge of the password step and a TOTP or recovery code
+		id, factor, err := handler.authService.AuthenticateMFA(mfaToken, c.PostForm("code"))
+		if err != nil {
+			if !errors.Is(err, auth.ErrInvalidMFAChallenge) {
+				page.MFAToken = mfaToken
+			}
+			page.Error = authorizeLoginError(err)
+			renderAuthorizePage(c, http.StatusUnauthorized, page)
+			return
+		}
+		authentication.UserID = id
+		// a recovery code is no one-time password of RFC 8176, only the mfa is reported for it
+		authentication.Methods = []string{oauth.AMR_PWD, oauth.AMR_MFA}
+		if factor == mfa.FACTOR_TOTP {
+			authentication.Methods = []string{oauth.AMR_PWD, oauth.AMR_OTP, oauth.AMR_MFA}
+		}
+	} else {
+		result, err := handler.authService.Authenticate(user.User{
+			Username: c.PostForm("username"),
+			Password: c.PostForm("password"),
+		})
+		if err != nil {
+			page.Error = authorizeLoginError(err)
+			renderAuthorizePage(c, http.StatusUnauthorized, page)
+			return
+		}
+		if result.MFARequired {
+			page.MFAToken = result.MFAChallenge
+			renderAuthorizePage(c, http.StatusOK, page)
+			return
+		}
+		authentication.UserID = result.UserID
+		authentication.Methods = []string{oauth.AMR_PWD}
+	}
+
+	code, err := handler.oauthService.Authorize(req, authentication)
+	if err != nil {
+		redirectAuthorizationError(c, req, err)
+		return
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	c.Redirect(http.StatusFound, authorizationRedirect(req.RedirectURI, params))
+}
+
+// validateAuthorization checks the authorization request. Requests of unknown clients or
+// with an unregistered redirect URI are answered with an error page, the other errors
+// are redirected to the client.
+func (handler *OAuthHandler) validateAuthorization(c *gin.Context, req oauth.AuthorizationRequest) (oauth.Client, bool) {
+	client, err := handler.oauthService.ValidateAuthorization(req)
+	if errors.Is(err, oauth.ErrInvalidClient) || errors.Is(err, oauth.ErrInvalidRedirectURI) {
+		renderAuthorizePage(c, http.StatusBadRequest, authorizePageData{Error: err.Error()})
+		return oauth.Client{}, false
+	}
+	if err != nil {
+		redirectAuthorizationError(c, req, err)
+		return oauth.Client{}, false
+	}
+
+	return client, true
+}
+
+// requestedScopes returns the scopes shown on the consent page
+func requestedScopes(client oauth.Client, req oauth.AuthorizationRequest) []string {
+	if scopes := strings.Fields(req.Scope); len(scopes) > 0 {
+		return scopes
+	}
+
+	return client.Scopes
+}
+
+// authorizeLoginError returns the message shown on the login page, failed credentials are
+// not told apart so that the page cannot be used to enumerate accounts
+func authorizeLoginError(err error) string {
+	switch {
+	case errors.Is(err, auth.ErrAccountLocked), errors.Is(err, auth.ErrAccountTempLocked):
+		return "The account is locked, try again later"
+	case errors.Is(err, auth.ErrUserInactive):
+		return "The account is inactive"
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		return "Verify your email before signing in"
+	case errors.Is(err, auth.ErrPasswordExpired):
+		return "The password expired, sign in to the application to change it"
+	case errors.Is(err, auth.ErrIncorrectMFACode):
+		return "Incorrect authentication code"
+	case errors.Is(err, auth.ErrInvalidMFAChallenge):
+		return "The sign in expired, please sign in again"
+	default:
+		return "Incorrect username or password"
+	}
+}
+
+// redirectAuthorizationError sends the error back to the client through its redirect URI
+func redirectAuthorizationError(c *gin.Context, req oauth.AuthorizationRequest, err error) {
+	code, _ := oauthErrorCode(err)
+	params := url.Values{
+		"error":             {code},
+		"error_description": {err.Error()},
+	}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+
+	c.Redirect(http.StatusFound, authorizationRedirect(req.RedirectURI, params))
+}
+
+// authorizationRedirect adds the parameters to the query of the registered redirect URI
+func authorizationRedirect(redirectURI string, params url.Values) string {
+	uri, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := uri.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	uri.RawQuery = query.Encode()
+
+	return uri.String()
+}
+
+// Token issues tokens for the authorization_code, refresh_token and client_credentials grants
+func (handler *OAuthHandler) Token(c *gin.Context) {
+	var req oauth.TokenRequest
+
+	// token responses must never be cached
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	if err := c.ShouldBind(&req); err != nil {
+		writeOAuthError(c, oauth.ErrInvalidRequest)
+		return
+	}
+
+	if !bindClientCredentials(c, &req.ClientID, &req.ClientSecret) {
+		writeOAuthError(c, oauth.ErrInvalidRequest)
+		return
+	}
+
+	tokens, err := handler.oauthService.Token(req)
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// bindClientCredentials takes the client credentials from HTTP Basic authentication when
+// present, they are form encoded (RFC 6749 2.3.1). Clients must use a single method, it
+// reports false when the form carries other credentials.
+func bindClientCredentials(c *gin.Context, clientID *string, clientSecret *string) bool {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return true
+	}
+
+	basicID, errID := url.QueryUnescape(username)
+	basicSecret, errSecret := url.QueryUnescape(password)
+	if errID != nil || errSecret != nil || *clientSecret != "" || (*clientID != "" && *clientID != basicID) {
+		return false
+	}
+	*clientID = basicID
+	*clientSecret = basicSecret
+
+	return true
+}
+
+// Introspect reports the state of a token to a resource server, inactive tokens are only
+// reported as such
+func (handler *OAuthHandler) Introspect(c *gin.Context) {
+	var req oauth.IntrospectionRequest
+
+	c.Header("Cache-Control", "no-store")
+	if err := c.ShouldBind(&req); err != nil || !bindClientCredentials(c, &req.ClientID, &req.ClientSecret) {
+		writeOAuthError(c, oauth.ErrInvalidRequest)
+		return
+	}
+
+	info, err := handler.oauthService.Introspect(req)
+	if err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// Revoke revokes an access or refresh token of the client, unknown tokens are answered
+// with success as well
+func (handler *OAuthHandler) Revoke(c *gin.Context) {
+	var req oauth.RevocationRequest
+
+	if err := c.ShouldBind(&req); err != nil || !bindClientCredentials(c, &req.ClientID, &req.ClientSecret) {
+		writeOAuthError(c, oauth.ErrInvalidRequest)
+		return
+	}
+
+	if err := handler.oauthService.Revoke(req); err != nil {
+		writeOAuthError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Discovery publishes the OpenID Connect provider metadata
+func (handler *OAuthHandler) Discovery(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, handler.oauthService.Discovery())
+}
+
+// UserInfo returns the claims of the user the access token was issued for
+func (handler *OAuthHandler) UserInfo(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	info, err := handler.oauthService.UserInfo(principal)
+	if err != nil {
+		code, status := userInfoErrorCode(err)
+		c.Header("WWW-Authenticate", `Bearer error="`+code+`"`)
+		c.JSON(status, gin.H{
+			"error":             code,
+			"error_description": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// userInfoErrorCode maps the userinfo errors to the RFC 6750 error codes and http status
+func userInfoErrorCode(err error) (string, int) {
+	switch {
+	case errors.Is(err, oauth.ErrInsufficientScope):
+		return "insufficient_scope", http.StatusForbidden
+	default:
+		return "invalid_token", http.StatusUnauthorized
+	}
+}
+
+// writeOAuthError writes an error response of the token endpoint
+func writeOAuthError(c *gin.Context, err error) {
+	code, status := oauthErrorCode(err)
+	if status == http.StatusUnauthorized {
+		c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	c.JSON(status, gin.H{
+		"error":             code,
+		"error_description": err.Error(),
+	})
+}
+
+// oauthErrorCode maps the OAuth errors to the RFC 6749 error codes and http status
+func oauthErrorCode(err error) (string, int) {
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClient):
+		return "invalid_client", http.StatusUnauthorized
+	case errors.Is(err, oauth.ErrInvalidRequest), errors.Is(err, oauth.ErrPKCERequired):
+		return "invalid_request", http.StatusBadRequest
+	case errors.Is(err, oauth.ErrUnsupportedResponseType):
+		return "unsupported_response_type", http.StatusBadRequest
+	case errors.Is(err, oauth.ErrInvalidScope):
+		return "invalid_scope", http.StatusBadRequest
+	case errors.Is(err, oauth.ErrAccessDenied):
+		return "access_denied", http.StatusForbidden
+	case errors.Is(err, oauth.ErrUnauthorizedClient):
+		return "unauthorized_client", http.StatusBadRequest
+	case errors.Is(err, oauth.ErrUnsupportedGrantType):
+		return "unsupported_grant_type", http.StatusBadRequest
+	case errors.Is(err, oauth.ErrInvalidGrant):
+		return "invalid_grant", http.StatusBadRequest
+	default:
+		return "server_error", http.StatusInternalServerError
+	}
+}
+
+// createClientRequest represents the request body of the client registration endpoint
+type createClientRequest struct {
+	Name         string   `json:"name" binding:"required"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"`
+}
+
+// CreateClient registers a new OAuth client
+func (handler *OAuthHandler) CreateClient(c *gin.Context) {
+	var req createClientRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	client, secret, err := handler.oauthService.CreateClient(oauth.Client{
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Scopes:       req.Scopes,
+		Confidential: req.Confidential,
+	})
+	if err != nil {
+		c.JSON(clientErrorStatus(err), gin.H{
+			"error":  "Failed to register OAuth client",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"message": "OAuth client registered successfully",
+		"client":  client,
+	}
+	// the secret is only shown once
+	if secret != "" {
+		response["client_secret"] = secret
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// GetClients lists the registered OAuth clients
+func (handler *OAuthHandler) GetClients(c *gin.Context) {
+	clients, err := handler.oauthService.GetClients()
+	if err != nil {
+		c.JSON(clientErrorStatus(err), gin.H{
+			"error":  "Failed to fetch OAuth clients",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"clients": clients})
+}
+
+// DeleteClient deletes an OAuth client, along with the sessions of its users
+func (handler *OAuthHandler) DeleteClient(c *gin.Context) {
+	if err := handler.oauthService.DeleteClient(c.Param("client_id")); err != nil {
+		c.JSON(clientErrorStatus(err), gin.H{
+			"error":  "Failed to delete OAuth client",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "OAuth client deleted successfully"})
+}
+
+// RotateClientSecret issues a new secret to a confidential client, the previous one stops working
+func (handler *OAuthHandler) RotateClientSecret(c *gin.Context) {
+	secret, err := handler.oauthService.RotateClientSecret(c.Param("client_id"))
+	if err != nil {
+		c.JSON(clientErrorStatus(err), gin.H{
+			"error":  "Failed to rotate OAuth client secret",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "OAuth client secret rotated successfully",
+		"client_secret": secret,
+	})
+}
+
+// clientErrorStatus maps the client administration errors to the http status returned to the client
+func clientErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, oauth.ErrInvalidClientDetails), errors.Is(err, oauth.ErrPublicClient):
+		return http.StatusBadRequest
+	case errors.Is(err, oauth.ErrClientNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,87 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+	"net/http"
+	"user-authentication/internal/core/oauth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authorizePage is the minimal login and consent page of the authorization endpoint,
+// the authorization request travels in hidden fields
+var authorizePage = template.Must(template.New("authorize").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in</title>
+<style>
+body { font-family: sans-serif; max-width: 24rem; margin: 4rem auto; padding: 0 1rem; }
+label, input, button { display: block; width: 100%; box-sizing: border-box; }
+input { margin: 0.25rem 0 1rem; padding: 0.5rem; }
+button { margin-top: 0.5rem; padding: 0.5rem; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+{{if .Client}}
+<h1>Sign in to {{.Client.Name}}</h1>
+{{if .Scopes}}<p>{{.Client.Name}} is requesting access to:</p>
+<ul>{{range .Scopes}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/oauth/authorize">
+<input type="hidden" name="response_type" value="{{.Request.ResponseType}}">
+<input type="hidden" name="client_id" value="{{.Request.ClientID}}">
+<input type="hidden" name="redirect_uri" value="{{.Request.RedirectURI}}">
+<input type="hidden" name="scope" value="{{.Request.Scope}}">
+<input type="hidden" name="state" value="{{.Request.State}}">
+<input type="hidden" name="code_challenge" value="{{.Request.CodeChallenge}}">
+<input type="hidden" name="code_challenge_method" value="{{.Request.CodeChallengeMethod}}">
+<input type="hidden" name="nonce" value="{{.Request.Nonce}}">
+{{if .MFAToken}}
+<input type="hidden" name="mfa_token" value="{{.MFAToken}}">
+<label for="code">Authentication code</label>
+<input id="code" name="code" autocomplete="one-time-code" required autofocus>
+{{else}}
+<label for="username">Username</label>
+<input id="username" name="username" autocomplete="username" required autofocus>
+<label for="password">Password</label>
+<input id="password" name="password" type="password" autocomplete="current-password" required>
+{{end}}
+<button type="submit" name="action" value="allow">Allow</button>
+<button type="submit" name="action" value="deny" formnovalidate>Deny</button>
+</form>
+{{else}}
+<h1>Invalid request</h1>
+<p class="error">{{.Error}}</p>
+{{end}}
+</body>
+</html>
+`))
+
+// authorizePageData represents the data rendered by the authorize page
+type authorizePageData struct {
+	Client   *oauth.Client
+	Scopes   []string
+	Request  oauth.AuthorizationRequest
+	MFAToken string
+	Error    string
+}
+
+// renderAuthorizePage writes the authorize page, it must not be framed by other sites
+func renderAuthorizePage(c *gin.Context, status int, data authorizePageData) {
+	var page bytes.Buffer
+	if err := authorizePage.Execute(&page, data); err != nil {
+		log.Printf("authorize page render failed: %v", err)
+		c.String(http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Header("X-Frame-Options", "DENY")
+	c.Header("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	c.Data(status, "text/html; charset=utf-8", page.Bytes())
+}
@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/passkey"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/webauthn"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasskeyHandlerPort represents the passkey handler port
+type PasskeyHandlerPort interface {
+	BeginRegistration(c *gin.Context)
+	FinishRegistration(c *gin.Context)
+	BeginLogin(c *gin.Context)
+	FinishLogin(c *gin.Context)
+	Get(c *gin.Context)
+	Delete(c *gin.Context)
+}
+
+// PasskeyHandler represents the passkey handler
+type PasskeyHandler struct {
+	passkeyService passkey.PasskeyServicePort
+	authService    auth.AuthServicePort
+}
+
+// NewPasskeyHandler creates a new passkey handler to be used by the router
+func NewPasskeyHandler(passkeyService passkey.PasskeyServicePort, authService auth.AuthServicePort) *PasskeyHandler {
+	return &PasskeyHandler{
+		passkeyService: passkeyService,
+		authService:    authService,
+	}
+}
+
+// finishRegistrationRequest represents the request body of the passkey registration finish endpoint
+type finishRegistrationRequest struct {
+	Name       string                        `json:"name"`
+	Credential webauthn.RegistrationResponse `json:"credential" binding:"required"`
+}
+
+// BeginRegistration returns the options to pass to navigator.credentials.create
+func (handler *PasskeyHandler) BeginRegistration(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	options, err := handler.passkeyService.BeginRegistration(principal.UserID)
+	if err != nil {
+		c.JSON(passkeyErrorStatus(err), gin.H{
+			"error":  "Failed to start passkey registration",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishRegistration verifies and stores the credential created by the authenticator
+func (handler *PasskeyHandler) FinishRegistration(c *gin.Context) {
+	var req finishRegistrationRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	credential, err := handler.passkeyService.FinishRegistration(principal.UserID, req.Name, req.Credential)
+	if err != nil {
+		c.JSON(passkeyErrorStatus(err), gin.H{
+			"error":  "Failed to register passkey",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Passkey registered successfully",
+		"passkey": credential,
+	})
+}
+
+// BeginLogin returns the options to pass to navigator.credentials.get, no username is asked
+// since passkeys are discoverable
+func (handler *PasskeyHandler) BeginLogin(c *gin.Context) {
+	options, err := handler.passkeyService.BeginLogin()
+	if err != nil {
+		c.JSON(passkeyErrorStatus(err), gin.H{
+			"error":  "Failed to start passkey login",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"publicKey": options})
+}
+
+// FinishLogin verifies the assertion of the authenticator and issues the tokens
+func (handler *PasskeyHandler) FinishLogin(c *gin.Context) {
+	var resp webauthn.AssertionResponse
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&resp); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	result, err := handler.authService.LoginWithPasskey(resp)
+	if err != nil {
+		c.JSON(loginErrorStatus(err), gin.H{
+			"error":  "Failed to login with passkey",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	writeLoginResult(c, result)
+}
+
+// Get lists the passkeys of the current user
+func (handler *PasskeyHandler) Get(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	credentials, err := handler.passkeyService.Get(principal.UserID)
+	if err != nil {
+		c.JSON(passkeyErrorStatus(err), gin.H{
+			"error":  "Failed to fetch passkeys",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"passkeys": credentials})
+}
+
+// Delete removes a passkey of the current user
+func (handler *PasskeyHandler) Delete(c *gin.Context) {
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	id, _ := strconv.Atoi(c.Param("id"))
+	if err := handler.passkeyService.Delete(principal.UserID, id); err != nil {
+		c.JSON(passkeyErrorStatus(err), gin.H{
+			"error":  "Failed to delete passkey",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Passkey deleted successfully"})
+}
+
+// passkeyErrorStatus maps the passkey errors to the http status returned to the client
+func passkeyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, passkey.ErrPasskeyVerification), errors.Is(err, passkey.ErrInvalidChallenge):
+		return http.StatusUnauthorized
+	case errors.Is(err, passkey.ErrCredentialNotFound), errors.Is(err, user.ErrInvalidUserID):
+		return http.StatusNotFound
+	case errors.Is(err, passkey.ErrPasskeyAlreadyRegistered):
+		return http.StatusConflict
+	case errors.Is(err, passkey.ErrInvalidPasskeyName):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
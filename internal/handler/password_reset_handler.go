@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/user"
+	"user-authentication/pkg/password"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PasswordResetHandlerPort represents the password reset handler port
+type PasswordResetHandlerPort interface {
+	Forgot(c *gin.Context)
+	Reset(c *gin.Context)
+}
+
+// PasswordResetHandler represents the password reset handler
+type PasswordResetHandler struct {
+	passwordResetService auth.PasswordResetServicePort
+}
+
+// NewPasswordResetHandler creates a new password reset handler to be used by the router
+func NewPasswordResetHandler(passwordResetService auth.PasswordResetServicePort) *PasswordResetHandler {
+	return &PasswordResetHandler{
+		passwordResetService: passwordResetService,
+	}
+}
+
+// forgotPasswordRequest represents the request body of the forgot password endpoint
+type forgotPasswordRequest struct {
+	Email string `json:"email" binding:"required"`
+}
+
+// Forgot sends a password reset link, the response is the same whether the account exists or not
+func (handler *PasswordResetHandler) Forgot(c *gin.Context) {
+	var req forgotPasswordRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	if err := handler.passwordResetService.Forgot(req.Email); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Failed to request a password reset",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// resetPasswordRequest represents the request body of the reset password endpoint
+type resetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// Reset sets a new password with a password reset token
+func (handler *PasswordResetHandler) Reset(c *gin.Context) {
+	var req resetPasswordRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	err := handler.passwordResetService.Reset(req.Token, req.Password)
+	if err != nil {
+		c.JSON(resetErrorStatus(err), passwordErrorBody("Failed to reset password", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password reset successfully"})
+}
+
+// resetErrorStatus maps the password reset errors to the http status returned to the client
+func resetErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, auth.ErrInvalidResetToken):
+		return http.StatusUnauthorized
+	case errors.Is(err, user.ErrInvalidUserPwd), errors.Is(err, user.ErrPasswordUnchanged),
+		errors.Is(err, user.ErrPasswordReused), errors.Is(err, password.ErrPolicyViolation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
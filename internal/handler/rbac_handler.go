@@ -0,0 +1,113 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"user-authentication/internal/core/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RBACHandlerPort interface {
+	GetRoles(c *gin.Context)
+	GetUserRoles(c *gin.Context)
+	AssignRole(c *gin.Context)
+	RemoveRole(c *gin.Context)
+}
+
+type RBACHandler struct {
+	rbacService rbac.RBACServicePort
+}
+
+func NewRBACHandler(rbacService rbac.RBACServicePort) *RBACHandler {
+	return &RBACHandler{
+		rbacService: rbacService,
+	}
+}
+
+// assignRoleRequest represents the request body of the role assignment endpoint
+type assignRoleRequest struct {
+	Role string `json:"role" binding:"required"`
+}
+
+func (handler *RBACHandler) GetRoles(c *gin.Context) {
+	roles, err := handler.rbacService.GetRoles()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to fetch roles",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Roles fetch successful", "roles": roles})
+}
+
+func (handler *RBACHandler) GetUserRoles(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	roles, err := handler.rbacService.GetUserRoles(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to fetch user roles",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User roles fetch successful", "roles": roles})
+}
+
+func (handler *RBACHandler) AssignRole(c *gin.Context) {
+	var req assignRoleRequest
+
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	if err := handler.rbacService.AssignRole(userID, req.Role); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to assign role",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role assigned successfully"})
+}
+
+func (handler *RBACHandler) RemoveRole(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := handler.rbacService.RemoveRole(userID, c.Param("role")); err != nil {
+		c.JSON(removeRoleErrorStatus(err), gin.H{
+			"error":  "Failed to remove role",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Role removed successfully"})
+}
+
+// removeRoleErrorStatus maps the role removal errors to the http status returned to the client
+func removeRoleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, rbac.ErrInvalidRoleUserID), errors.Is(err, rbac.ErrInvalidRole):
+		return http.StatusBadRequest
+	case errors.Is(err, rbac.ErrRoleNotAssigned):
+		return http.StatusNotFound
+	case errors.Is(err, rbac.ErrLastAdministrator):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
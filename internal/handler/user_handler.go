@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"user-authentication/internal/core/user"
+	"user-authentication/pkg/password"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,6 +15,9 @@ type UserHandlerPort interface {
 	Get(c *gin.Context)
 	GetByID(c *gin.Context)
 	Update(c *gin.Context)
+	Unlock(c *gin.Context)
+	ChangePassword(c *gin.Context)
+	PasswordExpiryReport(c *gin.Context)
 }
 
 type UserHandler struct {
@@ -40,10 +45,7 @@ func (handler *UserHandler) Create(c *gin.Context) {
 	// Call service layer to create user
 	user, err := handler.userService.Create(user)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":  "Failed to create user",
-			"detail": err.Error(),
-		})
+		c.JSON(createUserErrorStatus(err), passwordErrorBody("Failed to create user", err))
 		return
 	}
 
@@ -85,7 +87,7 @@ func (handler *UserHandler) GetByID(c *gin.Context) {
 func (handler *UserHandler) Update(c *gin.Context) {
 	var user user.User
 
-	userID, _ := strconv.Atoi(c.Query("id"))
+	userID, _ := strconv.Atoi(c.Param("id"))
 
 	// Bind JSON body to struct
 	if err := c.ShouldBindJSON(&user); err != nil {
@@ -111,3 +113,133 @@ func (handler *UserHandler) Update(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully", "user": user})
 
 }
+
+// Unlock lifts the lockout of a user account
+func (handler *UserHandler) Unlock(c *gin.Context) {
+	userID, _ := strconv.Atoi(c.Param("id"))
+
+	if err := handler.userService.Unlock(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":  "Failed to unlock user",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+// changePasswordRequest represents the request body of the password change endpoint
+type changePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+	// RevokeSessions ends every other session of the user, the current one stays valid
+	RevokeSessions bool `json:"revoke_sessions"`
+}
+
+// ChangePassword changes the password of the current user after re-verifying the current one
+func (handler *UserHandler) ChangePassword(c *gin.Context) {
+	var req changePasswordRequest
+
+	// Bind JSON body to struct
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":  "Invalid request body",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	principal, ok := GetPrincipal(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing authenticated principal"})
+		return
+	}
+
+	caller := user.Caller{UserID: principal.UserID, TokenID: principal.TokenID, SessionID: principal.SessionID}
+	err := handler.userService.ChangePassword(caller, req.CurrentPassword, req.NewPassword, req.RevokeSessions)
+	if err != nil {
+		c.JSON(changePasswordErrorStatus(err), passwordErrorBody("Failed to change password", err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Password changed successfully",
+		"sessions_revoked": req.RevokeSessions,
+	})
+}
+
+// PasswordExpiryReport lists the accounts whose password expires within the days query parameter,
+// the warning period by default
+func (handler *UserHandler) PasswordExpiryReport(c *gin.Context) {
+	withinDays := 0
+	if days := c.Query("days"); days != "" {
+		var err error
+		if withinDays, err = strconv.Atoi(days); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":  "Invalid days",
+				"detail": err.Error(),
+			})
+			return
+		}
+	}
+
+	report, err := handler.userService.PasswordExpiryReport(withinDays)
+	if err != nil {
+		c.JSON(passwordExpiryErrorStatus(err), gin.H{
+			"error":  "Failed to get the password expiry report",
+			"detail": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": report})
+}
+
+// passwordExpiryErrorStatus maps the password expiry report errors to the http status returned to the client
+func passwordExpiryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, user.ErrPasswordExpiryDisabled), errors.Is(err, user.ErrInvalidUserDetails):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// createUserErrorStatus maps the user creation and signup errors to the http status returned to the client
+func createUserErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, user.ErrInvalidUserDetails), errors.Is(err, password.ErrPolicyViolation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// passwordErrorBody builds the error response, password policy errors also list every violated rule
+func passwordErrorBody(message string, err error) gin.H {
+	body := gin.H{
+		"error":  message,
+		"detail": err.Error(),
+	}
+
+	var policyErr *password.PolicyError
+	if errors.As(err, &policyErr) {
+		body["violations"] = policyErr.Violations
+	}
+
+	return body
+}
+
+// changePasswordErrorStatus maps the password change errors to the http status returned to the client
+func changePasswordErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, user.ErrIncorrectCurrentPwd):
+		return http.StatusUnauthorized
+	case errors.Is(err, user.ErrInvalidUserPwd), errors.Is(err, user.ErrPasswordUnchanged),
+		errors.Is(err, user.ErrPasswordReused), errors.Is(err, password.ErrPolicyViolation):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
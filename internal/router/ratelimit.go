@@ -0,0 +1,101 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"user-authentication/pkg/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitKeyFunc extracts the value a request is limited by, an empty key skips the limit
+type rateLimitKeyFunc func(c *gin.Context) string
+
+// rateLimitKeyFuncs maps the configurable key types to their extractors
+var rateLimitKeyFuncs = map[string]rateLimitKeyFunc{
+	ratelimit.KEY_TYPE_IP:       clientIPKey,
+	ratelimit.KEY_TYPE_USERNAME: jsonFieldKey("username"),
+	ratelimit.KEY_TYPE_EMAIL:    jsonFieldKey("email"),
+}
+
+// RateLimit throttles the route by every key type configured for it, routes without
+// configured limits are not throttled
+func (r *Router) RateLimit(route string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, keyType := range []string{ratelimit.KEY_TYPE_IP, ratelimit.KEY_TYPE_USERNAME, ratelimit.KEY_TYPE_EMAIL} {
+			limit, ok := r.rateLimitRules.Get(route, keyType)
+			if !ok {
+				continue
+			}
+
+			key := rateLimitKeyFuncs[keyType](c)
+			if key == "" {
+				continue
+			}
+
+			result, err := r.rateLimitStore.Allow(c.Request.Context(), route+":"+keyType+":"+key, limit)
+			if err != nil {
+				// an unavailable store must not take the login down
+				log.Printf("rate limit store failed, allowing request: %v", err)
+				continue
+			}
+
+			setRateLimitHeaders(c, result)
+			if !result.Allowed {
+				c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, retry later"})
+				c.AbortWithStatus(http.StatusTooManyRequests)
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// setRateLimitHeaders sets the RateLimit header fields of the IETF httpapi draft
+func setRateLimitHeaders(c *gin.Context, result ratelimit.Result) {
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// clientIPKey limits by the client IP address
+func clientIPKey(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// jsonFieldKey limits by a field of the JSON request body, the body is restored for the handler
+func jsonFieldKey(field string) rateLimitKeyFunc {
+	return func(c *gin.Context) string {
+		if c.Request.Body == nil {
+			return ""
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		if err != nil {
+			return ""
+		}
+
+		var fields map[string]any
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return ""
+		}
+
+		value, _ := fields[field].(string)
+
+		return strings.ToLower(strings.TrimSpace(value))
+	}
+}
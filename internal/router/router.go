@@ -2,29 +2,69 @@ package router
 
 import (
 	"net/http"
+	"strconv"
 	"strings"
+	"user-authentication/internal/core/auth"
+	"user-authentication/internal/core/rbac"
 	"user-authentication/internal/handler"
+	"user-authentication/pkg/ratelimit"
 
 	"github.com/gin-gonic/gin"
 )
 
+// passwordChangePath is the only route accepting the token issued on a login with an expired password
+const passwordChangePath = "/user/me/password"
+
 type Router struct {
 	e           *gin.Engine
 	port        string
 	userHandler handler.UserHandlerPort
 	authHandler handler.AuthHandlerPort
+	rbacHandler handler.RBACHandlerPort
+
+	passwordResetHandler     handler.PasswordResetHandlerPort
+	emailVerificationHandler handler.EmailVerificationHandlerPort
+	mfaHandler               handler.MFAHandlerPort
+	passkeyHandler           handler.PasskeyHandlerPort
+	magicLinkHandler         handler.MagicLinkHandlerPort
+	oauthHandler             handler.OAuthHandlerPort
+	apiKeyHandler            handler.APIKeyHandlerPort
+
+	rateLimitStore ratelimit.Store
+	rateLimitRules ratelimit.Rules
 }
 
 func NewRouter(
 	port string,
 	userHandler handler.UserHandlerPort,
 	authHandler handler.AuthHandlerPort,
+	rbacHandler handler.RBACHandlerPort,
+	passwordResetHandler handler.PasswordResetHandlerPort,
+	emailVerificationHandler handler.EmailVerificationHandlerPort,
+	mfaHandler handler.MFAHandlerPort,
+	passkeyHandler handler.PasskeyHandlerPort,
+	magicLinkHandler handler.MagicLinkHandlerPort,
+	oauthHandler handler.OAuthHandlerPort,
+	apiKeyHandler handler.APIKeyHandlerPort,
+	rateLimitStore ratelimit.Store,
+	rateLimitRules ratelimit.Rules,
 ) *Router {
 	return &Router{
-		e:           nil,
-		port:        port,
-		userHandler: userHandler,
-		authHandler: authHandler,
+		e:              nil,
+		port:           port,
+		userHandler:    userHandler,
+		authHandler:    authHandler,
+		rbacHandler:    rbacHandler,
+		rateLimitStore: rateLimitStore,
+		rateLimitRules: rateLimitRules,
+
+		passwordResetHandler:     passwordResetHandler,
+		emailVerificationHandler: emailVerificationHandler,
+		mfaHandler:               mfaHandler,
+		passkeyHandler:           passkeyHandler,
+		magicLinkHandler:         magicLinkHandler,
+		oauthHandler:             oauthHandler,
+		apiKeyHandler:            apiKeyHandler,
 	}
 }
 
@@ -37,6 +77,9 @@ func (r *Router) InitRouter() {
 func (r *Router) initRoutes() {
 	r.registerUserRoutes()
 	r.registerAuthRoutes()
+	r.registerOAuthRoutes()
+	r.registerWellKnownRoutes()
+	r.registerAdminRoutes()
 }
 
 func (r *Router) Run() {
@@ -54,42 +97,192 @@ func (r *Router) authMiddleware(c *gin.Context) {
 	}
 
 	authToken := strings.Split(authHeader, " ")
-	if len(authToken) != 2 || authToken[0] != "Bearer" {
+	if len(authToken) != 2 {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	isValid, err := r.authHandler.ValidateAccessToken(authToken[1])
+	// Bearer access tokens and personal API keys resolve to the same principal
+	var principal auth.Principal
+	var err error
+	switch authToken[0] {
+	case "Bearer":
+		principal, err = r.authHandler.ValidateAccessToken(authToken[1])
+	case "ApiKey":
+		principal, err = r.apiKeyHandler.ValidateAPIKey(authToken[1])
+	default:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token format"})
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		c.AbortWithStatus(http.StatusUnauthorized)
 		return
 	}
 
-	if !isValid {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": err})
-		c.AbortWithStatus(http.StatusUnauthorized)
+	if principal.IsPasswordChangeOnly() && c.FullPath() != passwordChangePath {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Password expired, change the password to continue"})
+		c.AbortWithStatus(http.StatusForbidden)
 		return
 	}
 
+	// expose the caller to the handlers and the services
+	handler.SetPrincipal(c, principal)
 	c.Next()
 
 }
 
+// RequirePermission only lets principals holding the permission through,
+// it must run after the authMiddleware
+func (r *Router) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := handler.GetPrincipal(c)
+		if !ok || !principal.HasPermission(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireUser only lets tokens issued for a user through. A client credentials token acts on
+// no user and an API key only carries permissions, so they're limited to the routes guarded
+// by a permission. It must run after the authMiddleware.
+func (r *Router) RequireUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := handler.GetPrincipal(c)
+		if !ok || principal.IsClient() || principal.IsAPIKey() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The route requires a user token"})
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireSelfService only lets first-party user tokens through, for the routes managing the
+// account and its credentials. Tokens issued to OAuth clients, even on behalf of the user,
+// and API keys are rejected. It must run after the authMiddleware.
+func (r *Router) RequireSelfService() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := handler.GetPrincipal(c)
+		if !ok || principal.IsClient() || principal.IsDelegated() || principal.IsAPIKey() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The route requires a first-party user token"})
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequirePermissionOrSelf lets principals holding the permission through, as well as
+// self-service users acting on their own record identified by the idParam path parameter
+func (r *Router) RequirePermissionOrSelf(permission string, idParam string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, ok := handler.GetPrincipal(c)
+		if !ok {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		userID, err := strconv.Atoi(c.Param(idParam))
+		isSelf := err == nil && !principal.IsClient() && !principal.IsDelegated() && !principal.IsAPIKey() &&
+			principal.UserID == userID
+		if !isSelf && !principal.HasPermission(permission) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission " + permission})
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Next()
+	}
+}
+
 // registerUserRoutes registers the user routes
 func (r *Router) registerUserRoutes() {
 	userGroup := r.e.Group("/user")
 	userGroup.Use(r.authMiddleware)
-	userGroup.POST("", r.userHandler.Create)
-	userGroup.GET("", r.userHandler.Get)
-	userGroup.GET("/:id", r.userHandler.GetByID)
-	userGroup.PUT("/:id", r.userHandler.Update)
+	userGroup.POST("", r.RequirePermission(rbac.PERMISSION_USERS_WRITE), r.userHandler.Create)
+	userGroup.GET("", r.RequirePermission(rbac.PERMISSION_USERS_READ), r.userHandler.Get)
+	userGroup.GET("/:id", r.RequirePermissionOrSelf(rbac.PERMISSION_USERS_READ, "id"), r.userHandler.GetByID)
+	userGroup.PUT("/:id", r.RequirePermissionOrSelf(rbac.PERMISSION_USERS_WRITE, "id"), r.userHandler.Update)
+
+	// self-service routes of the calling user
+	meGroup := userGroup.Group("/me", r.RequireSelfService())
+	meGroup.POST("/password", r.RateLimit("password_change"), r.userHandler.ChangePassword)
+	meGroup.POST("/mfa", r.mfaHandler.Enroll)
+	meGroup.POST("/mfa/confirm", r.RateLimit("mfa_manage"), r.mfaHandler.Confirm)
+	meGroup.DELETE("/mfa", r.RateLimit("mfa_manage"), r.mfaHandler.Disable)
+	meGroup.GET("/passkeys", r.passkeyHandler.Get)
+	meGroup.DELETE("/passkeys/:id", r.passkeyHandler.Delete)
+	meGroup.GET("/api-keys", r.apiKeyHandler.Get)
+	meGroup.POST("/api-keys", r.apiKeyHandler.Create)
+	meGroup.DELETE("/api-keys/:id", r.apiKeyHandler.Delete)
 }
 
 // registerAuthRoutes registers the authentication routes
 func (r *Router) registerAuthRoutes() {
 	authGroup := r.e.Group("/auth")
-	authGroup.GET("/login", r.authHandler.Login)
-	authGroup.POST("/signup", r.authHandler.Signup)
+	authGroup.GET("/login", r.RateLimit("login"), r.authHandler.Login)
+	authGroup.POST("/signup", r.RateLimit("signup"), r.authHandler.Signup)
+	authGroup.POST("/mfa/verify", r.RateLimit("mfa_verify"), r.authHandler.VerifyMFA)
+	authGroup.POST("/refresh", r.authHandler.Refresh)
+	authGroup.POST("/logout", r.authMiddleware, r.RequireUser(), r.authHandler.Logout)
+	authGroup.POST("/logout-all", r.authMiddleware, r.RequireSelfService(), r.authHandler.LogoutAll)
+	authGroup.POST("/magic-link", r.RateLimit("magic_link"), r.magicLinkHandler.Request)
+	authGroup.GET("/magic-link/callback", r.magicLinkHandler.ConfirmPage)
+	authGroup.POST("/magic-link/callback", r.RateLimit("magic_link_callback"), r.magicLinkHandler.Callback)
+	authGroup.POST("/password/forgot", r.RateLimit("password_forgot"), r.passwordResetHandler.Forgot)
+	authGroup.POST("/password/reset", r.RateLimit("password_reset"), r.passwordResetHandler.Reset)
+	authGroup.GET("/verify-email", r.emailVerificationHandler.ConfirmPage)
+	authGroup.POST("/verify-email", r.emailVerificationHandler.Verify)
+	authGroup.POST("/verify-email/resend", r.RateLimit("verify_email_resend"), r.emailVerificationHandler.Resend)
+	authGroup.POST("/webauthn/register/options", r.authMiddleware, r.RequireSelfService(), r.passkeyHandler.BeginRegistration)
+	authGroup.POST("/webauthn/register/finish", r.authMiddleware, r.RequireSelfService(), r.passkeyHandler.FinishRegistration)
+	authGroup.POST("/webauthn/login/options", r.RateLimit("passkey_login"), r.passkeyHandler.BeginLogin)
+	authGroup.POST("/webauthn/login/finish", r.RateLimit("passkey_login"), r.passkeyHandler.FinishLogin)
+}
+
+// registerOAuthRoutes registers the OAuth authorization server routes
+func (r *Router) registerOAuthRoutes() {
+	oauthGroup := r.e.Group("/oauth")
+	oauthGroup.GET("/authorize", r.oauthHandler.Authorize)
+	oauthGroup.POST("/authorize", r.RateLimit("oauth_authorize"), r.oauthHandler.AuthorizeSubmit)
+	oauthGroup.POST("/token", r.RateLimit("oauth_token"), r.oauthHandler.Token)
+	oauthGroup.POST("/introspect", r.RateLimit("oauth_introspect"), r.oauthHandler.Introspect)
+	oauthGroup.POST("/revoke", r.RateLimit("oauth_revoke"), r.oauthHandler.Revoke)
+
+	// OpenID Connect userinfo endpoint, GET and POST are both defined by the specification
+	r.e.GET("/userinfo", r.authMiddleware, r.RequireUser(), r.oauthHandler.UserInfo)
+	r.e.POST("/userinfo", r.authMiddleware, r.RequireUser(), r.oauthHandler.UserInfo)
+}
+
+// registerWellKnownRoutes registers the public discovery routes
+func (r *Router) registerWellKnownRoutes() {
+	wellKnownGroup := r.e.Group("/.well-known")
+	wellKnownGroup.GET("/jwks.json", r.authHandler.JWKS)
+	wellKnownGroup.GET("/openid-configuration", r.oauthHandler.Discovery)
+}
+
+// registerAdminRoutes registers the administration routes
+func (r *Router) registerAdminRoutes() {
+	adminGroup := r.e.Group("/admin")
+	adminGroup.Use(r.authMiddleware)
+	adminGroup.GET("/roles", r.RequirePermission(rbac.PERMISSION_ROLES_READ), r.rbacHandler.GetRoles)
+	adminGroup.GET("/users/:id/roles", r.RequirePermission(rbac.PERMISSION_ROLES_READ), r.rbacHandler.GetUserRoles)
+	adminGroup.POST("/users/:id/roles", r.RequirePermission(rbac.PERMISSION_ROLES_WRITE), r.rbacHandler.AssignRole)
+	adminGroup.DELETE("/users/:id/roles/:role", r.RequirePermission(rbac.PERMISSION_ROLES_WRITE), r.rbacHandler.RemoveRole)
+	adminGroup.GET("/users/password-expiry", r.RequirePermission(rbac.PERMISSION_USERS_READ), r.userHandler.PasswordExpiryReport)
+	adminGroup.POST("/users/:id/unlock", r.RequirePermission(rbac.PERMISSION_USERS_WRITE), r.userHandler.Unlock)
+	adminGroup.GET("/oauth/clients", r.RequirePermission(rbac.PERMISSION_CLIENTS_READ), r.oauthHandler.GetClients)
+	adminGroup.POST("/oauth/clients", r.RequirePermission(rbac.PERMISSION_CLIENTS_WRITE), r.oauthHandler.CreateClient)
+	adminGroup.DELETE("/oauth/clients/:client_id", r.RequirePermission(rbac.PERMISSION_CLIENTS_WRITE), r.oauthHandler.DeleteClient)
+	adminGroup.POST("/oauth/clients/:client_id/secret", r.RequirePermission(rbac.PERMISSION_CLIENTS_WRITE), r.oauthHandler.RotateClientSecret)
 }
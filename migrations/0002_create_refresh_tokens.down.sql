@@ -0,0 +1,4 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the creation of the refresh_tokens table
+DROP TABLE IF EXISTS refresh_tokens;
@@ -0,0 +1,14 @@
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,                          -- auto-incrementing primary key
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- token owner
+    token_hash VARCHAR(64) UNIQUE NOT NULL,         -- sha256 hash of the refresh token
+    family_id VARCHAR(64) NOT NULL,                 -- rotation family shared by all descendants of a login
+    parent_id INTEGER REFERENCES refresh_tokens(id) ON DELETE SET NULL, -- token this one was rotated from
+    expires_at TIMESTAMP NOT NULL,                  -- token expiry
+    revoked_at TIMESTAMP,                           -- set once the token is rotated or revoked
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- record creation timestamp
+);
+
+CREATE INDEX idx_refresh_tokens_family_id ON refresh_tokens(family_id);
+CREATE INDEX idx_refresh_tokens_user_id ON refresh_tokens(user_id);
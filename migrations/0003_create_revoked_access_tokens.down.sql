@@ -0,0 +1,7 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the tokens_valid_after column of the user_accounts table
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS tokens_valid_after;
+
+-- Revert the creation of the revoked_access_tokens table
+DROP TABLE IF EXISTS revoked_access_tokens;
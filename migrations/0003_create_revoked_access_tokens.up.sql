@@ -0,0 +1,12 @@
+CREATE TABLE revoked_access_tokens (
+    jti VARCHAR(64) PRIMARY KEY,                    -- revoked access token ID
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- token owner
+    expires_at TIMESTAMP NOT NULL,                  -- token expiry, the entry can be purged afterwards
+    -- metadata
+    revoked_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP  -- record creation timestamp
+);
+
+CREATE INDEX idx_revoked_access_tokens_expires_at ON revoked_access_tokens(expires_at);
+
+-- access tokens issued before this timestamp are rejected (logout from all devices)
+ALTER TABLE user_accounts ADD COLUMN tokens_valid_after TIMESTAMP;
@@ -0,0 +1,14 @@
+CREATE TABLE signing_keys (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    kid VARCHAR(64) UNIQUE NOT NULL,        -- key ID published in the token header and the JWKS
+    algorithm VARCHAR(16) NOT NULL,         -- signing algorithm (RS256, ES256, EdDSA)
+    status VARCHAR(16) NOT NULL,            -- pending, active, retiring or retired
+    activated_at TIMESTAMP,                 -- timestamp the key started signing tokens
+    retire_at TIMESTAMP,                    -- end of the verification grace period of a retiring key
+    retired_at TIMESTAMP,                   -- timestamp the key stopped verifying tokens
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+-- only a single key can sign tokens at a time
+CREATE UNIQUE INDEX idx_signing_keys_single_active ON signing_keys(status) WHERE status = 'active';
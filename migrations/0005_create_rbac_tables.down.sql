@@ -0,0 +1,7 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the creation of the role based access control tables
+DROP TABLE IF EXISTS role_permissions;
+DROP TABLE IF EXISTS user_roles;
+DROP TABLE IF EXISTS permissions;
+DROP TABLE IF EXISTS roles;
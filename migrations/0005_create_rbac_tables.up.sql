@@ -0,0 +1,47 @@
+CREATE TABLE roles (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    name VARCHAR(50) UNIQUE NOT NULL,       -- unique role name embedded in access tokens
+    description VARCHAR(255),               -- role description
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+CREATE TABLE permissions (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    name VARCHAR(100) UNIQUE NOT NULL,      -- unique permission name, e.g. users:write
+    description VARCHAR(255),               -- permission description
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+CREATE TABLE user_roles (
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE,
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- record creation timestamp
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE TABLE role_permissions (
+    role_id INTEGER NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id INTEGER NOT NULL REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+-- default roles and permissions
+INSERT INTO roles (name, description) VALUES
+    ('admin', 'Administrator with access to every account'),
+    ('user', 'Self-service user, can only read and update their own account');
+
+INSERT INTO permissions (name, description) VALUES
+    ('users:read', 'Read every user account'),
+    ('users:write', 'Create and update every user account'),
+    ('roles:read', 'Read the roles of every user account'),
+    ('roles:write', 'Assign and remove roles of every user account');
+
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT r.id, p.id FROM roles r CROSS JOIN permissions p WHERE r.name = 'admin';
+
+-- existing accounts become self-service users
+INSERT INTO user_roles (user_id, role_id)
+    SELECT u.id, r.id FROM user_accounts u CROSS JOIN roles r WHERE r.name = 'user';
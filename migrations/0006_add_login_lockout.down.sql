@@ -0,0 +1,6 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the failed login tracking columns of the user_accounts table
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS locked_until;
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS lockout_count;
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS failed_login_attempts;
@@ -0,0 +1,8 @@
+-- failed login tracking used by the account lockout
+ALTER TABLE user_accounts ADD COLUMN failed_login_attempts INTEGER NOT NULL DEFAULT 0; -- consecutive failed logins
+ALTER TABLE user_accounts ADD COLUMN lockout_count INTEGER NOT NULL DEFAULT 0;         -- temporary lockouts since the last successful login
+ALTER TABLE user_accounts ADD COLUMN locked_until TIMESTAMP;                           -- end of the current temporary lockout
+
+-- accounts were created with is_active taken from the request body, which defaulted to false,
+-- while nothing deactivated accounts; activate them now that login enforces the flag
+UPDATE user_accounts SET is_active = TRUE WHERE is_active = FALSE;
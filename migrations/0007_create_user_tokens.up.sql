@@ -0,0 +1,12 @@
+CREATE TABLE user_tokens (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- token owner
+    purpose VARCHAR(32) NOT NULL,           -- what the token can be used for, e.g. password_reset
+    token_hash VARCHAR(64) UNIQUE NOT NULL, -- sha256 hash of the single-use token
+    expires_at TIMESTAMP NOT NULL,          -- token expiry
+    used_at TIMESTAMP,                      -- set once the token is consumed
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+CREATE INDEX idx_user_tokens_user_id_purpose ON user_tokens(user_id, purpose);
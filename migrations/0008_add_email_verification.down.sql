@@ -0,0 +1,5 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the email verification columns
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS email;
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,8 @@
+-- email verification, NULL while the user has not proved control of the email
+ALTER TABLE user_accounts ADD COLUMN email_verified_at TIMESTAMP;
+
+-- accounts created before email verification existed are trusted as verified
+UPDATE user_accounts SET email_verified_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+
+-- email a token was sent to, a verification token must not verify an email changed since
+ALTER TABLE user_tokens ADD COLUMN email VARCHAR(100);
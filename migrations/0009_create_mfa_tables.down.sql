@@ -0,0 +1,5 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the creation of the multi-factor authentication tables
+DROP TABLE IF EXISTS mfa_recovery_codes;
+DROP TABLE IF EXISTS user_mfa;
@@ -0,0 +1,18 @@
+CREATE TABLE user_mfa (
+    user_id INTEGER PRIMARY KEY REFERENCES user_accounts(id) ON DELETE CASCADE, -- enrolled user
+    secret_encrypted TEXT NOT NULL,         -- AES-GCM encrypted TOTP secret
+    enabled_at TIMESTAMP,                   -- set once enrollment is confirmed with a first code, NULL while pending
+    last_used_counter BIGINT NOT NULL DEFAULT 0, -- time step of the last accepted code, older steps are replays
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+CREATE TABLE mfa_recovery_codes (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- code owner
+    code_hash VARCHAR(64) NOT NULL,         -- sha256 hash of the single-use recovery code
+    used_at TIMESTAMP,                      -- set once the code is used
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP, -- record creation timestamp
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,5 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the creation of the webauthn tables
+DROP TABLE IF EXISTS webauthn_challenges;
+DROP TABLE IF EXISTS webauthn_credentials;
@@ -0,0 +1,26 @@
+CREATE TABLE webauthn_credentials (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- credential owner
+    credential_id BYTEA UNIQUE NOT NULL,    -- credential id chosen by the authenticator
+    public_key BYTEA NOT NULL,              -- COSE encoded credential public key
+    algorithm INTEGER NOT NULL,             -- COSE algorithm of the public key
+    sign_count BIGINT NOT NULL DEFAULT 0,   -- last signature counter reported by the authenticator
+    aaguid BYTEA,                           -- authenticator model identifier
+    transports VARCHAR(255),                -- comma separated transports hinted by the client
+    name VARCHAR(100) NOT NULL,             -- user given name of the passkey
+    last_used_at TIMESTAMP,                 -- last successful login with the passkey
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+CREATE INDEX idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+CREATE TABLE webauthn_challenges (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    challenge_hash VARCHAR(64) UNIQUE NOT NULL, -- sha256 hash of the single-use ceremony challenge
+    ceremony VARCHAR(32) NOT NULL,          -- registration or authentication
+    user_id INTEGER REFERENCES user_accounts(id) ON DELETE CASCADE, -- user the ceremony is bound to, if any
+    expires_at TIMESTAMP NOT NULL,          -- challenge expiry
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
@@ -0,0 +1,13 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the OAuth client administration permissions
+DELETE FROM permissions WHERE name IN ('clients:read', 'clients:write');
+
+-- Revert the client binding of the refresh tokens
+ALTER TABLE refresh_tokens
+    DROP COLUMN IF EXISTS scope,
+    DROP COLUMN IF EXISTS client_id;
+
+-- Revert the creation of the OAuth tables
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
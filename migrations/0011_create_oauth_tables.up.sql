@@ -0,0 +1,37 @@
+CREATE TABLE oauth_clients (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    client_id VARCHAR(64) UNIQUE NOT NULL,  -- public identifier of the client
+    name VARCHAR(100) NOT NULL,             -- name shown on the consent page
+    redirect_uris TEXT NOT NULL,            -- space separated redirect URIs, matched exactly
+    scopes TEXT NOT NULL DEFAULT '',        -- space separated scopes the client may request
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+CREATE TABLE oauth_authorization_codes (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    code_hash VARCHAR(64) UNIQUE NOT NULL,  -- sha256 hash of the single-use authorization code
+    client_id VARCHAR(64) NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE, -- client the code was issued to
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- user who granted the access
+    redirect_uri TEXT NOT NULL,             -- redirect URI of the authorization request
+    scope TEXT NOT NULL DEFAULT '',         -- granted scope
+    code_challenge VARCHAR(128) NOT NULL,   -- PKCE S256 code challenge
+    expires_at TIMESTAMP NOT NULL,          -- code expiry
+    used_at TIMESTAMP,                      -- set once the code is exchanged
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+-- refresh tokens issued to OAuth clients can only be used by the same client
+ALTER TABLE refresh_tokens
+    ADD COLUMN client_id VARCHAR(64) REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+    ADD COLUMN scope TEXT;
+
+-- OAuth client administration
+INSERT INTO permissions (name, description) VALUES
+    ('clients:read', 'Read the registered OAuth clients'),
+    ('clients:write', 'Register and delete OAuth clients');
+
+INSERT INTO role_permissions (role_id, permission_id)
+    SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+    WHERE r.name = 'admin' AND p.name IN ('clients:read', 'clients:write');
@@ -0,0 +1,4 @@
+ALTER TABLE oauth_authorization_codes
+    DROP COLUMN IF EXISTS nonce,
+    DROP COLUMN IF EXISTS auth_time,
+    DROP COLUMN IF EXISTS amr;
@@ -0,0 +1,5 @@
+-- OpenID Connect parameters carried from the authorization request to the ID token
+ALTER TABLE oauth_authorization_codes
+    ADD COLUMN nonce VARCHAR(255) NOT NULL DEFAULT '', -- nonce of the authentication request
+    ADD COLUMN auth_time TIMESTAMP,                    -- time the user authenticated
+    ADD COLUMN amr VARCHAR(64) NOT NULL DEFAULT '';    -- space separated authentication methods
@@ -0,0 +1,5 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the client secrets
+ALTER TABLE oauth_clients
+    DROP COLUMN IF EXISTS secret_hash;
@@ -0,0 +1,3 @@
+-- confidential clients authenticate with a secret, public clients have none
+ALTER TABLE oauth_clients
+    ADD COLUMN secret_hash VARCHAR(64); -- sha256 hash of the client secret, NULL for public clients
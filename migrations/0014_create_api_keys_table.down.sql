@@ -0,0 +1,4 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the creation of the api_keys table
+DROP TABLE IF EXISTS api_keys;
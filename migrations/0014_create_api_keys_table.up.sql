@@ -0,0 +1,14 @@
+CREATE TABLE api_keys (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- key owner
+    name VARCHAR(100) NOT NULL,             -- user given name of the key
+    prefix VARCHAR(16) UNIQUE NOT NULL,     -- leading characters of the key, used for the lookup
+    key_hash VARCHAR(64) NOT NULL,          -- sha256 hash of the whole key
+    scopes TEXT NOT NULL DEFAULT '',        -- space separated permissions the key is limited to, empty for all
+    expires_at TIMESTAMP,                   -- key expiry, NULL when the key does not expire
+    last_used_at TIMESTAMP,                 -- last request authenticated with the key
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- record creation timestamp
+);
+
+CREATE INDEX idx_api_keys_user_id ON api_keys(user_id);
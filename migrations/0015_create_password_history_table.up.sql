@@ -0,0 +1,9 @@
+CREATE TABLE password_history (
+    id SERIAL PRIMARY KEY,                  -- auto-incrementing primary key
+    user_id INTEGER NOT NULL REFERENCES user_accounts(id) ON DELETE CASCADE, -- owner of the password
+    password_hash VARCHAR(255) NOT NULL,    -- hash of a replaced password
+    -- metadata
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP -- time the password was replaced
+);
+
+CREATE INDEX idx_password_history_user_id ON password_history(user_id, created_at);
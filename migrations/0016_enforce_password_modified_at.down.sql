@@ -0,0 +1,8 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+-- Revert the password modification time constraints, the backfilled values are kept
+DROP INDEX IF EXISTS idx_user_accounts_password_modified_at;
+
+ALTER TABLE user_accounts
+    ALTER COLUMN password_modified_at DROP NOT NULL,
+    ALTER COLUMN password_modified_at DROP DEFAULT;
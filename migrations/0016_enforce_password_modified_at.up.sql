@@ -0,0 +1,8 @@
+-- accounts without a password modification time are aged from their creation
+UPDATE user_accounts SET password_modified_at = COALESCE(created_at, CURRENT_TIMESTAMP) WHERE password_modified_at IS NULL;
+
+ALTER TABLE user_accounts
+    ALTER COLUMN password_modified_at SET DEFAULT CURRENT_TIMESTAMP,
+    ALTER COLUMN password_modified_at SET NOT NULL;
+
+CREATE INDEX idx_user_accounts_password_modified_at ON user_accounts(password_modified_at);
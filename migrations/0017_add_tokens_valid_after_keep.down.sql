@@ -0,0 +1,4 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS tokens_valid_after_keep_token;
+ALTER TABLE user_accounts DROP COLUMN IF EXISTS tokens_valid_after_keep_session;
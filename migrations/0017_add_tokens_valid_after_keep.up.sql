@@ -0,0 +1,3 @@
+-- session and access token left valid by tokens_valid_after, e.g. the caller of a password change
+ALTER TABLE user_accounts ADD COLUMN tokens_valid_after_keep_session VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE user_accounts ADD COLUMN tokens_valid_after_keep_token VARCHAR(64) NOT NULL DEFAULT '';
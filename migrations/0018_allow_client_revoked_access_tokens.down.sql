@@ -0,0 +1,4 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+DELETE FROM revoked_access_tokens WHERE user_id IS NULL;
+ALTER TABLE revoked_access_tokens ALTER COLUMN user_id SET NOT NULL;
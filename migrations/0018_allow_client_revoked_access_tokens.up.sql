@@ -0,0 +1,2 @@
+-- access tokens of a client acting on its own behalf have no user
+ALTER TABLE revoked_access_tokens ALTER COLUMN user_id DROP NOT NULL;
@@ -0,0 +1,3 @@
+-- This file is used to revert the changes made in the corresponding up migration file.
+
+DROP TABLE IF EXISTS client_token_cutoffs;
@@ -0,0 +1,9 @@
+-- access tokens issued to a client before the cutoff are rejected (client deletion or secret rotation),
+-- the table has no foreign key so that the cutoff outlives a deleted client
+CREATE TABLE client_token_cutoffs (
+    client_id VARCHAR(64) PRIMARY KEY,              -- OAuth client whose tokens are revoked
+    tokens_valid_after TIMESTAMP NOT NULL,          -- access tokens issued before are rejected
+    expires_at TIMESTAMP NOT NULL                   -- expiry of the last revoked token, the entry can be purged afterwards
+);
+
+CREATE INDEX idx_client_token_cutoffs_expires_at ON client_token_cutoffs(expires_at);
@@ -0,0 +1,111 @@
+package jwt
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidSubject  = errors.New("id token generation: subject cannot be empty")
+	ErrInvalidAudience = errors.New("id token generation: audience cannot be empty")
+	ErrSymmetricKey    = errors.New("id token generation: an asymmetric signing key is required")
+)
+
+// IDToken represents the end-user claims of an OpenID Connect ID token
+type IDToken struct {
+	Subject string
+	// Audience is the client id of the relying party
+	Audience string
+	Nonce    string
+	AuthTime time.Time
+	// AMR lists the authentication methods used, e.g. pwd, otp and mfa
+	AMR               []string
+	Email             string
+	EmailVerified     *bool
+	PreferredUsername string
+}
+
+// IDTokenClaims represents the claims of a signed ID token
+type IDTokenClaims struct {
+	Nonce             string           `json:"nonce,omitempty"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	AMR               []string         `json:"amr,omitempty"`
+	Email             string           `json:"email,omitempty"`
+	EmailVerified     *bool            `json:"email_verified,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// CreateIDToken signs an ID token for the relying party, the issuer and the lifetime are
+// the ones of the access tokens
+func (authToken *AuthToken) CreateIDToken(idToken IDToken) (string, error) {
+	if authToken.keyRing == nil {
+
+		return "", jwt.ErrHashUnavailable
+	}
+
+	if idToken.Subject == "" {
+
+		return "", ErrInvalidSubject
+	}
+	if idToken.Audience == "" {
+
+		return "", ErrInvalidAudience
+	}
+
+	// every client holding the shared secret could forge the ID tokens of the others
+	signingKey, err := authToken.keyRing.SigningKey()
+	if err != nil {
+
+		return "", err
+	}
+	if !signingKey.IsAsymmetric() {
+
+		return "", ErrSymmetricKey
+	}
+
+	now := time.Now()
+	claims := &IDTokenClaims{
+		Nonce:             idToken.Nonce,
+		AMR:               idToken.AMR,
+		Email:             idToken.Email,
+		EmailVerified:     idToken.EmailVerified,
+		PreferredUsername: idToken.PreferredUsername,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    authToken.issuer,
+			Subject:   idToken.Subject,
+			Audience:  jwt.ClaimStrings{idToken.Audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(authToken.accessTokenExpiry)),
+		},
+	}
+	if !idToken.AuthTime.IsZero() {
+		claims.AuthTime = jwt.NewNumericDate(idToken.AuthTime)
+	}
+
+	_, signedToken, err := authToken.sign(claims, TOKEN_TYPE_ID)
+	if err != nil {
+
+		return "", err
+	}
+
+	return signedToken, nil
+}
+
+// TokenSigningAlgorithm returns the algorithm the tokens are signed with
+func (config AuthTokenConfig) TokenSigningAlgorithm() string {
+	if config.SigningAlgorithm == "" {
+
+		return ALG_HS256
+	}
+
+	return config.SigningAlgorithm
+}
+
+// SupportsIDTokens reports whether ID tokens can be issued, they must be signed with an
+// asymmetric key so that relying parties verify them with the published JWKS
+func (config AuthTokenConfig) SupportsIDTokens() bool {
+	return config.TokenSigningAlgorithm() != ALG_HS256
+}
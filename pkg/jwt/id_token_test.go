@@ -0,0 +1,95 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testConfig returns a configuration signing with a new ES256 key, without audience
+func testConfig(t *testing.T) AuthTokenConfig {
+	t.Helper()
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("key generation failed: %v", err)
+	}
+	signingKey, err := NewAsymmetricSigningKey(ALG_ES256, privateKey, "")
+	if err != nil {
+		t.Fatalf("NewAsymmetricSigningKey() failed: %v", err)
+	}
+
+	return AuthTokenConfig{SigningAlgorithm: ALG_ES256, KeyRing: NewKeyRing(signingKey)}
+}
+
+func TestValidateRejectsIDToken(t *testing.T) {
+	config := testConfig(t)
+	authToken := NewAuthToken(config)
+
+	idToken, err := authToken.CreateIDToken(IDToken{Subject: "42", Audience: "dashboard", AuthTime: time.Now()})
+	if err != nil {
+		t.Fatalf("CreateIDToken() failed: %v", err)
+	}
+
+	if _, err := NewAuthToken(config).Validate(idToken); !errors.Is(err, ErrInvalidTokenType) {
+		t.Errorf("Validate(id token) error = %v, want %v", err, ErrInvalidTokenType)
+	}
+
+	if err := authToken.Create(42, "alice", []string{"user"}); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	claims, err := NewAuthToken(config).Validate(authToken.EncodedAccessToken)
+	if err != nil {
+		t.Fatalf("Validate(access token) failed: %v", err)
+	}
+	if claims.UserID != 42 {
+		t.Errorf("Validate(access token) user = %d, want 42", claims.UserID)
+	}
+}
+
+func TestValidateTokenType(t *testing.T) {
+	config := testConfig(t)
+	authToken := NewAuthToken(config)
+
+	tests := []struct {
+		name      string
+		tokenType string
+		err       error
+	}{
+		{"access token", TOKEN_TYPE_ACCESS, nil},
+		{"access token media type", "application/at+jwt", nil},
+		{"upper case access token", "AT+JWT", nil},
+		{"id token", TOKEN_TYPE_ID, ErrInvalidTokenType},
+		{"no type", "", ErrInvalidTokenType},
+		{"other type", "logout+jwt", ErrInvalidTokenType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now := time.Now()
+			claims := &AccessTokenClaims{
+				UserID:   42,
+				UserName: "alice",
+				RegisteredClaims: jwt.RegisteredClaims{
+					Issuer:    config.TokenIssuer(),
+					Subject:   "42",
+					IssuedAt:  jwt.NewNumericDate(now),
+					ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+				},
+			}
+			_, signedToken, err := authToken.sign(claims, tt.tokenType)
+			if err != nil {
+				t.Fatalf("sign() failed: %v", err)
+			}
+
+			if _, err := authToken.Validate(signedToken); !errors.Is(err, tt.err) {
+				t.Errorf("Validate() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
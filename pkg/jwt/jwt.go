@@ -3,7 +3,10 @@ package jwt
 import (
 	"errors"
 	"log"
+	"strconv"
+	"strings"
 	"time"
+	"user-authentication/pkg/securetoken"
 
 	"github.com/golang-jwt/jwt/v5"
 )
@@ -11,64 +14,227 @@ import (
 var (
 	ErrInvalidUserID      = errors.New("token generation: Invalid user ID")
 	ErrEmptyUserName      = errors.New("token generation: Username cannot be empty")
+	ErrEmptyClientID      = errors.New("token generation: Client ID cannot be empty")
 	ErrInvalidAccessToken = errors.New("invalid access token: permission denied")
+	ErrUnknownKeyID       = errors.New("invalid access token: unknown signing key")
+	ErrInvalidTokenType   = errors.New("invalid access token: not an access token")
+)
+
+// typ header of the signed tokens, access tokens are typed as in RFC 9068 so that an ID token
+// signed with the same key and issuer is never accepted as an access token
+const (
+	TOKEN_TYPE_ACCESS = "at+jwt"
+	TOKEN_TYPE_ID     = "JWT"
+)
+
+// isAccessTokenType reports whether the typ header marks an access token, RFC 9068 allows
+// the full media type as well
+func isAccessTokenType(typ string) bool {
+	return strings.EqualFold(typ, TOKEN_TYPE_ACCESS) || strings.EqualFold(typ, "application/"+TOKEN_TYPE_ACCESS)
+}
+
+// default token settings used when the configuration leaves them empty
+const (
+	DefaultIssuer            = "mr-naveenseven/user-authentication"
+	DefaultAccessTokenExpiry = 15
 )
 
 type AuthToken struct {
-	secretKey          []byte
+	keyRing            *KeyRing
 	accessToken        *jwt.Token
 	EncodedAccessToken string
-	accessTokenExpiry  int
+	accessTokenExpiry  time.Duration
+	issuer             string
+	audience           []string
+	notBeforeSkew      time.Duration
+	clockLeeway        time.Duration
 }
 
 type AuthTokenConfig struct {
-	SecretKey         []byte
-	AccessTokenExpiry int
+	SecretKey          []byte
+	SigningAlgorithm   string
+	SigningKeyFile     string
+	SigningKeyID       string
+	SigningKeyDir      string
+	KeyGracePeriod     int
+	KeyRing            *KeyRing
+	AccessTokenExpiry  int
+	RefreshTokenExpiry int
+	// Issuer is set as iss and required on validation
+	Issuer string
+	// Audience is set as aud, validation requires one of them to be present
+	Audience []string
+	// NotBeforeSkew backdates nbf by the given seconds to tolerate clock drift of the verifiers
+	NotBeforeSkew int
+	// ClockLeeway is the tolerance in seconds applied to exp, nbf and iat on validation
+	ClockLeeway int
+}
+
+// AccessTokenTTL returns the configured access token lifetime
+func (config AuthTokenConfig) AccessTokenTTL() time.Duration {
+	if config.AccessTokenExpiry <= 0 {
+
+		return DefaultAccessTokenExpiry * time.Minute
+	}
+
+	return time.Duration(config.AccessTokenExpiry) * time.Minute
+}
+
+// TokenIssuer returns the configured issuer
+func (config AuthTokenConfig) TokenIssuer() string {
+	if config.Issuer == "" {
+
+		return DefaultIssuer
+	}
+
+	return config.Issuer
+}
+
+// LoadSigningKey loads a keyring holding the single key of the configured algorithm, HS256
+// uses the shared secret and the asymmetric algorithms read the PEM encoded private key file
+func (config *AuthTokenConfig) LoadSigningKey() error {
+	// with a key directory the keyring is managed and populated from the persisted keys
+	if config.SigningKeyDir != "" {
+		switch config.SigningAlgorithm {
+		case ALG_RS256, ALG_ES256, ALG_EDDSA:
+			config.KeyRing = NewKeyRing(nil)
+
+			return nil
+		default:
+
+			return ErrUnsupportedAlgorithm
+		}
+	}
+
+	var signingKey *SigningKey
+	var err error
+	switch config.SigningAlgorithm {
+	case "", ALG_HS256:
+		signingKey, err = NewHMACSigningKey(config.SecretKey, config.SigningKeyID)
+	case ALG_RS256, ALG_ES256, ALG_EDDSA:
+		signingKey, err = LoadSigningKeyFile(config.SigningAlgorithm, config.SigningKeyFile, config.SigningKeyID)
+	default:
+		err = ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+
+		return err
+	}
+
+	config.KeyRing = NewKeyRing(signingKey)
+
+	return nil
 }
 
 type AccessTokenClaims struct {
-	UserID   int    `json:"user_id"`
-	UserName string `json:"user_name"`
+	UserID   int      `json:"user_id,omitempty"`
+	UserName string   `json:"user_name,omitempty"`
+	Roles    []string `json:"roles,omitempty"`
+	// ClientID and Scope are set on tokens issued to an OAuth client on behalf of the user
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
+	// SessionID is the refresh token family of the login the token was issued to
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
 func NewAuthToken(config AuthTokenConfig) *AuthToken {
 
+	// configurations without a loaded keyring fall back to the shared secret
+	keyRing := config.KeyRing
+	if keyRing == nil && len(config.SecretKey) > 0 {
+		signingKey, _ := NewHMACSigningKey(config.SecretKey, config.SigningKeyID)
+		keyRing = NewKeyRing(signingKey)
+	}
+
 	return &AuthToken{
-		secretKey:          []byte(config.SecretKey),
+		keyRing:            keyRing,
 		accessToken:        nil,
 		EncodedAccessToken: "",
-		accessTokenExpiry:  config.AccessTokenExpiry,
+		accessTokenExpiry:  config.AccessTokenTTL(),
+		issuer:             config.TokenIssuer(),
+		audience:           config.Audience,
+		notBeforeSkew:      time.Duration(config.NotBeforeSkew) * time.Second,
+		clockLeeway:        time.Duration(config.ClockLeeway) * time.Second,
 	}
 }
 
-func (authToken *AuthToken) createAccessToken(userId int, userName string) error {
-	claims := &AccessTokenClaims{
-		UserID:   userId,
-		UserName: userName,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "mr-naveenseven/user-authentication",
-			Subject:   "AccessToken",
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(2 * time.Hour)),
-		},
+func (authToken *AuthToken) createAccessToken(subject string, claims *AccessTokenClaims) error {
+	// the token ID is used to revoke a single token before it expires
+	tokenID, err := securetoken.Generate(16)
+	if err != nil {
+		log.Println("Failed to generate access token ID:", err)
+
+		return err
+	}
+
+	now := time.Now()
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        tokenID,
+		Issuer:    authToken.issuer,
+		Subject:   subject,
+		Audience:  authToken.audience,
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now.Add(-authToken.notBeforeSkew)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(authToken.accessTokenExpiry)),
 	}
 
-	authToken.accessToken = jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	signedToken, err := authToken.accessToken.SignedString(authToken.secretKey)
+	token, signedToken, err := authToken.sign(claims, TOKEN_TYPE_ACCESS)
 	if err != nil {
 		log.Println("Failed to sign access token:", err)
 
 		return err
 	}
+	authToken.accessToken = token
 	authToken.EncodedAccessToken = signedToken
 
 	return nil
 }
 
-func (authToken *AuthToken) Create(userId int, userName string) error {
+// sign signs the claims with the newest key of the keyring, typed with the typ header
+func (authToken *AuthToken) sign(claims jwt.Claims, tokenType string) (*jwt.Token, string, error) {
+	signingKey, err := authToken.keyRing.SigningKey()
+	if err != nil {
 
-	if len(authToken.secretKey) == 0 {
+		return nil, "", err
+	}
+
+	signingMethod, err := signingKey.signingMethod()
+	if err != nil {
+
+		return nil, "", err
+	}
+
+	token := jwt.NewWithClaims(signingMethod, claims)
+	token.Header["typ"] = tokenType
+	if signingKey.KeyID != "" {
+		token.Header["kid"] = signingKey.KeyID
+	}
+	signedToken, err := token.SignedString(signingKey.signKey)
+	if err != nil {
+
+		return nil, "", err
+	}
+
+	return token, signedToken, nil
+}
+
+// Create signs a new access token for the user embedding the roles of the user
+func (authToken *AuthToken) Create(userId int, userName string, roles []string) error {
+
+	return authToken.CreateForClient(userId, userName, roles, "", "")
+}
+
+// CreateForClient signs a new access token issued to an OAuth client, limited to the granted scope
+func (authToken *AuthToken) CreateForClient(userId int, userName string, roles []string, clientID string, scope string) error {
+
+	return authToken.CreateForSession(userId, userName, roles, clientID, scope, "")
+}
+
+// CreateForSession signs a new access token like CreateForClient, bound to the session it was issued to
+func (authToken *AuthToken) CreateForSession(userId int, userName string, roles []string, clientID string, scope string, sessionID string) error {
+
+	if authToken.keyRing == nil {
 
 		return jwt.ErrHashUnavailable
 	}
@@ -82,7 +248,40 @@ func (authToken *AuthToken) Create(userId int, userName string) error {
 		return ErrEmptyUserName
 	}
 
-	err := authToken.createAccessToken(userId, userName)
+	err := authToken.createAccessToken(strconv.Itoa(userId), &AccessTokenClaims{
+		UserID:    userId,
+		UserName:  userName,
+		Roles:     roles,
+		ClientID:  clientID,
+		Scope:     scope,
+		SessionID: sessionID,
+	})
+	if err != nil {
+
+		return err
+	}
+
+	return nil
+}
+
+// CreateClientToken signs a new access token for an OAuth client acting on its own behalf,
+// the client is the subject and the token carries no user
+func (authToken *AuthToken) CreateClientToken(clientID string, scope string) error {
+
+	if authToken.keyRing == nil {
+
+		return jwt.ErrHashUnavailable
+	}
+
+	if clientID == "" {
+
+		return ErrEmptyClientID
+	}
+
+	err := authToken.createAccessToken(clientID, &AccessTokenClaims{
+		ClientID: clientID,
+		Scope:    scope,
+	})
 	if err != nil {
 
 		return err
@@ -91,24 +290,57 @@ func (authToken *AuthToken) Create(userId int, userName string) error {
 	return nil
 }
 
-func (authToken *AuthToken) Validate(tokenString string) (bool, error) {
+// Validate verifies the token and returns its claims
+func (authToken *AuthToken) Validate(tokenString string) (*AccessTokenClaims, error) {
+	if authToken.keyRing == nil {
+
+		return nil, jwt.ErrHashUnavailable
+	}
+
+	// tokens minted by another deployment are rejected through iss and aud
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{ALG_HS256, ALG_RS256, ALG_ES256, ALG_EDDSA}),
+		jwt.WithIssuer(authToken.issuer),
+		jwt.WithLeeway(authToken.clockLeeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if len(authToken.audience) > 0 {
+		parserOptions = append(parserOptions, jwt.WithAudience(authToken.audience...))
+	}
+
 	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(authToken.secretKey), nil
-	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+		// ID tokens are signed with the same key and issuer, only the typ header tells them apart
+		typ, _ := t.Header["typ"].(string)
+		if !isAccessTokenType(typ) {
+			return nil, ErrInvalidTokenType
+		}
+
+		// the key is selected by the kid header and must match the token algorithm
+		kid, _ := t.Header["kid"].(string)
+		key, err := authToken.keyRing.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.Algorithm {
+			return nil, ErrUnknownKeyID
+		}
+		return key.verifyKey, nil
+	}, parserOptions...)
 	if err != nil {
 		log.Println("Failed to parse/validate access token:", err)
 
-		return false, err
+		return nil, err
 	}
 
 	if claims, ok := token.Claims.(*AccessTokenClaims); ok && token.Valid {
 		log.Printf("Access token valid. UserID: %d, UserName: %s, ExpiresAt: %v\n",
 			claims.UserID, claims.UserName, claims.ExpiresAt)
 
-		return true, nil
+		return claims, nil
 	} else {
 		log.Println("Invalid access token claims")
 
-		return false, ErrInvalidAccessToken
+		return nil, ErrInvalidAccessToken
 	}
 }
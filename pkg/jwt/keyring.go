@@ -0,0 +1,147 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"sync"
+)
+
+// rsa modulus size of the generated RS256 keys
+const rsaKeyBits = 3072
+
+var (
+	ErrNoSigningKey = errors.New("keyring: no signing key available")
+)
+
+// KeyRing holds the key used to sign new tokens along with every key still accepted
+// for verification, keys are selected by their kid header
+type KeyRing struct {
+	mu         sync.RWMutex
+	signingKey *SigningKey
+	keys       map[string]*SigningKey
+}
+
+// NewKeyRing creates a keyring signing with signingKey and verifying with it and verifyKeys
+func NewKeyRing(signingKey *SigningKey, verifyKeys ...*SigningKey) *KeyRing {
+	keyRing := &KeyRing{}
+	keyRing.Replace(signingKey, verifyKeys...)
+
+	return keyRing
+}
+
+// Replace swaps the keyring content, used when the persisted keyring changes
+func (keyRing *KeyRing) Replace(signingKey *SigningKey, verifyKeys ...*SigningKey) {
+	keys := make(map[string]*SigningKey, len(verifyKeys)+1)
+	for _, key := range verifyKeys {
+		if key != nil {
+			keys[key.KeyID] = key
+		}
+	}
+	if signingKey != nil {
+		keys[signingKey.KeyID] = signingKey
+	}
+
+	keyRing.mu.Lock()
+	keyRing.signingKey = signingKey
+	keyRing.keys = keys
+	keyRing.mu.Unlock()
+}
+
+// SigningKey returns the key new tokens are signed with
+func (keyRing *KeyRing) SigningKey() (*SigningKey, error) {
+	keyRing.mu.RLock()
+	defer keyRing.mu.RUnlock()
+
+	if keyRing.signingKey == nil {
+
+		return nil, ErrNoSigningKey
+	}
+
+	return keyRing.signingKey, nil
+}
+
+// VerificationKey returns the key with the given ID, tokens without a kid header
+// only match a key configured without an ID
+func (keyRing *KeyRing) VerificationKey(keyID string) (*SigningKey, error) {
+	keyRing.mu.RLock()
+	defer keyRing.mu.RUnlock()
+
+	key, ok := keyRing.keys[keyID]
+	if !ok {
+
+		return nil, ErrUnknownKeyID
+	}
+
+	return key, nil
+}
+
+// Keys returns every key of the keyring
+func (keyRing *KeyRing) Keys() []*SigningKey {
+	keyRing.mu.RLock()
+	defer keyRing.mu.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(keyRing.keys))
+	for _, key := range keyRing.keys {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// String hides the key material when the configuration is logged
+func (keyRing *KeyRing) String() string {
+	signingKey, err := keyRing.SigningKey()
+	if err != nil {
+
+		return "KeyRing{}"
+	}
+
+	return "KeyRing{signing: " + signingKey.String() + "}"
+}
+
+// GenerateSigningKey creates a new asymmetric signing key for the algorithm
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	var privateKey crypto.Signer
+	var err error
+
+	switch algorithm {
+	case ALG_RS256:
+		privateKey, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	case ALG_ES256:
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ALG_EDDSA:
+		_, privateKey, err = ed25519.GenerateKey(rand.Reader)
+	default:
+
+		return nil, ErrUnsupportedAlgorithm
+	}
+	if err != nil {
+
+		return nil, err
+	}
+
+	return NewAsymmetricSigningKey(algorithm, privateKey, "")
+}
+
+// PrivateKeyPEM encodes the private key of an asymmetric signing key as PKCS#8 PEM
+func (key *SigningKey) PrivateKeyPEM() ([]byte, error) {
+	if !key.IsAsymmetric() {
+
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key.signKey)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), nil
+}
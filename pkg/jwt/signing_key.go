@@ -0,0 +1,193 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// supported signing algorithms
+const (
+	ALG_HS256 = "HS256"
+	ALG_RS256 = "RS256"
+	ALG_ES256 = "ES256"
+	ALG_EDDSA = "EdDSA"
+)
+
+var (
+	ErrUnsupportedAlgorithm = errors.New("signing key: unsupported signing algorithm")
+	ErrInvalidKeyFile       = errors.New("signing key: no PEM encoded private key found")
+	ErrKeyAlgorithmMismatch = errors.New("signing key: private key type does not match the signing algorithm")
+	ErrEmptySecretKey       = errors.New("signing key: secret key cannot be empty")
+)
+
+// SigningKey represents a key used to sign and verify access tokens
+type SigningKey struct {
+	KeyID     string
+	Algorithm string
+	// signKey is the HMAC secret or the asymmetric private key
+	signKey any
+	// verifyKey is the HMAC secret or the asymmetric public key
+	verifyKey any
+}
+
+// NewHMACSigningKey creates an HS256 signing key from a shared secret
+func NewHMACSigningKey(secret []byte, keyID string) (*SigningKey, error) {
+	if len(secret) == 0 {
+
+		return nil, ErrEmptySecretKey
+	}
+
+	return &SigningKey{
+		KeyID:     keyID,
+		Algorithm: ALG_HS256,
+		signKey:   secret,
+		verifyKey: secret,
+	}, nil
+}
+
+// NewAsymmetricSigningKey creates a signing key from an RSA, ECDSA P-256 or Ed25519 private key.
+// The key ID defaults to a thumbprint of the public key when empty.
+func NewAsymmetricSigningKey(algorithm string, privateKey crypto.Signer, keyID string) (*SigningKey, error) {
+	switch key := privateKey.(type) {
+	case *rsa.PrivateKey:
+		if algorithm != ALG_RS256 {
+
+			return nil, ErrKeyAlgorithmMismatch
+		}
+	case *ecdsa.PrivateKey:
+		if algorithm != ALG_ES256 || key.Curve != elliptic.P256() {
+
+			return nil, ErrKeyAlgorithmMismatch
+		}
+	case ed25519.PrivateKey:
+		if algorithm != ALG_EDDSA {
+
+			return nil, ErrKeyAlgorithmMismatch
+		}
+	default:
+
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if keyID == "" {
+		thumbprint, err := publicKeyThumbprint(privateKey.Public())
+		if err != nil {
+
+			return nil, err
+		}
+		keyID = thumbprint
+	}
+
+	return &SigningKey{
+		KeyID:     keyID,
+		Algorithm: algorithm,
+		signKey:   privateKey,
+		verifyKey: privateKey.Public(),
+	}, nil
+}
+
+// LoadSigningKeyFile loads a PEM encoded PKCS#8, PKCS#1 (RSA) or SEC 1 (EC) private key
+func LoadSigningKeyFile(algorithm string, path string, keyID string) (*SigningKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+
+		return nil, fmt.Errorf("signing key: reading key file failed: %w", err)
+	}
+
+	privateKey, err := ParsePrivateKeyPEM(data)
+	if err != nil {
+
+		return nil, err
+	}
+
+	return NewAsymmetricSigningKey(algorithm, privateKey, keyID)
+}
+
+// ParsePrivateKeyPEM parses the first private key block of the PEM data
+func ParsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+
+		return nil, ErrInvalidKeyFile
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+
+		return x509.ParseECPrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+
+			return nil, err
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+
+			return nil, ErrUnsupportedAlgorithm
+		}
+
+		return signer, nil
+	default:
+
+		return nil, ErrInvalidKeyFile
+	}
+}
+
+// signingMethod returns the jwt signing method of the key algorithm
+func (key *SigningKey) signingMethod() (jwt.SigningMethod, error) {
+	switch key.Algorithm {
+	case ALG_HS256:
+
+		return jwt.SigningMethodHS256, nil
+	case ALG_RS256:
+
+		return jwt.SigningMethodRS256, nil
+	case ALG_ES256:
+
+		return jwt.SigningMethodES256, nil
+	case ALG_EDDSA:
+
+		return jwt.SigningMethodEdDSA, nil
+	default:
+
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// IsAsymmetric reports whether the key can be published as a JWK
+func (key *SigningKey) IsAsymmetric() bool {
+	return key.Algorithm != ALG_HS256
+}
+
+// String hides the key material when the configuration is logged
+func (key *SigningKey) String() string {
+	return fmt.Sprintf("SigningKey{kid: %s, alg: %s}", key.KeyID, key.Algorithm)
+}
+
+// publicKeyThumbprint returns a short, stable identifier of the public key
+func publicKeyThumbprint(publicKey crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+
+	return base64.RawURLEncoding.EncodeToString(sum[:12]), nil
+}
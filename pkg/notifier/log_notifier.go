@@ -0,0 +1,19 @@
+package notifier
+
+import (
+	"context"
+	"user-authentication/pkg/logger"
+)
+
+// LogNotifier writes the messages to the application log, meant for local development only
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	logger.Info("Notification", "to", msg.To, "subject", msg.Subject, "body", msg.Body)
+
+	return nil
+}
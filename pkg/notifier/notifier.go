@@ -0,0 +1,38 @@
+package notifier
+
+import (
+	"context"
+	"errors"
+)
+
+// notifier sinks
+const (
+	NOTIFIER_LOG  = "log"
+	NOTIFIER_FILE = "file"
+)
+
+var ErrUnknownNotifier = errors.New("notifier: unknown notifier sink")
+
+// Message represents a notification delivered to a user
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Notifier delivers messages to users, e.g. by email
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the notifier of the sink, the file sink appends to path
+func New(sink string, path string) (Notifier, error) {
+	switch sink {
+	case "", NOTIFIER_LOG:
+		return NewLogNotifier(), nil
+	case NOTIFIER_FILE:
+		return NewFileNotifier(path), nil
+	default:
+		return nil, ErrUnknownNotifier
+	}
+}
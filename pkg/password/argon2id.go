@@ -0,0 +1,144 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// default argon2id parameters, RFC 9106 recommends 64 MiB and 3 passes when memory is constrained
+	defaultArgon2Memory      = 64 * 1024
+	defaultArgon2Iterations  = 3
+	defaultArgon2Parallelism = 2
+
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+var (
+	ErrInvalidArgon2Params = errors.New("password hashing: invalid argon2id parameters")
+	errInvalidArgon2Hash   = errors.New("password hashing: invalid argon2id hash")
+)
+
+// argon2Params represents the parameters of an argon2id hash
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+}
+
+// Argon2idHasher hashes passwords with argon2id, encoded in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+type Argon2idHasher struct {
+	params argon2Params
+}
+
+// NewArgon2idHasher creates an argon2id hasher, zero parameters use the defaults
+func NewArgon2idHasher(memory int, iterations int, parallelism int) (*Argon2idHasher, error) {
+	if memory == 0 {
+		memory = defaultArgon2Memory
+	}
+	if iterations == 0 {
+		iterations = defaultArgon2Iterations
+	}
+	if parallelism == 0 {
+		parallelism = defaultArgon2Parallelism
+	}
+
+	// argon2 needs at least 8 KiB of memory per thread
+	if parallelism < 1 || parallelism > 255 || iterations < 1 || memory < 8*parallelism || int64(memory) > math.MaxUint32 {
+		return nil, ErrInvalidArgon2Params
+	}
+
+	return &Argon2idHasher{
+		params: argon2Params{
+			memory:      uint32(memory),
+			iterations:  uint32(iterations),
+			parallelism: uint8(parallelism),
+		},
+	}, nil
+}
+
+// Hash hashes the password with argon2id and a random salt
+func (hasher *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	params := hasher.params
+	key := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf(
+		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		ALGORITHM_ARGON2ID,
+		argon2.Version,
+		params.memory,
+		params.iterations,
+		params.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+// Verify compares a plaintext password with an argon2id hash, using the parameters of the hash
+func (hasher *Argon2idHasher) Verify(password string, encodedHash string) bool {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return false
+	}
+
+	derived := argon2.IDKey([]byte(password), salt, params.iterations, params.memory, params.parallelism, uint32(len(key)))
+
+	return subtle.ConstantTimeCompare(derived, key) == 1
+}
+
+// NeedsRehash reports whether the hash is not an argon2id hash of the configured parameters
+func (hasher *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+
+	return params != hasher.params || len(salt) != argon2SaltLength || len(key) != argon2KeyLength
+}
+
+// decodeArgon2id parses an argon2id hash in the PHC string format
+func decodeArgon2id(encodedHash string) (argon2Params, []byte, []byte, error) {
+	// the leading $ yields an empty first part
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != ALGORITHM_ARGON2ID {
+		return argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	var params argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.iterations, &params.parallelism); err != nil {
+		return argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+	if params.iterations < 1 || params.parallelism < 1 {
+		return argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return argon2Params{}, nil, nil, errInvalidArgon2Hash
+	}
+
+	return params, salt, key, nil
+}
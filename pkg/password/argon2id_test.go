@@ -0,0 +1,169 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// argon2id hash of testPassword made with m=64,t=1,p=1
+const (
+	testPassword     = "correct horse battery staple"
+	testArgon2idHash = "$argon2id$v=19$m=64,t=1,p=1$Q3Df4oAtpYanwfR0z5JOoA$lxXkalrnhC8q3U/W/C0l5qSj9p2MvyRCvhwQuVivXh4"
+)
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	hasher, err := NewArgon2idHasher(64, 2, 1)
+	if err != nil {
+		t.Fatalf("NewArgon2idHasher() failed: %v", err)
+	}
+
+	encodedHash, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash() failed: %v", err)
+	}
+	if !strings.HasPrefix(encodedHash, "$argon2id$v=19$m=64,t=2,p=1$") || Algorithm(encodedHash) != ALGORITHM_ARGON2ID {
+		t.Errorf("Hash() = %s, want the PHC string of m=64,t=2,p=1", encodedHash)
+	}
+
+	params, salt, key, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		t.Fatalf("decodeArgon2id() failed: %v", err)
+	}
+	if params != hasher.params || len(salt) != argon2SaltLength || len(key) != argon2KeyLength {
+		t.Errorf("decodeArgon2id() = %+v, %d byte salt, %d byte key", params, len(salt), len(key))
+	}
+
+	if !hasher.Verify(testPassword, encodedHash) {
+		t.Error("Verify() of the hashed password = false, want true")
+	}
+	if hasher.Verify("Correct horse battery staple", encodedHash) {
+		t.Error("Verify() of another password = true, want false")
+	}
+	if hasher.NeedsRehash(encodedHash) {
+		t.Error("NeedsRehash() of a hash of the configured parameters = true, want false")
+	}
+
+	// the salt is random, the same password never hashes the same
+	if other, _ := hasher.Hash(testPassword); other == encodedHash {
+		t.Error("Hash() returned the same hash twice")
+	}
+}
+
+func TestArgon2idVerifyOwnParameters(t *testing.T) {
+	// the hash is verified with its own parameters, not the configured ones
+	hasher, err := NewArgon2idHasher(0, 0, 0)
+	if err != nil {
+		t.Fatalf("NewArgon2idHasher() failed: %v", err)
+	}
+
+	if !hasher.Verify(testPassword, testArgon2idHash) {
+		t.Error("Verify() of a hash of other parameters = false, want true")
+	}
+	if hasher.Verify("wrong password", testArgon2idHash) {
+		t.Error("Verify() of another password = true, want false")
+	}
+}
+
+func TestDecodeArgon2idMalformed(t *testing.T) {
+	tests := []struct {
+		name        string
+		encodedHash string
+	}{
+		{"empty", ""},
+		{"bcrypt hash", testBcryptHash},
+		{"other algorithm", strings.Replace(testArgon2idHash, "argon2id", "argon2i", 1)},
+		{"missing hash", testArgon2idHash[:strings.LastIndex(testArgon2idHash, "$")]},
+		{"truncated after the parameters", "$argon2id$v=19$m=64,t=1,p=1"},
+		{"extra part", testArgon2idHash + "$AAAA"},
+		{"unknown version", strings.Replace(testArgon2idHash, "v=19", "v=16", 1)},
+		{"malformed version", strings.Replace(testArgon2idHash, "v=19", "version=19", 1)},
+		{"missing parallelism", strings.Replace(testArgon2idHash, ",p=1", "", 1)},
+		{"non numeric memory", strings.Replace(testArgon2idHash, "m=64", "m=x", 1)},
+		{"negative memory", strings.Replace(testArgon2idHash, "m=64", "m=-64", 1)},
+		{"zero iterations", strings.Replace(testArgon2idHash, "t=1", "t=0", 1)},
+		{"zero parallelism", strings.Replace(testArgon2idHash, "p=1", "p=0", 1)},
+		{"parallelism overflow", strings.Replace(testArgon2idHash, "p=1", "p=256", 1)},
+		{"salt not base64", strings.Replace(testArgon2idHash, "Q3Df", "Q3D!", 1)},
+		{"padded hash", testArgon2idHash + "="},
+		{"empty hash", testArgon2idHash[:strings.LastIndex(testArgon2idHash, "$")+1]},
+	}
+
+	hasher, err := NewArgon2idHasher(64, 1, 1)
+	if err != nil {
+		t.Fatalf("NewArgon2idHasher() failed: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, _, err := decodeArgon2id(tt.encodedHash); !errors.Is(err, errInvalidArgon2Hash) {
+				t.Errorf("decodeArgon2id(%q) error = %v, want %v", tt.encodedHash, err, errInvalidArgon2Hash)
+			}
+			if hasher.Verify(testPassword, tt.encodedHash) {
+				t.Errorf("Verify(%q) = true, want false", tt.encodedHash)
+			}
+			if !hasher.NeedsRehash(tt.encodedHash) {
+				t.Errorf("NeedsRehash(%q) = false, want true", tt.encodedHash)
+			}
+		})
+	}
+}
+
+func TestArgon2idNeedsRehash(t *testing.T) {
+	tests := []struct {
+		name        string
+		memory      int
+		iterations  int
+		parallelism int
+		rehash      bool
+	}{
+		{"same parameters", 64, 1, 1, false},
+		{"more memory", 128, 1, 1, true},
+		{"more iterations", 64, 3, 1, true},
+		{"more threads", 64, 1, 2, true},
+		{"less memory", 32, 1, 1, true},
+		{"defaults", 0, 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewArgon2idHasher(tt.memory, tt.iterations, tt.parallelism)
+			if err != nil {
+				t.Fatalf("NewArgon2idHasher() failed: %v", err)
+			}
+			if rehash := hasher.NeedsRehash(testArgon2idHash); rehash != tt.rehash {
+				t.Errorf("NeedsRehash() = %v, want %v", rehash, tt.rehash)
+			}
+		})
+	}
+
+	// a hash of the right parameters but another salt or key length is upgraded too
+	hasher, _ := NewArgon2idHasher(64, 1, 1)
+	shortSalt := strings.Replace(testArgon2idHash, "Q3Df4oAtpYanwfR0z5JOoA", "Q3Df4oAtpYanwfR0", 1)
+	if !hasher.NeedsRehash(shortSalt) {
+		t.Error("NeedsRehash() of a short salt = false, want true")
+	}
+}
+
+func TestNewArgon2idHasherInvalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		memory      int
+		iterations  int
+		parallelism int
+	}{
+		{"memory below 8 KiB per thread", 15, 1, 2},
+		{"negative iterations", 64, -1, 1},
+		{"too many threads", 64 * 1024, 1, 256},
+		{"negative threads", 64, 1, -1},
+		{"memory overflow", 1 << 33, 1, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewArgon2idHasher(tt.memory, tt.iterations, tt.parallelism); !errors.Is(err, ErrInvalidArgon2Params) {
+				t.Errorf("NewArgon2idHasher() error = %v, want %v", err, ErrInvalidArgon2Params)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package password
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// default bcrypt cost, the one every hash was made with before the cost was configurable
+const defaultBcryptCost = 10
+
+var ErrInvalidBcryptCost = errors.New("password hashing: invalid bcrypt cost")
+
+// BcryptHasher hashes passwords with bcrypt
+type BcryptHasher struct {
+	cost int
+}
+
+// NewBcryptHasher creates a bcrypt hasher, a zero cost uses the default
+func NewBcryptHasher(cost int) (*BcryptHasher, error) {
+	if cost == 0 {
+		cost = defaultBcryptCost
+	}
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return nil, ErrInvalidBcryptCost
+	}
+
+	return &BcryptHasher{cost: cost}, nil
+}
+
+// Hash hashes the password with bcrypt
+func (hasher *BcryptHasher) Hash(password string) (string, error) {
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), hasher.cost)
+
+	return string(bytes), err
+}
+
+// Verify compares a plaintext password with a bcrypt hash of any cost
+func (hasher *BcryptHasher) Verify(password string, encodedHash string) bool {
+	err := bcrypt.CompareHashAndPassword([]byte(encodedHash), []byte(password))
+
+	return err == nil
+}
+
+// NeedsRehash reports whether the hash is not a bcrypt hash of the configured cost
+func (hasher *BcryptHasher) NeedsRehash(encodedHash string) bool {
+	if Algorithm(encodedHash) != ALGORITHM_BCRYPT {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(encodedHash))
+
+	return err != nil || cost != hasher.cost
+}
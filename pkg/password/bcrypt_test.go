@@ -0,0 +1,101 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// bcrypt hash of testPassword made with the cost 10 used before the cost was configurable
+const testBcryptHash = "$2a$10$drvwJ/KRdN6maTh5Zv6g..lAi7UXfRTUVSFMU0uqZMbYKnarbibJS"
+
+func TestBcryptVerifyLegacyHash(t *testing.T) {
+	tests := []struct {
+		name        string
+		encodedHash string
+		password    string
+		ok          bool
+	}{
+		{"legacy hash", testBcryptHash, testPassword, true},
+		{"legacy hash with the 2b prefix", strings.Replace(testBcryptHash, "$2a$", "$2b$", 1), testPassword, true},
+		{"legacy hash with the 2y prefix", strings.Replace(testBcryptHash, "$2a$", "$2y$", 1), testPassword, true},
+		{"other password", testBcryptHash, "wrong password", false},
+		{"empty password", testBcryptHash, "", false},
+		{"truncated hash", testBcryptHash[:len(testBcryptHash)-1], testPassword, false},
+		{"truncated salt", testBcryptHash[:20], testPassword, false},
+		{"empty hash", "", testPassword, false},
+		{"argon2id hash", testArgon2idHash, testPassword, false},
+	}
+
+	// a higher configured cost still verifies the hashes of the legacy cost
+	hasher, err := NewBcryptHasher(12)
+	if err != nil {
+		t.Fatalf("NewBcryptHasher() failed: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok := hasher.Verify(tt.password, tt.encodedHash); ok != tt.ok {
+				t.Errorf("Verify() = %v, want %v", ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBcryptRoundTrip(t *testing.T) {
+	hasher, err := NewBcryptHasher(4)
+	if err != nil {
+		t.Fatalf("NewBcryptHasher() failed: %v", err)
+	}
+
+	encodedHash, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash() failed: %v", err)
+	}
+	if !strings.HasPrefix(encodedHash, "$2a$04$") || Algorithm(encodedHash) != ALGORITHM_BCRYPT {
+		t.Errorf("Hash() = %s, want a bcrypt hash of cost 4", encodedHash)
+	}
+	if !hasher.Verify(testPassword, encodedHash) {
+		t.Error("Verify() of the hashed password = false, want true")
+	}
+	if hasher.NeedsRehash(encodedHash) {
+		t.Error("NeedsRehash() of a hash of the configured cost = true, want false")
+	}
+}
+
+func TestBcryptNeedsRehash(t *testing.T) {
+	tests := []struct {
+		name        string
+		cost        int
+		encodedHash string
+		rehash      bool
+	}{
+		{"same cost", 10, testBcryptHash, false},
+		{"default cost", 0, testBcryptHash, false},
+		{"higher cost", 12, testBcryptHash, true},
+		{"lower cost", 4, testBcryptHash, true},
+		{"argon2id hash", 10, testArgon2idHash, true},
+		{"truncated cost", 10, "$2a$1", true},
+		{"empty hash", 10, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewBcryptHasher(tt.cost)
+			if err != nil {
+				t.Fatalf("NewBcryptHasher() failed: %v", err)
+			}
+			if rehash := hasher.NeedsRehash(tt.encodedHash); rehash != tt.rehash {
+				t.Errorf("NeedsRehash() = %v, want %v", rehash, tt.rehash)
+			}
+		})
+	}
+}
+
+func TestNewBcryptHasherInvalid(t *testing.T) {
+	for _, cost := range []int{-1, 3, 32} {
+		if _, err := NewBcryptHasher(cost); !errors.Is(err, ErrInvalidBcryptCost) {
+			t.Errorf("NewBcryptHasher(%d) error = %v, want %v", cost, err, ErrInvalidBcryptCost)
+		}
+	}
+}
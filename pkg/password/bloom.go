@@ -0,0 +1,184 @@
+package password
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomMagic starts every bloom filter file, followed by the version and the header
+var bloomMagic = [4]byte{'P', 'W', 'B', 'F'}
+
+const (
+	bloomVersion = 1
+	// maximum number of hash functions, more only slow the lookups down
+	maxBloomHashes = 32
+	// header: magic, version, hash type, number of hashes, one reserved byte, number of bits
+	// and number of hashes added
+	bloomHeaderLength = 4 + 4 + 8 + 8
+)
+
+var ErrInvalidBloomFilter = errors.New("breached passwords: invalid bloom filter")
+
+// bloom filter file codes of the hash types
+var bloomHashTypes = map[string]byte{
+	HASH_SHA1: 1,
+	HASH_NTLM: 2,
+}
+
+// BloomFilter is a compact probabilistic set of password hashes. It never misses a hash
+// added to it but reports hashes it does not hold at the false positive rate it was sized for.
+type BloomFilter struct {
+	hashType string
+	hashes   uint8
+	bits     uint64
+	count    uint64
+	data     []byte
+}
+
+// NewBloomFilter creates an empty filter sized for the expected number of hashes and the
+// false positive rate
+func NewBloomFilter(hashType string, expected uint64, falsePositiveRate float64) (*BloomFilter, error) {
+	if _, ok := bloomHashTypes[hashType]; !ok {
+		return nil, ErrUnknownHashType
+	}
+	if expected == 0 || falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	bits := uint64(math.Ceil(-float64(expected) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	hashes := math.Round(float64(bits) / float64(expected) * math.Ln2)
+	hashes = math.Max(1, math.Min(maxBloomHashes, hashes))
+
+	return &BloomFilter{
+		hashType: hashType,
+		hashes:   uint8(hashes),
+		bits:     bits,
+		data:     make([]byte, (bits+7)/8),
+	}, nil
+}
+
+// HashType returns the hash type of the corpus the filter was built from
+func (filter *BloomFilter) HashType() string {
+	return filter.hashType
+}
+
+// positions calls fn with the bit positions of the digest, derived by double hashing from
+// the digest itself since the corpus hashes are already uniformly distributed
+func (filter *BloomFilter) positions(digest []byte, fn func(position uint64) bool) {
+	h1 := binary.BigEndian.Uint64(digest[0:8])
+	h2 := binary.BigEndian.Uint64(digest[8:16]) | 1
+	for i := uint64(0); i < uint64(filter.hashes); i++ {
+		if !fn((h1 + i*h2) % filter.bits) {
+			return
+		}
+	}
+}
+
+// AddDigest adds the hash digest of a breached password
+func (filter *BloomFilter) AddDigest(digest []byte) {
+	filter.positions(digest, func(position uint64) bool {
+		filter.data[position/8] |= 1 << (position % 8)
+		return true
+	})
+	filter.count++
+}
+
+// ContainsDigest reports whether the hash digest was probably added
+func (filter *BloomFilter) ContainsDigest(digest []byte) bool {
+	found := true
+	filter.positions(digest, func(position uint64) bool {
+		found = filter.data[position/8]&(1<<(position%8)) != 0
+		return found
+	})
+
+	return found
+}
+
+// Contains reports whether the password probably appears in the corpus
+func (filter *BloomFilter) Contains(password string) (bool, error) {
+	digest, err := Digest(filter.hashType, password)
+	if err != nil {
+		return false, err
+	}
+
+	return filter.ContainsDigest(digest), nil
+}
+
+// WriteTo writes the filter in the bloom filter file format
+func (filter *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, bloomHeaderLength)
+	copy(header, bloomMagic[:])
+	header[4] = bloomVersion
+	header[5] = bloomHashTypes[filter.hashType]
+	header[6] = filter.hashes
+	binary.BigEndian.PutUint64(header[8:16], filter.bits)
+	binary.BigEndian.PutUint64(header[16:24], filter.count)
+
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	m, err := w.Write(filter.data)
+
+	return int64(n + m), err
+}
+
+// ReadBloomFilter reads a filter in the bloom filter file format
+func ReadBloomFilter(r io.Reader) (*BloomFilter, error) {
+	header := make([]byte, bloomHeaderLength)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBloomFilter, err)
+	}
+	if [4]byte(header[0:4]) != bloomMagic || header[4] != bloomVersion {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	filter := &BloomFilter{
+		hashes: header[6],
+		bits:   binary.BigEndian.Uint64(header[8:16]),
+		count:  binary.BigEndian.Uint64(header[16:24]),
+	}
+	for hashType, code := range bloomHashTypes {
+		if code == header[5] {
+			filter.hashType = hashType
+		}
+	}
+	if filter.hashType == "" || filter.hashes == 0 || filter.hashes > maxBloomHashes || filter.bits == 0 {
+		return nil, ErrInvalidBloomFilter
+	}
+
+	// the data must hold exactly the bits of the header, (bits+7)/8 without overflowing
+	size := filter.bits / 8
+	if filter.bits%8 != 0 {
+		size++
+	}
+
+	// reading one byte past the size catches trailing data without trusting the header
+	// for the allocation
+	data, err := io.ReadAll(io.LimitReader(r, int64(size)+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBloomFilter, err)
+	}
+	if uint64(len(data)) != size {
+		return nil, fmt.Errorf("%w: %d data bytes for %d bits", ErrInvalidBloomFilter, len(data), filter.bits)
+	}
+	filter.data = data
+
+	return filter, nil
+}
+
+// LoadBloomFilter reads a bloom filter file in memory
+func LoadBloomFilter(path string) (*BloomFilter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBreachCorpus, err)
+	}
+	defer file.Close()
+
+	return ReadBloomFilter(bufio.NewReader(file))
+}
@@ -0,0 +1,145 @@
+package password
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+// bloomHeader builds a bloom filter file header
+func bloomHeader(magic [4]byte, version byte, hashType byte, hashes byte, bits uint64) []byte {
+	header := make([]byte, bloomHeaderLength)
+	copy(header, magic[:])
+	header[4] = version
+	header[5] = hashType
+	header[6] = hashes
+	binary.BigEndian.PutUint64(header[8:16], bits)
+
+	return header
+}
+
+func TestBloomFilterRoundTrip(t *testing.T) {
+	for _, hashType := range []string{HASH_SHA1, HASH_NTLM} {
+		t.Run(hashType, func(t *testing.T) {
+			filter, err := NewBloomFilter(hashType, 1000, 0.001)
+			if err != nil {
+				t.Fatalf("NewBloomFilter() failed: %v", err)
+			}
+			for i := 0; i < 1000; i++ {
+				digest, _ := Digest(hashType, fmt.Sprintf("breached-%d", i))
+				filter.AddDigest(digest)
+			}
+
+			var buf bytes.Buffer
+			written, err := filter.WriteTo(&buf)
+			if err != nil {
+				t.Fatalf("WriteTo() failed: %v", err)
+			}
+			if written != int64(buf.Len()) {
+				t.Errorf("WriteTo() = %d, wrote %d bytes", written, buf.Len())
+			}
+
+			read, err := ReadBloomFilter(&buf)
+			if err != nil {
+				t.Fatalf("ReadBloomFilter() failed: %v", err)
+			}
+			if read.HashType() != hashType || read.hashes != filter.hashes || read.bits != filter.bits || read.count != 1000 {
+				t.Errorf("ReadBloomFilter() = %s, %d hashes, %d bits, %d count, want %s, %d, %d, 1000",
+					read.HashType(), read.hashes, read.bits, read.count, hashType, filter.hashes, filter.bits)
+			}
+			if !bytes.Equal(read.data, filter.data) {
+				t.Error("ReadBloomFilter() data differs from the written filter")
+			}
+
+			// a bloom filter never misses an added hash
+			for i := 0; i < 1000; i++ {
+				if found, err := read.Contains(fmt.Sprintf("breached-%d", i)); err != nil || !found {
+					t.Fatalf("Contains(breached-%d) = %v, %v, want true", i, found, err)
+				}
+			}
+
+			// sized for 0.1%, a handful of false positives out of 1000 is expected
+			falsePositives := 0
+			for i := 0; i < 1000; i++ {
+				if found, _ := read.Contains(fmt.Sprintf("safe-%d", i)); found {
+					falsePositives++
+				}
+			}
+			if falsePositives > 10 {
+				t.Errorf("Contains() reported %d false positives out of 1000", falsePositives)
+			}
+		})
+	}
+}
+
+func TestNewBloomFilterInvalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashType string
+		expected uint64
+		rate     float64
+		err      error
+	}{
+		{"unknown hash type", "md5", 1000, 0.001, ErrUnknownHashType},
+		{"no expected hashes", HASH_SHA1, 0, 0.001, ErrInvalidBloomFilter},
+		{"zero rate", HASH_SHA1, 1000, 0, ErrInvalidBloomFilter},
+		{"negative rate", HASH_SHA1, 1000, -0.1, ErrInvalidBloomFilter},
+		{"rate of one", HASH_SHA1, 1000, 1, ErrInvalidBloomFilter},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBloomFilter(tt.hashType, tt.expected, tt.rate); !errors.Is(err, tt.err) {
+				t.Errorf("NewBloomFilter() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReadBloomFilterMalformed(t *testing.T) {
+	sha1Code := bloomHashTypes[HASH_SHA1]
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated header", bloomHeader(bloomMagic, bloomVersion, sha1Code, 7, 64)[:bloomHeaderLength-1]},
+		{"wrong magic", append(bloomHeader([4]byte{'P', 'W', 'B', 'X'}, bloomVersion, sha1Code, 7, 64), make([]byte, 8)...)},
+		{"unknown version", append(bloomHeader(bloomMagic, bloomVersion+1, sha1Code, 7, 64), make([]byte, 8)...)},
+		{"unknown hash type", append(bloomHeader(bloomMagic, bloomVersion, 9, 7, 64), make([]byte, 8)...)},
+		{"no hash type", append(bloomHeader(bloomMagic, bloomVersion, 0, 7, 64), make([]byte, 8)...)},
+		{"zero hashes", append(bloomHeader(bloomMagic, bloomVersion, sha1Code, 0, 64), make([]byte, 8)...)},
+		{"too many hashes", append(bloomHeader(bloomMagic, bloomVersion, sha1Code, maxBloomHashes+1, 64), make([]byte, 8)...)},
+		{"zero bits", bloomHeader(bloomMagic, bloomVersion, sha1Code, 7, 0)},
+		{"missing data", bloomHeader(bloomMagic, bloomVersion, sha1Code, 7, 64)},
+		{"truncated data", append(bloomHeader(bloomMagic, bloomVersion, sha1Code, 7, 64), make([]byte, 7)...)},
+		{"partial byte truncated", append(bloomHeader(bloomMagic, bloomVersion, sha1Code, 7, 65), make([]byte, 8)...)},
+		{"trailing data", append(bloomHeader(bloomMagic, bloomVersion, sha1Code, 7, 64), make([]byte, 9)...)},
+		{"bits overflowing the size", append(bloomHeader(bloomMagic, bloomVersion, sha1Code, 7, math.MaxUint64), make([]byte, 8)...)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadBloomFilter(bytes.NewReader(tt.data)); !errors.Is(err, ErrInvalidBloomFilter) {
+				t.Errorf("ReadBloomFilter() error = %v, want %v", err, ErrInvalidBloomFilter)
+			}
+		})
+	}
+}
+
+func TestReadBloomFilterPartialByte(t *testing.T) {
+	// 65 bits are stored in 9 bytes, the last one partially used
+	data := append(bloomHeader(bloomMagic, bloomVersion, bloomHashTypes[HASH_NTLM], 3, 65), make([]byte, 9)...)
+
+	filter, err := ReadBloomFilter(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ReadBloomFilter() failed: %v", err)
+	}
+	if filter.HashType() != HASH_NTLM || filter.bits != 65 || len(filter.data) != 9 {
+		t.Errorf("ReadBloomFilter() = %s, %d bits, %d bytes, want ntlm, 65 bits, 9 bytes", filter.HashType(), filter.bits, len(filter.data))
+	}
+}
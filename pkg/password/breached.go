@@ -0,0 +1,213 @@
+package password
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+
+	"golang.org/x/crypto/md4"
+)
+
+// hash types of the breach corpora
+const (
+	HASH_SHA1 = "sha1"
+	HASH_NTLM = "ntlm"
+)
+
+// formats of the breach corpus files
+const (
+	// BREACH_FORMAT_HASH_LIST is a list of hex hashes sorted by hash, one HASH:COUNT line per
+	// hash as in the ordered Have I Been Pwned downloads, the count is optional
+	BREACH_FORMAT_HASH_LIST = "hashlist"
+	// BREACH_FORMAT_BLOOM is a bloom filter built from such a list by the breach command
+	BREACH_FORMAT_BLOOM = "bloom"
+)
+
+// longest line read while searching the hash list, a hash and its count
+const maxHashListLine = 256
+
+var (
+	ErrUnknownHashType  = errors.New("breached passwords: unknown hash type")
+	ErrBreachCorpus     = errors.New("breached passwords: invalid corpus file")
+	ErrBreachLookup     = errors.New("breached passwords: lookup failed")
+	errMalformedHashHex = errors.New("breached passwords: malformed hash")
+)
+
+// BreachChecker reports whether a password appears in a breach corpus
+type BreachChecker interface {
+	Contains(password string) (bool, error)
+}
+
+// Digest returns the hash of the password the corpora of the hash type are made of, NTLM
+// being the MD4 of the UTF-16LE encoded password
+func Digest(hashType string, password string) ([]byte, error) {
+	switch hashType {
+	case HASH_SHA1:
+		sum := sha1.Sum([]byte(password))
+
+		return sum[:], nil
+	case HASH_NTLM:
+		encoded := utf16.Encode([]rune(password))
+		buf := make([]byte, 0, 2*len(encoded))
+		for _, unit := range encoded {
+			buf = append(buf, byte(unit), byte(unit>>8))
+		}
+		hash := md4.New()
+		hash.Write(buf)
+
+		return hash.Sum(nil), nil
+	default:
+		return nil, ErrUnknownHashType
+	}
+}
+
+// digestLength returns the length in bytes of the hashes of the hash type
+func digestLength(hashType string) int {
+	switch hashType {
+	case HASH_SHA1:
+		return sha1.Size
+	case HASH_NTLM:
+		return md4.Size
+	default:
+		return 0
+	}
+}
+
+// ParseHashLine parses a HASH or HASH:COUNT line of a corpus and returns the hash digest
+func ParseHashLine(line string, hashType string) ([]byte, error) {
+	hexHash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+	digest, err := hex.DecodeString(hexHash)
+	if err != nil || len(digest) != digestLength(hashType) {
+		return nil, errMalformedHashHex
+	}
+
+	return digest, nil
+}
+
+// NewBreachChecker opens the breach corpus file of the format
+func NewBreachChecker(format string, path string, hashType string) (BreachChecker, error) {
+	switch format {
+	case BREACH_FORMAT_HASH_LIST:
+		return OpenHashList(path, hashType)
+	case BREACH_FORMAT_BLOOM:
+		return LoadBloomFilter(path)
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrBreachCorpus, format)
+	}
+}
+
+// HashList searches a sorted hash list file in place, the file is never loaded in memory so
+// the full corpora of several gigabytes can be used
+type HashList struct {
+	file     *os.File
+	size     int64
+	hashType string
+}
+
+// OpenHashList opens a hash list sorted by hash, the file stays open for the lookups
+func OpenHashList(path string, hashType string) (*HashList, error) {
+	if digestLength(hashType) == 0 {
+		return nil, ErrUnknownHashType
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBreachCorpus, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%w: %v", ErrBreachCorpus, err)
+	}
+
+	return &HashList{
+		file:     file,
+		size:     info.Size(),
+		hashType: hashType,
+	}, nil
+}
+
+// Contains binary searches the list for the hash of the password
+func (list *HashList) Contains(password string) (bool, error) {
+	digest, err := Digest(list.hashType, password)
+	if err != nil {
+		return false, err
+	}
+	target := []byte(strings.ToUpper(hex.EncodeToString(digest)))
+
+	// every line starting in [lo, hi) is a candidate, lo is always the start of a line
+	lo, hi := int64(0), list.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, line, err := list.lineFrom(mid)
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrBreachLookup, err)
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		hexHash, _, _ := bytes.Cut(bytes.TrimSpace(line), []byte(":"))
+		switch bytes.Compare(bytes.ToUpper(hexHash), target) {
+		case 0:
+			return true, nil
+		case -1:
+			lo = start + int64(len(line)) + 1
+		default:
+			hi = mid
+		}
+	}
+
+	return false, nil
+}
+
+// lineFrom returns the first line starting at or after the offset along with its start,
+// the start is the file size when no line starts there
+func (list *HashList) lineFrom(offset int64) (int64, []byte, error) {
+	buf := make([]byte, 2*maxHashListLine)
+
+	// reading from the byte before the offset tells whether a line starts right at the offset
+	readAt := offset
+	if offset > 0 {
+		readAt = offset - 1
+	}
+	n, err := list.file.ReadAt(buf, readAt)
+	if err != nil && err != io.EOF {
+		return 0, nil, err
+	}
+	buf = buf[:n]
+
+	start := 0
+	if offset > 0 {
+		newline := bytes.IndexByte(buf, '\n')
+		if newline < 0 {
+			if err == io.EOF {
+				return list.size, nil, nil
+			}
+			return 0, nil, ErrBreachCorpus
+		}
+		start = newline + 1
+	}
+
+	line := buf[start:]
+	if end := bytes.IndexByte(line, '\n'); end >= 0 {
+		line = line[:end]
+	} else if err != io.EOF {
+		return 0, nil, ErrBreachCorpus
+	}
+
+	return readAt + int64(start), line, nil
+}
+
+// Close closes the hash list file
+func (list *HashList) Close() error {
+	return list.file.Close()
+}
@@ -0,0 +1,207 @@
+package password
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeHashList writes the SHA1 hashes of the passwords as a sorted hash list file, with the
+// lines built by the line function
+func writeHashList(t *testing.T, passwords []string, line func(hash string, count int) string) string {
+	t.Helper()
+
+	lines := make([]string, 0, len(passwords))
+	for i, password := range passwords {
+		digest, _ := Digest(HASH_SHA1, password)
+		lines = append(lines, line(strings.ToUpper(hex.EncodeToString(digest)), i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "")), 0o600); err != nil {
+		t.Fatalf("hash list write failed: %v", err)
+	}
+
+	return path
+}
+
+func TestDigest(t *testing.T) {
+	tests := []struct {
+		hashType string
+		password string
+		digest   string
+	}{
+		{HASH_SHA1, "password", "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8"},
+		{HASH_SHA1, "", "da39a3ee5e6b4b0d3255bfef95601890afd80709"},
+		{HASH_NTLM, "password", "8846f7eaee8fb117ad06bdd830b7586c"},
+		{HASH_NTLM, "", "31d6cfe0d16ae931b73c59d7e0c089c0"},
+	}
+
+	for _, tt := range tests {
+		digest, err := Digest(tt.hashType, tt.password)
+		if err != nil {
+			t.Fatalf("Digest(%s, %q) failed: %v", tt.hashType, tt.password, err)
+		}
+		if hex.EncodeToString(digest) != tt.digest {
+			t.Errorf("Digest(%s, %q) = %x, want %s", tt.hashType, tt.password, digest, tt.digest)
+		}
+	}
+
+	if _, err := Digest("md5", "password"); !errors.Is(err, ErrUnknownHashType) {
+		t.Errorf("Digest(md5) error = %v, want %v", err, ErrUnknownHashType)
+	}
+}
+
+func TestParseHashLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		hashType string
+		digest   string
+		err      error
+	}{
+		{"hash and count", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004", HASH_SHA1, "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", nil},
+		{"hash only", "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", HASH_SHA1, "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", nil},
+		{"carriage return", "8846F7EAEE8FB117AD06BDD830B7586C:1\r\n", HASH_NTLM, "8846f7eaee8fb117ad06bdd830b7586c", nil},
+		{"empty", "", HASH_SHA1, "", errMalformedHashHex},
+		{"not hex", "ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:1", HASH_SHA1, "", errMalformedHashHex},
+		{"odd length", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD:1", HASH_SHA1, "", errMalformedHashHex},
+		{"length of another hash type", "8846F7EAEE8FB117AD06BDD830B7586C:1", HASH_SHA1, "", errMalformedHashHex},
+		{"unknown hash type", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", "md5", "", errMalformedHashHex},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest, err := ParseHashLine(tt.line, tt.hashType)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("ParseHashLine() error = %v, want %v", err, tt.err)
+			}
+			if hex.EncodeToString(digest) != tt.digest {
+				t.Errorf("ParseHashLine() = %x, want %s", digest, tt.digest)
+			}
+		})
+	}
+}
+
+func TestHashListRoundTrip(t *testing.T) {
+	passwords := make([]string, 0, 300)
+	for i := 0; i < 300; i++ {
+		passwords = append(passwords, fmt.Sprintf("breached-%d", i))
+	}
+
+	tests := []struct {
+		name string
+		line func(hash string, count int) string
+	}{
+		{"hash and count", func(hash string, count int) string { return fmt.Sprintf("%s:%d\n", hash, count) }},
+		{"carriage returns", func(hash string, count int) string { return fmt.Sprintf("%s:%d\r\n", hash, count) }},
+		{"hash only", func(hash string, _ int) string { return hash + "\n" }},
+		{"lower case hashes", func(hash string, count int) string { return fmt.Sprintf("%s:%d\n", strings.ToLower(hash), count) }},
+		{"long counts", func(hash string, count int) string { return fmt.Sprintf("%s:%0200d\n", hash, count) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := OpenHashList(writeHashList(t, passwords, tt.line), HASH_SHA1)
+			if err != nil {
+				t.Fatalf("OpenHashList() failed: %v", err)
+			}
+			defer list.Close()
+
+			for _, password := range passwords {
+				if found, err := list.Contains(password); err != nil || !found {
+					t.Fatalf("Contains(%q) = %v, %v, want true", password, found, err)
+				}
+			}
+			for i := 0; i < 300; i++ {
+				password := fmt.Sprintf("safe-%d", i)
+				if found, err := list.Contains(password); err != nil || found {
+					t.Fatalf("Contains(%q) = %v, %v, want false", password, found, err)
+				}
+			}
+		})
+	}
+}
+
+func TestHashListSingleLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents string
+		password string
+		found    bool
+	}{
+		{"empty file", "", "password", false},
+		{"single line", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3\n", "password", true},
+		{"without final newline", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3", "password", true},
+		{"lower case hash", "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8:3\n", "password", true},
+		{"other hash", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:3\n", "letmein", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "hashes.txt")
+			if err := os.WriteFile(path, []byte(tt.contents), 0o600); err != nil {
+				t.Fatalf("hash list write failed: %v", err)
+			}
+
+			list, err := OpenHashList(path, HASH_SHA1)
+			if err != nil {
+				t.Fatalf("OpenHashList() failed: %v", err)
+			}
+			defer list.Close()
+
+			if found, err := list.Contains(tt.password); err != nil || found != tt.found {
+				t.Errorf("Contains(%q) = %v, %v, want %v", tt.password, found, err, tt.found)
+			}
+		})
+	}
+}
+
+func TestHashListLineTooLong(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hashes.txt")
+	contents := "0000000000000000000000000000000000000000:" + strings.Repeat("1", 3*maxHashListLine) + "\n" +
+		"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:1\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("hash list write failed: %v", err)
+	}
+
+	list, err := OpenHashList(path, HASH_SHA1)
+	if err != nil {
+		t.Fatalf("OpenHashList() failed: %v", err)
+	}
+	defer list.Close()
+
+	if _, err := list.Contains("password"); !errors.Is(err, ErrBreachLookup) {
+		t.Errorf("Contains() error = %v, want %v", err, ErrBreachLookup)
+	}
+}
+
+func TestNewBreachChecker(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	tests := []struct {
+		name     string
+		format   string
+		hashType string
+		err      error
+	}{
+		{"unknown format", "csv", HASH_SHA1, ErrBreachCorpus},
+		{"unknown hash type", BREACH_FORMAT_HASH_LIST, "md5", ErrUnknownHashType},
+		{"missing hash list", BREACH_FORMAT_HASH_LIST, HASH_SHA1, ErrBreachCorpus},
+		{"missing bloom filter", BREACH_FORMAT_BLOOM, HASH_SHA1, ErrBreachCorpus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBreachChecker(tt.format, missing, tt.hashType); !errors.Is(err, tt.err) {
+				t.Errorf("NewBreachChecker() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -1,23 +1,119 @@
 package password
 
 import (
-	"golang.org/x/crypto/bcrypt"
+	"errors"
+	"strings"
 )
 
 const (
-	passwordHashCost = 10
+	ALGORITHM_ARGON2ID = "argon2id"
+	ALGORITHM_BCRYPT   = "bcrypt"
 )
 
-// HashPassword hashes the provided password using bcrypt.
-func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+var ErrUnknownAlgorithm = errors.New("password hashing: unknown algorithm")
 
-	return string(bytes), err
+// Hasher hashes passwords into self-describing encoded hashes and verifies passwords against them
+type Hasher interface {
+	// Hash returns the encoded hash of the password, the algorithm and its parameters included
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the encoded hash
+	Verify(password string, encodedHash string) bool
+	// NeedsRehash reports whether the encoded hash was made with another algorithm or other
+	// parameters than the configured ones
+	NeedsRehash(encodedHash string) bool
 }
 
-// VerifyPassword compares a plaintext password with a hashed password and returns true if they match.
-func VerifyPassword(password string, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+// HasherConfig represents the password hashing settings, zero values use the defaults
+type HasherConfig struct {
+	// Algorithm new hashes are made with, argon2id or bcrypt
+	Algorithm string
+	// Argon2Memory is the memory in KiB used by argon2id
+	Argon2Memory int
+	// Argon2Iterations is the number of passes over the memory
+	Argon2Iterations int
+	// Argon2Parallelism is the number of threads
+	Argon2Parallelism int
+	// BcryptCost is the log2 of the bcrypt iterations
+	BcryptCost int
+}
+
+// ConfiguredHasher hashes with the configured algorithm and verifies the hashes of every
+// supported one, so the users keep logging in while their hashes are migrated
+type ConfiguredHasher struct {
+	algorithm string
+	argon2id  *Argon2idHasher
+	bcrypt    *BcryptHasher
+}
+
+// NewHasher creates the hasher of the configured algorithm
+func NewHasher(config HasherConfig) (*ConfiguredHasher, error) {
+	algorithm := config.Algorithm
+	if algorithm == "" {
+		algorithm = ALGORITHM_ARGON2ID
+	}
+	if algorithm != ALGORITHM_ARGON2ID && algorithm != ALGORITHM_BCRYPT {
+		return nil, ErrUnknownAlgorithm
+	}
+
+	argon2idHasher, err := NewArgon2idHasher(config.Argon2Memory, config.Argon2Iterations, config.Argon2Parallelism)
+	if err != nil {
+		return nil, err
+	}
+
+	bcryptHasher, err := NewBcryptHasher(config.BcryptCost)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ConfiguredHasher{
+		algorithm: algorithm,
+		argon2id:  argon2idHasher,
+		bcrypt:    bcryptHasher,
+	}, nil
+}
+
+// hasher returns the hasher of the configured algorithm
+func (hasher *ConfiguredHasher) hasher() Hasher {
+	if hasher.algorithm == ALGORITHM_BCRYPT {
+		return hasher.bcrypt
+	}
+
+	return hasher.argon2id
+}
+
+// Hash hashes the password with the configured algorithm
+func (hasher *ConfiguredHasher) Hash(password string) (string, error) {
+	return hasher.hasher().Hash(password)
+}
+
+// Verify verifies the password with the algorithm the hash was made with
+func (hasher *ConfiguredHasher) Verify(password string, encodedHash string) bool {
+	switch Algorithm(encodedHash) {
+	case ALGORITHM_ARGON2ID:
+		return hasher.argon2id.Verify(password, encodedHash)
+	case ALGORITHM_BCRYPT:
+		return hasher.bcrypt.Verify(password, encodedHash)
+	default:
+		return false
+	}
+}
+
+// NeedsRehash reports whether the hash was made with another algorithm or other parameters
+// than the configured ones
+func (hasher *ConfiguredHasher) NeedsRehash(encodedHash string) bool {
+	return hasher.hasher().NeedsRehash(encodedHash)
+}
 
-	return err == nil
+// Algorithm returns the algorithm of the encoded hash, empty when unknown. The argon2id
+// hashes use the PHC string format and the bcrypt ones its modular crypt format, both start
+// with the identifier of the algorithm.
+func Algorithm(encodedHash string) string {
+	switch {
+	case strings.HasPrefix(encodedHash, "$"+ALGORITHM_ARGON2ID+"$"):
+		return ALGORITHM_ARGON2ID
+	case strings.HasPrefix(encodedHash, "$2a$"), strings.HasPrefix(encodedHash, "$2b$"), strings.HasPrefix(encodedHash, "$2y$"):
+		return ALGORITHM_BCRYPT
+	default:
+		return ""
+	}
 }
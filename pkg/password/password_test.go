@@ -0,0 +1,60 @@
+package password
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfiguredHasherMigration(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      HasherConfig
+		encodedHash string
+		verified    bool
+		rehash      bool
+	}{
+		{"legacy bcrypt hash with argon2id configured", HasherConfig{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1}, testBcryptHash, true, true},
+		{"argon2id hash with argon2id configured", HasherConfig{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1}, testArgon2idHash, true, false},
+		{"argon2id hash with stronger argon2id configured", HasherConfig{Argon2Memory: 128, Argon2Iterations: 1, Argon2Parallelism: 1}, testArgon2idHash, true, true},
+		{"bcrypt hash with bcrypt configured", HasherConfig{Algorithm: ALGORITHM_BCRYPT}, testBcryptHash, true, false},
+		{"argon2id hash with bcrypt configured", HasherConfig{Algorithm: ALGORITHM_BCRYPT}, testArgon2idHash, true, true},
+		{"unknown hash", HasherConfig{}, "$1$plain-md5-crypt", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasher, err := NewHasher(tt.config)
+			if err != nil {
+				t.Fatalf("NewHasher() failed: %v", err)
+			}
+			if verified := hasher.Verify(testPassword, tt.encodedHash); verified != tt.verified {
+				t.Errorf("Verify() = %v, want %v", verified, tt.verified)
+			}
+			if rehash := hasher.NeedsRehash(tt.encodedHash); rehash != tt.rehash {
+				t.Errorf("NeedsRehash() = %v, want %v", rehash, tt.rehash)
+			}
+		})
+	}
+}
+
+func TestConfiguredHasherRehash(t *testing.T) {
+	// the login replaces a legacy hash with one of the configured algorithm
+	hasher, err := NewHasher(HasherConfig{Argon2Memory: 64, Argon2Iterations: 1, Argon2Parallelism: 1})
+	if err != nil {
+		t.Fatalf("NewHasher() failed: %v", err)
+	}
+
+	rehashed, err := hasher.Hash(testPassword)
+	if err != nil {
+		t.Fatalf("Hash() failed: %v", err)
+	}
+	if Algorithm(rehashed) != ALGORITHM_ARGON2ID || hasher.NeedsRehash(rehashed) || !hasher.Verify(testPassword, rehashed) {
+		t.Errorf("Hash() = %s, want an argon2id hash of the configured parameters", rehashed)
+	}
+}
+
+func TestNewHasherUnknownAlgorithm(t *testing.T) {
+	if _, err := NewHasher(HasherConfig{Algorithm: "scrypt"}); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("NewHasher() error = %v, want %v", err, ErrUnknownAlgorithm)
+	}
+}
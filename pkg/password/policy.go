@@ -0,0 +1,242 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// default minimum length in characters of a new password
+	defaultMinPasswordLength = 8
+	// bcrypt ignores every byte past the 72nd, longer passwords are rejected instead of truncated
+	maxPasswordBytes = 72
+	// usernames and email local parts shorter than this are not looked for in the passwords
+	minIdentifierLength = 3
+)
+
+// password policy rules, reported in the violations
+const (
+	RULE_MIN_LENGTH        = "min_length"
+	RULE_MAX_LENGTH        = "max_length"
+	RULE_LOWERCASE         = "lowercase"
+	RULE_UPPERCASE         = "uppercase"
+	RULE_DIGIT             = "digit"
+	RULE_SYMBOL            = "symbol"
+	RULE_CONTAINS_USERNAME = "contains_username"
+	RULE_CONTAINS_EMAIL    = "contains_email"
+	RULE_COMMON_PASSWORD   = "common_password"
+	RULE_BREACHED_PASSWORD = "breached_password"
+)
+
+var (
+	// ErrPolicyViolation is wrapped by every password policy error
+	ErrPolicyViolation   = errors.New("password policy violation")
+	ErrInvalidPolicy     = errors.New("password policy: invalid configuration")
+	ErrDictionaryLoading = errors.New("password policy: loading the dictionary failed")
+)
+
+// PolicyConfig represents the password policy settings, zero values use the defaults
+type PolicyConfig struct {
+	// MinLength is the minimum length in characters
+	MinLength int
+	// MaxLength is the maximum length in bytes, at most 72
+	MaxLength int
+	// character classes every password must contain
+	RequireLowercase bool
+	RequireUppercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// ForbidUserInfo rejects passwords containing the username or the local part of the email
+	ForbidUserInfo bool
+	// DictionaryFile lists the common passwords to reject, one per line, compared case-insensitively
+	DictionaryFile string
+	// BreachFile is the corpus of breached password hashes to reject, a hash list or a bloom filter
+	BreachFile string
+	// BreachFormat is the format of the breach file, hashlist or bloom
+	BreachFormat string
+	// BreachHashType is the hash type of a hash list, sha1 or ntlm, bloom filters record theirs
+	BreachHashType string
+}
+
+// Violation represents a password policy rule the password breaks
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// PolicyError lists every rule a password breaks, it wraps ErrPolicyViolation
+type PolicyError struct {
+	Violations []Violation
+}
+
+func (err *PolicyError) Error() string {
+	messages := make([]string, 0, len(err.Violations))
+	for _, violation := range err.Violations {
+		messages = append(messages, violation.Message)
+	}
+
+	return ErrPolicyViolation.Error() + ": " + strings.Join(messages, "; ")
+}
+
+func (err *PolicyError) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+// Policy validates new passwords against the configured rules
+type Policy struct {
+	config        PolicyConfig
+	dictionary    map[string]struct{}
+	breachChecker BreachChecker
+}
+
+// NewPolicy creates a password policy, loading the dictionary file when one is configured
+func NewPolicy(config PolicyConfig) (*Policy, error) {
+	if config.MinLength == 0 {
+		config.MinLength = defaultMinPasswordLength
+	}
+	if config.MaxLength == 0 {
+		config.MaxLength = maxPasswordBytes
+	}
+	if config.MinLength < 1 || config.MaxLength > maxPasswordBytes || config.MinLength > config.MaxLength {
+		return nil, ErrInvalidPolicy
+	}
+
+	policy := &Policy{
+		config:     config,
+		dictionary: map[string]struct{}{},
+	}
+	if config.DictionaryFile != "" {
+		if err := policy.loadDictionary(config.DictionaryFile); err != nil {
+			return nil, err
+		}
+	}
+	if config.BreachFile != "" {
+		if config.BreachFormat == "" {
+			config.BreachFormat = BREACH_FORMAT_HASH_LIST
+		}
+		if config.BreachHashType == "" {
+			config.BreachHashType = HASH_SHA1
+		}
+
+		breachChecker, err := NewBreachChecker(config.BreachFormat, config.BreachFile, config.BreachHashType)
+		if err != nil {
+			return nil, err
+		}
+		policy.breachChecker = breachChecker
+	}
+
+	return policy, nil
+}
+
+// loadDictionary reads the common passwords, empty lines and lines starting with # are skipped
+func (policy *Policy) loadDictionary(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDictionaryLoading, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.dictionary[strings.ToLower(line)] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%w: %v", ErrDictionaryLoading, err)
+	}
+
+	return nil
+}
+
+// Check validates a new password of the user against every rule, the returned *PolicyError
+// lists all the violated ones. Other errors report a failed breach corpus lookup.
+func (policy *Policy) Check(password string, username string, email string) error {
+	config := policy.config
+	violations := []Violation{}
+	violate := func(rule string, message string) {
+		violations = append(violations, Violation{Rule: rule, Message: message})
+	}
+
+	if utf8.RuneCountInString(password) < config.MinLength {
+		violate(RULE_MIN_LENGTH, fmt.Sprintf("password must be at least %d characters long", config.MinLength))
+	}
+	if len(password) > config.MaxLength {
+		violate(RULE_MAX_LENGTH, fmt.Sprintf("password must be at most %d bytes long", config.MaxLength))
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if config.RequireLowercase && !hasLower {
+		violate(RULE_LOWERCASE, "password must contain a lowercase letter")
+	}
+	if config.RequireUppercase && !hasUpper {
+		violate(RULE_UPPERCASE, "password must contain an uppercase letter")
+	}
+	if config.RequireDigit && !hasDigit {
+		violate(RULE_DIGIT, "password must contain a digit")
+	}
+	if config.RequireSymbol && !hasSymbol {
+		violate(RULE_SYMBOL, "password must contain a symbol")
+	}
+
+	lowerPassword := strings.ToLower(password)
+	if config.ForbidUserInfo {
+		if containsIdentifier(lowerPassword, username) {
+			violate(RULE_CONTAINS_USERNAME, "password must not contain the username")
+		}
+		localPart, _, _ := strings.Cut(email, "@")
+		if containsIdentifier(lowerPassword, localPart) {
+			violate(RULE_CONTAINS_EMAIL, "password must not contain the email address")
+		}
+	}
+
+	if _, ok := policy.dictionary[lowerPassword]; ok {
+		violate(RULE_COMMON_PASSWORD, "password is too common")
+	}
+
+	// the corpus is only searched locally, the password never leaves the service
+	if policy.breachChecker != nil {
+		breached, err := policy.breachChecker.Contains(password)
+		if err != nil {
+			return err
+		}
+		if breached {
+			violate(RULE_BREACHED_PASSWORD, "password appears in a known data breach")
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+// containsIdentifier reports whether the lowercased password contains the identifier, ignoring case
+func containsIdentifier(lowerPassword string, identifier string) bool {
+	identifier = strings.ToLower(strings.TrimSpace(identifier))
+	if utf8.RuneCountInString(identifier) < minIdentifierLength {
+		return false
+	}
+
+	return strings.Contains(lowerPassword, identifier)
+}
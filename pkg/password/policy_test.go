@@ -0,0 +1,197 @@
+package password
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeBreachChecker reports the listed passwords as breached and counts the lookups
+type fakeBreachChecker struct {
+	breached map[string]bool
+	err      error
+	lookups  int
+}
+
+func (checker *fakeBreachChecker) Contains(password string) (bool, error) {
+	checker.lookups++
+	return checker.breached[password], checker.err
+}
+
+// violatedRules returns the rules listed by a policy error
+func violatedRules(t *testing.T, err error) []string {
+	t.Helper()
+
+	if err == nil {
+		return nil
+	}
+
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) || !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("Check() error = %v, want a *PolicyError", err)
+	}
+
+	rules := make([]string, 0, len(policyErr.Violations))
+	for _, violation := range policyErr.Violations {
+		rules = append(rules, violation.Rule)
+	}
+
+	return rules
+}
+
+func TestPolicyCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   PolicyConfig
+		password string
+		rules    []string
+	}{
+		{"default minimum length", PolicyConfig{}, "Abc123!", []string{RULE_MIN_LENGTH}},
+		{"default minimum length reached", PolicyConfig{}, "abcdefgh", nil},
+		{"minimum length in characters", PolicyConfig{MinLength: 4}, "äöü", []string{RULE_MIN_LENGTH}},
+		{"multi byte characters count once", PolicyConfig{MinLength: 4}, "äöüß", nil},
+		{"default maximum of 72 bytes", PolicyConfig{}, strings.Repeat("a", 73), []string{RULE_MAX_LENGTH}},
+		{"72 bytes", PolicyConfig{}, strings.Repeat("a", 72), nil},
+		{"maximum length in bytes", PolicyConfig{MinLength: 4, MaxLength: 10}, strings.Repeat("ä", 6), []string{RULE_MAX_LENGTH}},
+		{"every class missing", PolicyConfig{MinLength: 1, RequireLowercase: true, RequireUppercase: true, RequireDigit: true, RequireSymbol: true}, "", []string{RULE_MIN_LENGTH, RULE_LOWERCASE, RULE_UPPERCASE, RULE_DIGIT, RULE_SYMBOL}},
+		{"lowercase missing", PolicyConfig{RequireLowercase: true}, "ABCDEFGH", []string{RULE_LOWERCASE}},
+		{"uppercase missing", PolicyConfig{RequireUppercase: true}, "abcdefgh", []string{RULE_UPPERCASE}},
+		{"digit missing", PolicyConfig{RequireDigit: true}, "abcdefgh", []string{RULE_DIGIT}},
+		{"symbol missing", PolicyConfig{RequireSymbol: true}, "abcdefg1", []string{RULE_SYMBOL}},
+		{"space counts as a symbol", PolicyConfig{RequireSymbol: true}, "abc defg", nil},
+		{"non ASCII letters count", PolicyConfig{RequireLowercase: true, RequireUppercase: true}, "ÄBCDEFGß", nil},
+		{"every class present", PolicyConfig{RequireLowercase: true, RequireUppercase: true, RequireDigit: true, RequireSymbol: true}, "Abcdef1!", nil},
+		{"classes not required", PolicyConfig{}, "aaaaaaaa", nil},
+		{"username", PolicyConfig{ForbidUserInfo: true}, "xxALICExx", []string{RULE_CONTAINS_USERNAME}},
+		{"email local part", PolicyConfig{ForbidUserInfo: true}, "wonderland-1", []string{RULE_CONTAINS_EMAIL}},
+		{"user info allowed", PolicyConfig{}, "xxalicexx", nil},
+		{"several rules", PolicyConfig{MinLength: 12, RequireDigit: true, ForbidUserInfo: true}, "alice", []string{RULE_MIN_LENGTH, RULE_DIGIT, RULE_CONTAINS_USERNAME}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := NewPolicy(tt.config)
+			if err != nil {
+				t.Fatalf("NewPolicy() failed: %v", err)
+			}
+
+			rules := violatedRules(t, policy.Check(tt.password, "alice", "wonderland@example.com"))
+			if strings.Join(rules, ",") != strings.Join(tt.rules, ",") {
+				t.Errorf("Check(%q) violations = %v, want %v", tt.password, rules, tt.rules)
+			}
+		})
+	}
+}
+
+func TestPolicyCheckShortIdentifiers(t *testing.T) {
+	// usernames and local parts too short to be meaningful are not looked for
+	policy, err := NewPolicy(PolicyConfig{ForbidUserInfo: true})
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+
+	if err := policy.Check("bobsleigh-jo", "bo", "jo@example.com"); err != nil {
+		t.Errorf("Check() error = %v, want nil", err)
+	}
+}
+
+func TestPolicyCheckDictionary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "common.txt")
+	if err := os.WriteFile(path, []byte("# common passwords\n\nPassword1\n  letmein123  \n"), 0o600); err != nil {
+		t.Fatalf("dictionary write failed: %v", err)
+	}
+
+	policy, err := NewPolicy(PolicyConfig{DictionaryFile: path})
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+
+	tests := []struct {
+		password string
+		rules    []string
+	}{
+		{"password1", []string{RULE_COMMON_PASSWORD}},
+		{"LETMEIN123", []string{RULE_COMMON_PASSWORD}},
+		{"# common passwords", nil},
+		{"letmein1234", nil},
+	}
+
+	for _, tt := range tests {
+		rules := violatedRules(t, policy.Check(tt.password, "alice", "alice@example.com"))
+		if strings.Join(rules, ",") != strings.Join(tt.rules, ",") {
+			t.Errorf("Check(%q) violations = %v, want %v", tt.password, rules, tt.rules)
+		}
+	}
+
+	if _, err := NewPolicy(PolicyConfig{DictionaryFile: filepath.Join(t.TempDir(), "missing")}); !errors.Is(err, ErrDictionaryLoading) {
+		t.Errorf("NewPolicy() with a missing dictionary error = %v, want %v", err, ErrDictionaryLoading)
+	}
+}
+
+func TestPolicyCheckBreached(t *testing.T) {
+	checker := &fakeBreachChecker{breached: map[string]bool{"Tr0ub4dor&3": true, "abc123": true}}
+	policy, err := NewPolicy(PolicyConfig{})
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+	policy.breachChecker = checker
+
+	rules := violatedRules(t, policy.Check("Tr0ub4dor&3", "alice", "alice@example.com"))
+	if strings.Join(rules, ",") != RULE_BREACHED_PASSWORD {
+		t.Errorf("Check() of a breached password violations = %v, want %v", rules, RULE_BREACHED_PASSWORD)
+	}
+
+	// the breach hit is reported along with the other violations
+	rules = violatedRules(t, policy.Check("abc123", "alice", "alice@example.com"))
+	if strings.Join(rules, ",") != RULE_MIN_LENGTH+","+RULE_BREACHED_PASSWORD {
+		t.Errorf("Check() of a short breached password violations = %v, want %v and %v", rules, RULE_MIN_LENGTH, RULE_BREACHED_PASSWORD)
+	}
+
+	if err := policy.Check("correct horse battery staple", "alice", "alice@example.com"); err != nil {
+		t.Errorf("Check() of a safe password error = %v, want nil", err)
+	}
+	if checker.lookups != 3 {
+		t.Errorf("Check() looked the corpus up %d times, want 3", checker.lookups)
+	}
+
+	// a failed lookup is not a policy violation
+	checker.err = ErrBreachLookup
+	if err := policy.Check("correct horse battery staple", "alice", "alice@example.com"); !errors.Is(err, ErrBreachLookup) || errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Check() with a failing corpus error = %v, want %v", err, ErrBreachLookup)
+	}
+}
+
+func TestPolicyCheckBreachHashList(t *testing.T) {
+	path := writeHashList(t, []string{"Tr0ub4dor&3"}, func(hash string, count int) string { return hash + ":1\n" })
+
+	policy, err := NewPolicy(PolicyConfig{BreachFile: path})
+	if err != nil {
+		t.Fatalf("NewPolicy() failed: %v", err)
+	}
+
+	rules := violatedRules(t, policy.Check("Tr0ub4dor&3", "alice", "alice@example.com"))
+	if strings.Join(rules, ",") != RULE_BREACHED_PASSWORD {
+		t.Errorf("Check() violations = %v, want %v", rules, RULE_BREACHED_PASSWORD)
+	}
+}
+
+func TestNewPolicyInvalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PolicyConfig
+	}{
+		{"negative minimum length", PolicyConfig{MinLength: -1}},
+		{"maximum beyond 72 bytes", PolicyConfig{MaxLength: 73}},
+		{"minimum above the maximum", PolicyConfig{MinLength: 20, MaxLength: 10}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPolicy(tt.config); !errors.Is(err, ErrInvalidPolicy) {
+				t.Errorf("NewPolicy() error = %v, want %v", err, ErrInvalidPolicy)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+// Package pkce implements the Proof Key for Code Exchange of RFC 7636, only the S256
+// method is supported since plain offers no protection against an intercepted code
+package pkce
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// METHOD_S256 is the code challenge method of a SHA-256 challenge
+const METHOD_S256 = "S256"
+
+// length bounds of a code verifier, also the bounds of a S256 challenge once encoded
+const (
+	minVerifierLength = 43
+	maxVerifierLength = 128
+)
+
+// ValidVerifier reports whether the code verifier has a valid length and only uses the
+// unreserved characters [A-Z] / [a-z] / [0-9] / "-" / "." / "_" / "~"
+func ValidVerifier(verifier string) bool {
+	if len(verifier) < minVerifierLength || len(verifier) > maxVerifierLength {
+		return false
+	}
+
+	for i := 0; i < len(verifier); i++ {
+		c := verifier[i]
+		switch {
+		case c >= 'A' && c <= 'Z', c >= 'a' && c <= 'z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// ValidChallenge reports whether the value is a well formed S256 code challenge, the
+// base64url encoding of a SHA-256 digest without padding
+func ValidChallenge(challenge string) bool {
+	digest, err := base64.RawURLEncoding.DecodeString(challenge)
+
+	return err == nil && len(digest) == sha256.Size
+}
+
+// S256Challenge returns the S256 code challenge of the code verifier
+func S256Challenge(verifier string) string {
+	digest := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(digest[:])
+}
+
+// VerifyS256 reports whether the code verifier matches the S256 code challenge
+func VerifyS256(verifier string, challenge string) bool {
+	if !ValidVerifier(verifier) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(S256Challenge(verifier)), []byte(challenge)) == 1
+}
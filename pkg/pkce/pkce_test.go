@@ -0,0 +1,97 @@
+package pkce
+
+import (
+	"strings"
+	"testing"
+)
+
+// code verifier and S256 challenge of the example of RFC 7636 appendix B
+const (
+	rfcVerifier  = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	rfcChallenge = "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+func TestS256Challenge(t *testing.T) {
+	if challenge := S256Challenge(rfcVerifier); challenge != rfcChallenge {
+		t.Errorf("S256Challenge() = %s, want %s", challenge, rfcChallenge)
+	}
+}
+
+func TestVerifyS256(t *testing.T) {
+	tests := []struct {
+		name      string
+		verifier  string
+		challenge string
+		ok        bool
+	}{
+		{"RFC 7636 example", rfcVerifier, rfcChallenge, true},
+		{"minimum length verifier", strings.Repeat("a", 43), S256Challenge(strings.Repeat("a", 43)), true},
+		{"maximum length verifier", strings.Repeat("~", 128), S256Challenge(strings.Repeat("~", 128)), true},
+		{"other verifier", strings.Repeat("a", 43), rfcChallenge, false},
+		{"verifier used as plain challenge", rfcVerifier, rfcVerifier, false},
+		{"padded challenge", rfcVerifier, rfcChallenge + "=", false},
+		{"empty challenge", rfcVerifier, "", false},
+		{"verifier too short", rfcVerifier[:42], S256Challenge(rfcVerifier[:42]), false},
+		{"verifier too long", strings.Repeat("a", 129), S256Challenge(strings.Repeat("a", 129)), false},
+		{"verifier with invalid characters", rfcVerifier[:42] + "+", S256Challenge(rfcVerifier[:42] + "+"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok := VerifyS256(tt.verifier, tt.challenge); ok != tt.ok {
+				t.Errorf("VerifyS256(%q, %q) = %v, want %v", tt.verifier, tt.challenge, ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidVerifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		verifier string
+		ok       bool
+	}{
+		{"RFC 7636 example", rfcVerifier, true},
+		{"every unreserved character", "ABCXYZabcxyz0189-._~" + strings.Repeat("a", 23), true},
+		{"empty", "", false},
+		{"42 characters", strings.Repeat("a", 42), false},
+		{"129 characters", strings.Repeat("a", 129), false},
+		{"space", strings.Repeat("a", 42) + " ", false},
+		{"plus", strings.Repeat("a", 42) + "+", false},
+		{"slash", strings.Repeat("a", 42) + "/", false},
+		{"padding", strings.Repeat("a", 42) + "=", false},
+		{"non ASCII", strings.Repeat("a", 41) + "é", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok := ValidVerifier(tt.verifier); ok != tt.ok {
+				t.Errorf("ValidVerifier(%q) = %v, want %v", tt.verifier, ok, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidChallenge(t *testing.T) {
+	tests := []struct {
+		name      string
+		challenge string
+		ok        bool
+	}{
+		{"RFC 7636 example", rfcChallenge, true},
+		{"empty", "", false},
+		{"padded", rfcChallenge + "=", false},
+		{"standard base64 alphabet", strings.NewReplacer("-", "+").Replace(rfcChallenge), false},
+		{"digest too short", rfcChallenge[:42], false},
+		{"digest too long", rfcChallenge + "AAAA", false},
+		{"plain verifier", rfcVerifier + "x", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok := ValidChallenge(tt.challenge); ok != tt.ok {
+				t.Errorf("ValidChallenge(%q) = %v, want %v", tt.challenge, ok, tt.ok)
+			}
+		})
+	}
+}
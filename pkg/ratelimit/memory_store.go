@@ -0,0 +1,97 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// window holds the counters of a key for the current and the previous fixed window
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+	length   time.Duration
+}
+
+// MemoryStore keeps the counters in memory, limits only apply per process
+type MemoryStore struct {
+	mu      sync.Mutex
+	windows map[string]*window
+	now     func() time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		windows: map[string]*window{},
+		now:     time.Now,
+	}
+}
+
+func (store *MemoryStore) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if err := limit.validate(); err != nil {
+		return Result{}, err
+	}
+
+	now := store.now()
+	start := now.Truncate(limit.Window)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	w, ok := store.windows[key]
+	switch {
+	case !ok || w.length != limit.Window || !start.Before(w.start.Add(2*limit.Window)):
+		// first request, limit changed or the previous window is over as well
+		w = &window{start: start, length: limit.Window}
+		store.windows[key] = w
+	case start.After(w.start):
+		// the window moved by one, the current counter becomes the previous one
+		w.previous = w.current
+		w.current = 0
+		w.start = start
+	}
+
+	result, allowed := slidingWindow(limit, w.previous, w.current, now.Sub(start))
+	if allowed {
+		w.current++
+	}
+
+	return result, nil
+}
+
+// purge removes the keys without requests in the last two windows
+func (store *MemoryStore) purge() {
+	now := store.now()
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	for key, w := range store.windows {
+		if !now.Before(w.start.Add(2 * w.length)) {
+			delete(store.windows, key)
+		}
+	}
+}
+
+// StartPurge periodically drops idle keys in the background, the returned function stops it
+func (store *MemoryStore) StartPurge(interval time.Duration) func() {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+
+	go func() {
+		for {
+			select {
+			case <-ticker.C:
+				store.purge()
+			case <-done:
+				ticker.Stop()
+				return
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestMemoryStore returns a memory store reading the time from the returned clock
+func newTestMemoryStore() (*MemoryStore, *time.Time) {
+	now := time.Date(2026, 1, 2, 3, 4, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+
+	return store, &now
+}
+
+func TestMemoryStoreAllow(t *testing.T) {
+	store, now := newTestMemoryStore()
+	limit := Limit{Requests: 3, Window: time.Minute}
+
+	// the limit is used up within the first window
+	for i := 0; i < 3; i++ {
+		result, err := store.Allow(context.Background(), "login:ip:1.2.3.4", limit)
+		if err != nil {
+			t.Fatalf("Allow() failed: %v", err)
+		}
+		if !result.Allowed || result.Remaining != 2-i || result.Limit != 3 {
+			t.Errorf("Allow() request %d = %+v, want allowed with %d remaining", i+1, result, 2-i)
+		}
+	}
+
+	*now = now.Add(30 * time.Second)
+	result, _ := store.Allow(context.Background(), "login:ip:1.2.3.4", limit)
+	if result.Allowed || result.Remaining != 0 || result.RetryAfter != 30*time.Second || result.ResetAfter != 30*time.Second {
+		t.Errorf("Allow() over the limit = %+v, want denied until the next window", result)
+	}
+
+	// other keys are counted apart
+	if result, _ := store.Allow(context.Background(), "login:ip:5.6.7.8", limit); !result.Allowed {
+		t.Errorf("Allow() of another key = %+v, want allowed", result)
+	}
+
+	// a third into the next window, two thirds of the previous count still weigh: 3*2/3 = 2
+	*now = now.Add(50 * time.Second)
+	result, _ = store.Allow(context.Background(), "login:ip:1.2.3.4", limit)
+	if !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Allow() in the next window = %+v, want allowed with 0 remaining", result)
+	}
+	result, _ = store.Allow(context.Background(), "login:ip:1.2.3.4", limit)
+	if result.Allowed || result.RetryAfter <= 0 {
+		t.Errorf("Allow() in the next window over the limit = %+v, want denied", result)
+	}
+
+	// two windows later the counters are reset
+	*now = now.Add(2 * time.Minute)
+	result, _ = store.Allow(context.Background(), "login:ip:1.2.3.4", limit)
+	if !result.Allowed || result.Remaining != 2 {
+		t.Errorf("Allow() two windows later = %+v, want allowed with 2 remaining", result)
+	}
+}
+
+func TestMemoryStoreLimitChange(t *testing.T) {
+	store, _ := newTestMemoryStore()
+
+	for i := 0; i < 2; i++ {
+		store.Allow(context.Background(), "key", Limit{Requests: 2, Window: time.Minute})
+	}
+
+	// a new window length starts the counting over
+	result, err := store.Allow(context.Background(), "key", Limit{Requests: 2, Window: time.Hour})
+	if err != nil || !result.Allowed {
+		t.Errorf("Allow() after a limit change = %+v, %v, want allowed", result, err)
+	}
+}
+
+func TestMemoryStorePurge(t *testing.T) {
+	store, now := newTestMemoryStore()
+	limit := Limit{Requests: 1, Window: time.Minute}
+
+	store.Allow(context.Background(), "idle", limit)
+	*now = now.Add(time.Minute)
+	store.Allow(context.Background(), "active", limit)
+
+	*now = now.Add(time.Minute)
+	store.purge()
+	if _, ok := store.windows["idle"]; ok {
+		t.Error("purge() kept a key idle for two windows")
+	}
+	if _, ok := store.windows["active"]; !ok {
+		t.Error("purge() removed a key used in the previous window")
+	}
+}
+
+func TestMemoryStoreInvalidLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit Limit
+	}{
+		{"zero window", Limit{Requests: 1, Window: 0}},
+		{"window under a millisecond", Limit{Requests: 1, Window: time.Microsecond}},
+		{"window of partial milliseconds", Limit{Requests: 1, Window: 1500 * time.Microsecond}},
+		{"negative window", Limit{Requests: 1, Window: -time.Minute}},
+		{"no requests", Limit{Requests: 0, Window: time.Minute}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := newTestMemoryStore()
+			if _, err := store.Allow(context.Background(), "key", tt.limit); !errors.Is(err, ErrInvalidLimit) {
+				t.Errorf("Allow() error = %v, want %v", err, ErrInvalidLimit)
+			}
+			if _, err := NewRedisStore(nil, "").Allow(context.Background(), "key", tt.limit); !errors.Is(err, ErrInvalidLimit) {
+				t.Errorf("RedisStore Allow() error = %v, want %v", err, ErrInvalidLimit)
+			}
+		})
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		value string
+		limit Limit
+		err   error
+	}{
+		{"10/1m", Limit{Requests: 10, Window: time.Minute}, nil},
+		{" 5/15m ", Limit{Requests: 5, Window: 15 * time.Minute}, nil},
+		{"1/1ms", Limit{Requests: 1, Window: time.Millisecond}, nil},
+		{"1/999us", Limit{}, ErrInvalidLimit},
+		{"1/1500us", Limit{}, ErrInvalidLimit},
+		{"1/0s", Limit{}, ErrInvalidLimit},
+		{"0/1m", Limit{}, ErrInvalidLimit},
+		{"-1/1m", Limit{}, ErrInvalidLimit},
+		{"10", Limit{}, ErrInvalidLimit},
+		{"ten/1m", Limit{}, ErrInvalidLimit},
+		{"10/minute", Limit{}, ErrInvalidLimit},
+	}
+
+	for _, tt := range tests {
+		limit, err := ParseLimit(tt.value)
+		if !errors.Is(err, tt.err) || limit != tt.limit {
+			t.Errorf("ParseLimit(%q) = %v, %v, want %v, %v", tt.value, limit, err, tt.limit, tt.err)
+		}
+	}
+}
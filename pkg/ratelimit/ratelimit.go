@@ -0,0 +1,160 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// key types a limit can be applied to
+const (
+	KEY_TYPE_IP       = "ip"
+	KEY_TYPE_USERNAME = "username"
+	KEY_TYPE_EMAIL    = "email"
+)
+
+var (
+	ErrInvalidLimit = errors.New("rate limit: invalid limit, expected <requests>/<window> e.g. 10/1m with a window of whole milliseconds")
+	ErrInvalidRule  = errors.New("rate limit: invalid rule, expected <route>:<key type>=<limit>")
+)
+
+// Limit allows Requests per sliding Window
+type Limit struct {
+	Requests int
+	Window   time.Duration
+}
+
+// validate rejects the limits the stores can't count, the Redis store counts the window in
+// whole milliseconds and divides by it
+func (limit Limit) validate() error {
+	if limit.Requests <= 0 || limit.Window < time.Millisecond || limit.Window%time.Millisecond != 0 {
+		return ErrInvalidLimit
+	}
+
+	return nil
+}
+
+// String formats the limit the way ParseLimit reads it
+func (limit Limit) String() string {
+	return strconv.Itoa(limit.Requests) + "/" + limit.Window.String()
+}
+
+// Result represents the outcome of a rate limited request
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// ResetAfter is the time until the window fully resets
+	ResetAfter time.Duration
+	// RetryAfter is the time until the next request is allowed, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Store keeps the request counters, implementations must be safe for concurrent use
+type Store interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// Rules holds the configured limits by route and key type
+type Rules map[string]Limit
+
+// Get returns the limit of the route for the key type
+func (rules Rules) Get(route string, keyType string) (Limit, bool) {
+	limit, ok := rules[route+":"+keyType]
+
+	return limit, ok
+}
+
+// ParseLimit parses a limit formatted as <requests>/<window>, e.g. 10/1m
+func ParseLimit(value string) (Limit, error) {
+	requests, window, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	count, err := strconv.Atoi(requests)
+	if err != nil {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	duration, err := time.ParseDuration(window)
+	if err != nil {
+		return Limit{}, ErrInvalidLimit
+	}
+
+	limit := Limit{Requests: count, Window: duration}
+	if err := limit.validate(); err != nil {
+		return Limit{}, err
+	}
+
+	return limit, nil
+}
+
+// ParseRules parses comma separated rules formatted as <route>:<key type>=<limit>,
+// e.g. login:ip=10/1m,login:username=5/1m
+func ParseRules(value string) (Rules, error) {
+	rules := Rules{}
+	for _, rule := range strings.Split(value, ",") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+
+		name, limitValue, ok := strings.Cut(rule, "=")
+		if !ok || !strings.Contains(name, ":") {
+			return Rules{}, fmt.Errorf("%w: %s", ErrInvalidRule, rule)
+		}
+
+		limit, err := ParseLimit(limitValue)
+		if err != nil {
+			return Rules{}, fmt.Errorf("%w: %s", err, rule)
+		}
+		rules[strings.TrimSpace(name)] = limit
+	}
+
+	return rules, nil
+}
+
+// slidingWindow computes the result of the sliding window counter algorithm, the count of
+// the previous window is weighted by how much of it still overlaps the sliding window
+func slidingWindow(limit Limit, previous int, current int, elapsed time.Duration) (Result, bool) {
+	weight := float64(limit.Window-elapsed) / float64(limit.Window)
+	estimated := float64(previous)*weight + float64(current)
+
+	result := Result{
+		Limit:      limit.Requests,
+		ResetAfter: limit.Window - elapsed,
+	}
+
+	if estimated+1 > float64(limit.Requests) {
+		result.Remaining = 0
+		result.RetryAfter = retryAfter(limit, previous, current, elapsed)
+
+		return result, false
+	}
+
+	result.Allowed = true
+	result.Remaining = int(float64(limit.Requests) - estimated - 1)
+
+	return result, true
+}
+
+// retryAfter estimates when the weighted count drops enough to allow one more request
+func retryAfter(limit Limit, previous int, current int, elapsed time.Duration) time.Duration {
+	// the current window alone exhausts the limit, wait for the next window
+	if current+1 > limit.Requests || previous == 0 {
+		return limit.Window - elapsed
+	}
+
+	// solve previous * (window - t) / window + current + 1 <= requests for t
+	allowedPrevious := float64(limit.Requests - current - 1)
+	t := time.Duration(float64(limit.Window) * (1 - allowedPrevious/float64(previous)))
+	if t <= elapsed {
+		return time.Second
+	}
+
+	return t - elapsed
+}
@@ -0,0 +1,91 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrUnexpectedReply = errors.New("rate limit: unexpected reply from the redis store")
+
+// RedisScripter is the subset of a Redis client used by the RedisStore, e.g. a thin
+// adapter around the Eval method of go-redis or any Redis protocol compatible server
+type RedisScripter interface {
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// slidingWindowScript atomically reads both window counters and counts the request when allowed.
+// KEYS: current window key, previous window key. ARGV: requests, window ms, elapsed ms.
+const slidingWindowScript = `
+local requests = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+local previous = tonumber(redis.call('GET', KEYS[2]) or '0')
+if previous * (window - elapsed) / window + current + 1 > requests then
+	return {0, current, previous}
+end
+current = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], window * 2)
+return {1, current - 1, previous}
+`
+
+// RedisStore keeps the counters in Redis so that limits are shared by every replica
+type RedisStore struct {
+	client RedisScripter
+	prefix string
+	now    func() time.Time
+}
+
+func NewRedisStore(client RedisScripter, prefix string) *RedisStore {
+	return &RedisStore{
+		client: client,
+		prefix: prefix,
+		now:    time.Now,
+	}
+}
+
+func (store *RedisStore) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if err := limit.validate(); err != nil {
+		return Result{}, err
+	}
+
+	now := store.now()
+	start := now.Truncate(limit.Window)
+	index := start.UnixMilli() / limit.Window.Milliseconds()
+	elapsed := now.Sub(start)
+
+	reply, err := store.client.Eval(ctx, slidingWindowScript,
+		[]string{
+			store.prefix + key + ":" + strconv.FormatInt(index, 10),
+			store.prefix + key + ":" + strconv.FormatInt(index-1, 10),
+		},
+		limit.Requests, limit.Window.Milliseconds(), elapsed.Milliseconds(),
+	)
+	if err != nil {
+		return Result{}, err
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 3 {
+		return Result{}, ErrUnexpectedReply
+	}
+
+	current, okCurrent := values[1].(int64)
+	previous, okPrevious := values[2].(int64)
+	if !okCurrent || !okPrevious {
+		return Result{}, ErrUnexpectedReply
+	}
+
+	// the script already decided, the shared computation only fills in the headers
+	result, _ := slidingWindow(limit, int(previous), int(current), elapsed)
+	result.Allowed = values[0] == int64(1)
+	if result.Allowed {
+		result.RetryAfter = 0
+	} else {
+		result.Remaining = 0
+	}
+
+	return result, nil
+}
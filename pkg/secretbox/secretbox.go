@@ -0,0 +1,79 @@
+package secretbox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+)
+
+// length in bytes of the key, AES-256
+const KeyBytes = 32
+
+var (
+	ErrInvalidKey        = errors.New("secretbox: key must be 32 bytes")
+	ErrInvalidCiphertext = errors.New("secretbox: invalid ciphertext")
+)
+
+// Box encrypts small secrets at rest with AES-256-GCM
+type Box struct {
+	aead cipher.AEAD
+}
+
+// NewBox creates a box encrypting with the key
+func NewBox(key []byte) (*Box, error) {
+	if len(key) != KeyBytes {
+		return nil, ErrInvalidKey
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Box{aead: aead}, nil
+}
+
+// ParseKey decodes a base64 encoded key
+func ParseKey(encodedKey string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(encodedKey)
+	if err != nil || len(key) != KeyBytes {
+		return nil, ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+// Seal encrypts the plaintext and returns the base64 encoded nonce and ciphertext
+func (box *Box) Seal(plaintext []byte) (string, error) {
+	nonce := make([]byte, box.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := box.aead.Seal(nonce, nonce, plaintext, nil)
+
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a value returned by Seal
+func (box *Box) Open(sealed string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < box.aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonce, ciphertext := data[:box.aead.NonceSize()], data[box.aead.NonceSize():]
+	plaintext, err := box.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
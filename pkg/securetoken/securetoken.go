@@ -0,0 +1,31 @@
+package securetoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+const (
+	// DefaultTokenBytes is the amount of entropy used for opaque tokens
+	DefaultTokenBytes = 32
+)
+
+// Generate returns a URL-safe random token built from n random bytes.
+func Generate(n int) (string, error) {
+	bytes := make([]byte, n)
+	if _, err := rand.Read(bytes); err != nil {
+
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// Hash returns the hex encoded SHA-256 digest of the token, used for storage and lookups.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+
+	return hex.EncodeToString(sum[:])
+}
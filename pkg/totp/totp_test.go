@@ -0,0 +1,121 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// secret of the RFC 6238 and RFC 4226 test vectors, the ASCII string "12345678901234567890"
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestCodeRFC6238(t *testing.T) {
+	// the SHA1 vectors of RFC 6238 appendix B, truncated to the last 6 of their 8 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		code, err := Code(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) failed: %v", tt.unix, err)
+		}
+		if code != tt.code {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, code, tt.code)
+		}
+	}
+}
+
+func TestCodeRFC4226(t *testing.T) {
+	// the HOTP vectors of RFC 4226 appendix D, for the counters 0 to 9
+	codes := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, want := range codes {
+		code, err := Code(rfcSecret, int64(counter))
+		if err != nil {
+			t.Fatalf("Code(%d) failed: %v", counter, err)
+		}
+		if code != want {
+			t.Errorf("Code(%d) = %s, want %s", counter, code, want)
+		}
+	}
+}
+
+func TestCodeSecretEncoding(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		err    error
+	}{
+		{"lower case", "gezdgnbvgy3tqojqgezdgnbvgy3tqojq", nil},
+		{"surrounding spaces", " " + rfcSecret + " ", nil},
+		{"empty", "", ErrInvalidSecret},
+		{"invalid character", "GEZDGNBVGY3TQOJ1", ErrInvalidSecret},
+		{"padding", rfcSecret + "====", ErrInvalidSecret},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := Code(tt.secret, 1)
+			if err != tt.err {
+				t.Fatalf("Code() error = %v, want %v", err, tt.err)
+			}
+			if err == nil && code != "287082" {
+				t.Errorf("Code() = %s, want 287082", code)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// 1111111111 is the counter 37037037, 1111111109 the one before it
+	now := time.Unix(1111111111, 0)
+
+	tests := []struct {
+		name    string
+		code    string
+		counter int64
+		ok      bool
+	}{
+		{"current step", "050471", 37037037, true},
+		{"previous step", "081804", 37037036, true},
+		{"surrounding spaces", " 050471 ", 37037037, true},
+		{"too short", "05047", 0, false},
+		{"too long", "0504710", 0, false},
+		{"wrong code", "000000", 0, false},
+		{"outside the skew", "005924", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counter, ok := Validate(rfcSecret, tt.code, now)
+			if ok != tt.ok || counter != tt.counter {
+				t.Errorf("Validate(%q) = %d, %v, want %d, %v", tt.code, counter, ok, tt.counter, tt.ok)
+			}
+		})
+	}
+}
+
+func TestValidateInvalidSecret(t *testing.T) {
+	if _, ok := Validate("", "050471", time.Unix(1111111111, 0)); ok {
+		t.Error("Validate() accepted a code for an empty secret")
+	}
+}
+
+func TestURI(t *testing.T) {
+	uri := URI("Example Corp", "alice@example.com", rfcSecret)
+	want := "otpauth://totp/Example%20Corp:alice@example.com?algorithm=SHA1&digits=6&issuer=Example+Corp&period=30&secret=" + rfcSecret
+	if uri != want {
+		t.Errorf("URI() = %s, want %s", uri, want)
+	}
+}
@@ -0,0 +1,91 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent            = 0x01
+	flagUserVerified           = 0x04
+	flagAttestedCredentialData = 0x40
+	flagExtensionData          = 0x80
+)
+
+// length of the fixed part of the authenticator data: rpIdHash, flags and signCount
+const authenticatorDataMinLength = 37
+
+var ErrInvalidAuthenticatorData = errors.New("webauthn: invalid authenticator data")
+
+// AuthenticatorData represents the parsed authenticator data of a ceremony
+type AuthenticatorData struct {
+	RPIDHash  []byte
+	Flags     byte
+	SignCount uint32
+	// attested credential data, only present on registration
+	AAGUID       []byte
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// UserPresent reports whether the user interacted with the authenticator
+func (data AuthenticatorData) UserPresent() bool {
+	return data.Flags&flagUserPresent != 0
+}
+
+// UserVerified reports whether the authenticator verified the user, e.g. with a PIN or biometrics
+func (data AuthenticatorData) UserVerified() bool {
+	return data.Flags&flagUserVerified != 0
+}
+
+// parseAuthenticatorData parses the binary authenticator data
+func parseAuthenticatorData(raw []byte) (AuthenticatorData, error) {
+	if len(raw) < authenticatorDataMinLength {
+		return AuthenticatorData{}, ErrInvalidAuthenticatorData
+	}
+
+	data := AuthenticatorData{
+		RPIDHash:  raw[:32],
+		Flags:     raw[32],
+		SignCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[authenticatorDataMinLength:]
+
+	if data.Flags&flagAttestedCredentialData != 0 {
+		// aaguid (16) and credential id length (2)
+		if len(rest) < 18 {
+			return AuthenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		data.AAGUID = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+
+		if idLength == 0 || len(rest) < idLength {
+			return AuthenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		data.CredentialID = rest[:idLength]
+		rest = rest[idLength:]
+
+		_, keyLength, err := decodeCBOR(rest)
+		if err != nil {
+			return AuthenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		data.PublicKey = rest[:keyLength]
+		rest = rest[keyLength:]
+	}
+
+	if data.Flags&flagExtensionData != 0 {
+		_, extensionsLength, err := decodeCBOR(rest)
+		if err != nil {
+			return AuthenticatorData{}, ErrInvalidAuthenticatorData
+		}
+		rest = rest[extensionsLength:]
+	}
+
+	if len(rest) != 0 {
+		return AuthenticatorData{}, ErrInvalidAuthenticatorData
+	}
+
+	return data, nil
+}
@@ -0,0 +1,143 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// authenticatorData builds authenticator data with the flags, followed by the given parts
+func authenticatorData(flags byte, signCount uint32, parts ...[]byte) []byte {
+	rpIDHash := sha256.Sum256([]byte("example.com"))
+	raw := append(rpIDHash[:], flags)
+	raw = binary.BigEndian.AppendUint32(raw, signCount)
+	for _, part := range parts {
+		raw = append(raw, part...)
+	}
+
+	return raw
+}
+
+// attestedCredentialData builds the aaguid, the credential id and its length prefix
+func attestedCredentialData(credentialID []byte) []byte {
+	raw := bytes.Repeat([]byte{0x01}, 16)
+	raw = binary.BigEndian.AppendUint16(raw, uint16(len(credentialID)))
+
+	return append(raw, credentialID...)
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	publicKey := coseEC2Key(&ecKey.PublicKey)
+	credentialID := []byte("credential-id")
+	extensions := cborEncode(cborPairs{"credProtect", 2})
+
+	tests := []struct {
+		name         string
+		raw          []byte
+		userPresent  bool
+		userVerified bool
+		signCount    uint32
+		credentialID []byte
+		publicKey    []byte
+	}{
+		{
+			name:        "assertion",
+			raw:         authenticatorData(flagUserPresent, 7),
+			userPresent: true,
+			signCount:   7,
+		},
+		{
+			name:         "user verified assertion",
+			raw:          authenticatorData(flagUserPresent|flagUserVerified, 0),
+			userPresent:  true,
+			userVerified: true,
+		},
+		{
+			name:         "registration",
+			raw:          authenticatorData(flagUserPresent|flagUserVerified|flagAttestedCredentialData, 1, attestedCredentialData(credentialID), publicKey),
+			userPresent:  true,
+			userVerified: true,
+			signCount:    1,
+			credentialID: credentialID,
+			publicKey:    publicKey,
+		},
+		{
+			name:         "registration with extensions",
+			raw:          authenticatorData(flagUserPresent|flagAttestedCredentialData|flagExtensionData, 0, attestedCredentialData(credentialID), publicKey, extensions),
+			userPresent:  true,
+			credentialID: credentialID,
+			publicKey:    publicKey,
+		},
+		{
+			name:        "assertion with extensions",
+			raw:         authenticatorData(flagUserPresent|flagExtensionData, 3, extensions),
+			userPresent: true,
+			signCount:   3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := parseAuthenticatorData(tt.raw)
+			if err != nil {
+				t.Fatalf("parseAuthenticatorData() failed: %v", err)
+			}
+			if data.UserPresent() != tt.userPresent || data.UserVerified() != tt.userVerified {
+				t.Errorf("parseAuthenticatorData() flags = %#x, want user present %v and verified %v", data.Flags, tt.userPresent, tt.userVerified)
+			}
+			if data.SignCount != tt.signCount {
+				t.Errorf("parseAuthenticatorData() sign count = %d, want %d", data.SignCount, tt.signCount)
+			}
+			if !bytes.Equal(data.RPIDHash, tt.raw[:32]) {
+				t.Errorf("parseAuthenticatorData() rp id hash = %x, want %x", data.RPIDHash, tt.raw[:32])
+			}
+			if !bytes.Equal(data.CredentialID, tt.credentialID) {
+				t.Errorf("parseAuthenticatorData() credential id = %x, want %x", data.CredentialID, tt.credentialID)
+			}
+			if !bytes.Equal(data.PublicKey, tt.publicKey) {
+				t.Errorf("parseAuthenticatorData() public key = %x, want %x", data.PublicKey, tt.publicKey)
+			}
+		})
+	}
+}
+
+func TestParseAuthenticatorDataMalformed(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	publicKey := coseEC2Key(&ecKey.PublicKey)
+	credentialID := []byte("credential-id")
+	registration := flagUserPresent | flagAttestedCredentialData
+
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"empty", []byte{}},
+		{"shorter than the fixed part", authenticatorData(flagUserPresent, 0)[:authenticatorDataMinLength-1]},
+		{"trailing bytes", authenticatorData(flagUserPresent, 0, []byte{0x00})},
+		{"attested flag without credential data", authenticatorData(byte(registration), 0)},
+		{"truncated aaguid", authenticatorData(byte(registration), 0, bytes.Repeat([]byte{0x01}, 17))},
+		{"empty credential id", authenticatorData(byte(registration), 0, attestedCredentialData(nil), publicKey)},
+		{"credential id longer than the data", authenticatorData(byte(registration), 0, attestedCredentialData(credentialID)[:20])},
+		{"missing public key", authenticatorData(byte(registration), 0, attestedCredentialData(credentialID))},
+		{"truncated public key", authenticatorData(byte(registration), 0, attestedCredentialData(credentialID), publicKey[:len(publicKey)-1])},
+		{"indefinite length public key", authenticatorData(byte(registration), 0, attestedCredentialData(credentialID), []byte{0xbf, 0x01, 0x02, 0xff})},
+		{"bytes after the public key", authenticatorData(byte(registration), 0, attestedCredentialData(credentialID), publicKey, []byte{0x00})},
+		{"extension flag without extensions", authenticatorData(flagUserPresent|flagExtensionData, 0)},
+		{"truncated extensions", authenticatorData(flagUserPresent|flagExtensionData, 0, []byte{0xa1, 0x61})},
+		{"bytes after the extensions", authenticatorData(flagUserPresent|flagExtensionData, 0, cborEncode(cborPairs{}), []byte{0x00})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseAuthenticatorData(tt.raw); !errors.Is(err, ErrInvalidAuthenticatorData) {
+				t.Errorf("parseAuthenticatorData() error = %v, want %v", err, ErrInvalidAuthenticatorData)
+			}
+		})
+	}
+}
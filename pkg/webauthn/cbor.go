@@ -0,0 +1,166 @@
+package webauthn
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// maximum nesting of the decoded CBOR items, authenticator data is shallow
+const maxCBORDepth = 16
+
+var ErrInvalidCBOR = errors.New("webauthn: invalid cbor")
+
+// decodeCBOR decodes the first CBOR item of data and returns it along with the number of
+// bytes it spans. Only the definite length encodings authenticators produce are supported,
+// integers decode to int64, byte strings to []byte, text to string, arrays to []any
+// and maps to map[any]any.
+func decodeCBOR(data []byte) (any, int, error) {
+	d := cborDecoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return value, d.offset, nil
+}
+
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+func (d *cborDecoder) decode(depth int) (any, error) {
+	if depth > maxCBORDepth {
+		return nil, ErrInvalidCBOR
+	}
+
+	majorType, argument, err := d.header()
+	if err != nil {
+		return nil, err
+	}
+
+	switch majorType {
+	case 0: // unsigned integer
+		if argument > math.MaxInt64 {
+			return nil, ErrInvalidCBOR
+		}
+		return int64(argument), nil
+	case 1: // negative integer
+		if argument > math.MaxInt64 {
+			return nil, ErrInvalidCBOR
+		}
+		return -1 - int64(argument), nil
+	case 2: // byte string
+		return d.bytes(argument)
+	case 3: // text string
+		text, err := d.bytes(argument)
+		if err != nil {
+			return nil, err
+		}
+		return string(text), nil
+	case 4: // array
+		if argument > uint64(len(d.data)) {
+			return nil, ErrInvalidCBOR
+		}
+		items := make([]any, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+		}
+		return items, nil
+	case 5: // map
+		if argument > uint64(len(d.data)) {
+			return nil, ErrInvalidCBOR
+		}
+		items := make(map[any]any, argument)
+		for i := uint64(0); i < argument; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, ErrInvalidCBOR
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items[key] = value
+		}
+		return items, nil
+	case 6: // tag, the tagged item is returned as is
+		return d.decode(depth + 1)
+	default: // simple values
+		switch argument {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		default:
+			return nil, ErrInvalidCBOR
+		}
+	}
+}
+
+// header reads the initial byte of an item and its argument
+func (d *cborDecoder) header() (byte, uint64, error) {
+	if d.offset >= len(d.data) {
+		return 0, 0, ErrInvalidCBOR
+	}
+
+	initial := d.data[d.offset]
+	d.offset++
+	majorType, info := initial>>5, initial&0x1f
+
+	var size int
+	switch {
+	case info < 24:
+		return majorType, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		// indefinite lengths and reserved values
+		return 0, 0, ErrInvalidCBOR
+	}
+
+	// floats are encoded as simple values with a sized argument, they never occur in webauthn data
+	if majorType == 7 {
+		return 0, 0, ErrInvalidCBOR
+	}
+
+	if d.offset+size > len(d.data) {
+		return 0, 0, ErrInvalidCBOR
+	}
+
+	buf := make([]byte, 8)
+	copy(buf[8-size:], d.data[d.offset:d.offset+size])
+	d.offset += size
+
+	return majorType, binary.BigEndian.Uint64(buf), nil
+}
+
+// bytes reads the content of a byte or text string
+func (d *cborDecoder) bytes(length uint64) ([]byte, error) {
+	if length > uint64(len(d.data)-d.offset) {
+		return nil, ErrInvalidCBOR
+	}
+
+	content := d.data[d.offset : d.offset+int(length)]
+	d.offset += int(length)
+
+	return content, nil
+}
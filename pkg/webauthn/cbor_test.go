@@ -0,0 +1,178 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// cborPairs is encoded as a map, its keys and values alternate to keep the encoding ordered
+type cborPairs []any
+
+// cborHeader encodes the initial byte and the argument of an item in its shortest form
+func cborHeader(majorType byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{majorType<<5 | byte(argument)}
+	case argument <= 0xff:
+		return []byte{majorType<<5 | 24, byte(argument)}
+	case argument <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{majorType<<5 | 25}, uint16(argument))
+	case argument <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{majorType<<5 | 26}, uint32(argument))
+	default:
+		return binary.BigEndian.AppendUint64([]byte{majorType<<5 | 27}, argument)
+	}
+}
+
+// cborEncode encodes the values the decoder supports, for building test inputs
+func cborEncode(value any) []byte {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return cborHeader(1, uint64(-1-v))
+		}
+		return cborHeader(0, uint64(v))
+	case []byte:
+		return append(cborHeader(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHeader(3, uint64(len(v))), v...)
+	case []any:
+		out := cborHeader(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, cborEncode(item)...)
+		}
+		return out
+	case cborPairs:
+		out := cborHeader(5, uint64(len(v)/2))
+		for _, item := range v {
+			out = append(out, cborEncode(item)...)
+		}
+		return out
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	case nil:
+		return []byte{0xf6}
+	default:
+		panic("cborEncode: unsupported value")
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		value any
+	}{
+		{"small integer", []byte{0x17}, int64(23)},
+		{"one byte integer", []byte{0x18, 0x18}, int64(24)},
+		{"two byte integer", []byte{0x19, 0x03, 0xe8}, int64(1000)},
+		{"four byte integer", []byte{0x1a, 0x00, 0x0f, 0x42, 0x40}, int64(1000000)},
+		{"eight byte integer", []byte{0x1b, 0, 0, 0, 0xe8, 0xd4, 0xa5, 0x10, 0x00}, int64(1000000000000)},
+		{"negative integer", []byte{0x20}, int64(-1)},
+		{"negative two byte integer", []byte{0x39, 0x01, 0x00}, int64(-257)},
+		{"byte string", []byte{0x43, 1, 2, 3}, []byte{1, 2, 3}},
+		{"empty byte string", []byte{0x40}, []byte{}},
+		{"text string", []byte{0x64, 'I', 'E', 'T', 'F'}, "IETF"},
+		{"array", []byte{0x83, 0x01, 0x02, 0x03}, []any{int64(1), int64(2), int64(3)}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x61, 'a', 0xf5}, map[any]any{int64(1): int64(2), "a": true}},
+		{"tag", []byte{0xc2, 0x41, 0x01}, []byte{1}},
+		{"false", []byte{0xf4}, false},
+		{"true", []byte{0xf5}, true},
+		{"null", []byte{0xf6}, nil},
+		{"undefined", []byte{0xf7}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, length, err := decodeCBOR(tt.data)
+			if err != nil {
+				t.Fatalf("decodeCBOR() failed: %v", err)
+			}
+			if !reflect.DeepEqual(value, tt.value) {
+				t.Errorf("decodeCBOR() = %#v, want %#v", value, tt.value)
+			}
+			if length != len(tt.data) {
+				t.Errorf("decodeCBOR() length = %d, want %d", length, len(tt.data))
+			}
+		})
+	}
+}
+
+func TestDecodeCBORFirstItem(t *testing.T) {
+	// only the first item is decoded, the length tells where the next one starts
+	value, length, err := decodeCBOR([]byte{0x01, 0x02})
+	if err != nil || value != int64(1) || length != 1 {
+		t.Errorf("decodeCBOR() = %v, %d, %v, want 1, 1, nil", value, length, err)
+	}
+}
+
+func TestDecodeCBORMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"truncated byte string", []byte{0x45, 1, 2}},
+		{"truncated text string", []byte{0x62, 'a'}},
+		{"byte string longer than the data", append([]byte{0x5b}, bytes.Repeat([]byte{0xff}, 8)...)},
+		{"truncated array", []byte{0x82, 0x01}},
+		{"array longer than the data", []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{"truncated map", []byte{0xa1, 0x01}},
+		{"map longer than the data", []byte{0xba, 0xff, 0xff, 0xff, 0xff}},
+		{"byte string map key", []byte{0xa1, 0x41, 0x01, 0x01}},
+		{"array map key", []byte{0xa1, 0x80, 0x01}},
+		{"unsigned integer overflow", []byte{0x1b, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{"negative integer overflow", []byte{0x3b, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{"indefinite length byte string", []byte{0x5f, 0x41, 0x01, 0xff}},
+		{"indefinite length array", []byte{0x9f, 0x01, 0xff}},
+		{"reserved additional information", []byte{0x1c}},
+		{"half precision float", []byte{0xf9, 0x3c, 0x00}},
+		{"double precision float", []byte{0xfb, 0x3f, 0xf1, 0x99, 0x99, 0x99, 0x99, 0x99, 0x9a}},
+		{"unassigned simple value", []byte{0xe0}},
+		{"break outside an indefinite item", []byte{0xff}},
+		{"tag without item", []byte{0xc2}},
+		{"nesting too deep", append(bytes.Repeat([]byte{0x81}, maxCBORDepth+1), 0x01)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.data); !errors.Is(err, ErrInvalidCBOR) {
+				t.Errorf("decodeCBOR(%x) error = %v, want %v", tt.data, err, ErrInvalidCBOR)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORMaxDepth(t *testing.T) {
+	data := append(bytes.Repeat([]byte{0x81}, maxCBORDepth), 0x01)
+	if _, _, err := decodeCBOR(data); err != nil {
+		t.Errorf("decodeCBOR() of %d nested arrays failed: %v", maxCBORDepth, err)
+	}
+}
+
+func TestCBOREncodeRoundTrip(t *testing.T) {
+	value := cborPairs{1, 2, 3, -7, -1, 1, -2, []byte{0xaa}, "id", "text", "list", []any{1, -1, true, nil}}
+
+	decoded, length, err := decodeCBOR(cborEncode(value))
+	if err != nil {
+		t.Fatalf("decodeCBOR() failed: %v", err)
+	}
+
+	want := map[any]any{
+		int64(1): int64(2), int64(3): int64(-7), int64(-1): int64(1), int64(-2): []byte{0xaa},
+		"id": "text", "list": []any{int64(1), int64(-1), true, nil},
+	}
+	if !reflect.DeepEqual(decoded, want) {
+		t.Errorf("decodeCBOR() = %#v, want %#v", decoded, want)
+	}
+	if length != len(cborEncode(value)) {
+		t.Errorf("decodeCBOR() length = %d, want %d", length, len(cborEncode(value)))
+	}
+}
@@ -0,0 +1,130 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// COSE algorithm identifiers of the supported credential keys
+const (
+	COSE_ALG_ES256 = -7
+	COSE_ALG_EDDSA = -8
+	COSE_ALG_RS256 = -257
+)
+
+// COSE key parameters (RFC 9053)
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyRSAN      = -1
+	coseKeyRSAE      = -2
+
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// minimum size of the accepted RSA credential keys
+const minRSAKeyBits = 2048
+
+var (
+	ErrUnsupportedKey = errors.New("webauthn: unsupported credential public key")
+	ErrBadSignature   = errors.New("webauthn: signature verification failed")
+)
+
+// SupportedAlgorithms lists the accepted credential algorithms by preference
+var SupportedAlgorithms = []int{COSE_ALG_ES256, COSE_ALG_EDDSA, COSE_ALG_RS256}
+
+// ParsePublicKey parses a COSE encoded credential public key and returns it with its algorithm
+func ParsePublicKey(coseKey []byte) (crypto.PublicKey, int, error) {
+	decoded, _, err := decodeCBOR(coseKey)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	params, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, 0, ErrUnsupportedKey
+	}
+
+	keyType, _ := params[int64(coseKeyType)].(int64)
+	algorithm, _ := params[int64(coseKeyAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == COSE_ALG_ES256:
+		curve, _ := params[int64(coseKeyCurve)].(int64)
+		x, okX := params[int64(coseKeyX)].([]byte)
+		y, okY := params[int64(coseKeyY)].([]byte)
+		if curve != coseCurveP256 || !okX || !okY || len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrUnsupportedKey
+		}
+
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !publicKey.Curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, 0, ErrUnsupportedKey
+		}
+
+		return publicKey, COSE_ALG_ES256, nil
+	case keyType == coseKeyTypeOKP && algorithm == COSE_ALG_EDDSA:
+		curve, _ := params[int64(coseKeyCurve)].(int64)
+		x, okX := params[int64(coseKeyX)].([]byte)
+		if curve != coseCurveEd25519 || !okX || len(x) != ed25519.PublicKeySize {
+			return nil, 0, ErrUnsupportedKey
+		}
+
+		return ed25519.PublicKey(x), COSE_ALG_EDDSA, nil
+	case keyType == coseKeyTypeRSA && algorithm == COSE_ALG_RS256:
+		n, okN := params[int64(coseKeyRSAN)].([]byte)
+		e, okE := params[int64(coseKeyRSAE)].([]byte)
+		if !okN || !okE || len(e) == 0 || len(e) > 4 {
+			return nil, 0, ErrUnsupportedKey
+		}
+
+		publicKey := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if publicKey.N.BitLen() < minRSAKeyBits || publicKey.E < 3 {
+			return nil, 0, ErrUnsupportedKey
+		}
+
+		return publicKey, COSE_ALG_RS256, nil
+	default:
+		return nil, 0, ErrUnsupportedKey
+	}
+}
+
+// verifySignature verifies the signature of the message with a COSE encoded public key
+func verifySignature(coseKey []byte, message []byte, signature []byte) error {
+	publicKey, algorithm, err := ParsePublicKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	digest := sha256.Sum256(message)
+
+	valid := false
+	switch algorithm {
+	case COSE_ALG_ES256:
+		valid = ecdsa.VerifyASN1(publicKey.(*ecdsa.PublicKey), digest[:], signature)
+	case COSE_ALG_EDDSA:
+		valid = ed25519.Verify(publicKey.(ed25519.PublicKey), message, signature)
+	case COSE_ALG_RS256:
+		valid = rsa.VerifyPKCS1v15(publicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], signature) == nil
+	}
+
+	if !valid {
+		return ErrBadSignature
+	}
+
+	return nil
+}
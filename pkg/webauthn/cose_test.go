@@ -0,0 +1,158 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+// coseEC2Key encodes the ES256 COSE key of the public key
+func coseEC2Key(publicKey *ecdsa.PublicKey) []byte {
+	return cborEncode(cborPairs{
+		coseKeyType, coseKeyTypeEC2,
+		coseKeyAlgorithm, COSE_ALG_ES256,
+		coseKeyCurve, coseCurveP256,
+		coseKeyX, publicKey.X.FillBytes(make([]byte, 32)),
+		coseKeyY, publicKey.Y.FillBytes(make([]byte, 32)),
+	})
+}
+
+// coseOKPKey encodes the EdDSA COSE key of the public key
+func coseOKPKey(publicKey ed25519.PublicKey) []byte {
+	return cborEncode(cborPairs{
+		coseKeyType, coseKeyTypeOKP,
+		coseKeyAlgorithm, COSE_ALG_EDDSA,
+		coseKeyCurve, coseCurveEd25519,
+		coseKeyX, []byte(publicKey),
+	})
+}
+
+// coseRSAKey encodes the RS256 COSE key of the public key
+func coseRSAKey(publicKey *rsa.PublicKey) []byte {
+	return cborEncode(cborPairs{
+		coseKeyType, coseKeyTypeRSA,
+		coseKeyAlgorithm, COSE_ALG_RS256,
+		coseKeyRSAN, publicKey.N.Bytes(),
+		coseKeyRSAE, big.NewInt(int64(publicKey.E)).Bytes(),
+	})
+}
+
+func TestParsePublicKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublicKey, _, _ := ed25519.GenerateKey(rand.Reader)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+
+	tests := []struct {
+		name      string
+		coseKey   []byte
+		algorithm int
+		publicKey crypto.PublicKey
+	}{
+		{"ES256", coseEC2Key(&ecKey.PublicKey), COSE_ALG_ES256, &ecKey.PublicKey},
+		{"EdDSA", coseOKPKey(edPublicKey), COSE_ALG_EDDSA, edPublicKey},
+		{"RS256", coseRSAKey(&rsaKey.PublicKey), COSE_ALG_RS256, &rsaKey.PublicKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			publicKey, algorithm, err := ParsePublicKey(tt.coseKey)
+			if err != nil {
+				t.Fatalf("ParsePublicKey() failed: %v", err)
+			}
+			if algorithm != tt.algorithm {
+				t.Errorf("ParsePublicKey() algorithm = %d, want %d", algorithm, tt.algorithm)
+			}
+			if equal, ok := publicKey.(interface{ Equal(crypto.PublicKey) bool }); !ok || !equal.Equal(tt.publicKey) {
+				t.Errorf("ParsePublicKey() returned a different key")
+			}
+		})
+	}
+}
+
+func TestParsePublicKeyMalformed(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	x := ecKey.X.FillBytes(make([]byte, 32))
+	y := ecKey.Y.FillBytes(make([]byte, 32))
+	smallRSAKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+
+	tests := []struct {
+		name    string
+		coseKey []byte
+		err     error
+	}{
+		{"empty", []byte{}, ErrInvalidCBOR},
+		{"truncated", coseEC2Key(&ecKey.PublicKey)[:40], ErrInvalidCBOR},
+		{"not a map", cborEncode([]any{coseKeyTypeEC2, COSE_ALG_ES256}), ErrUnsupportedKey},
+		{"no key type", cborEncode(cborPairs{coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, coseCurveP256, coseKeyX, x, coseKeyY, y}), ErrUnsupportedKey},
+		{"text key type", cborEncode(cborPairs{coseKeyType, "EC2", coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, coseCurveP256, coseKeyX, x, coseKeyY, y}), ErrUnsupportedKey},
+		{"unsupported algorithm", cborEncode(cborPairs{coseKeyType, coseKeyTypeEC2, coseKeyAlgorithm, -35, coseKeyCurve, coseCurveP256, coseKeyX, x, coseKeyY, y}), ErrUnsupportedKey},
+		{"algorithm of another key type", cborEncode(cborPairs{coseKeyType, coseKeyTypeOKP, coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, coseCurveP256, coseKeyX, x, coseKeyY, y}), ErrUnsupportedKey},
+		{"EC2 wrong curve", cborEncode(cborPairs{coseKeyType, coseKeyTypeEC2, coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, 2, coseKeyX, x, coseKeyY, y}), ErrUnsupportedKey},
+		{"EC2 missing y", cborEncode(cborPairs{coseKeyType, coseKeyTypeEC2, coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, coseCurveP256, coseKeyX, x}), ErrUnsupportedKey},
+		{"EC2 short x", cborEncode(cborPairs{coseKeyType, coseKeyTypeEC2, coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, coseCurveP256, coseKeyX, x[1:], coseKeyY, y}), ErrUnsupportedKey},
+		{"EC2 text coordinates", cborEncode(cborPairs{coseKeyType, coseKeyTypeEC2, coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, coseCurveP256, coseKeyX, string(x), coseKeyY, string(y)}), ErrUnsupportedKey},
+		{"EC2 point not on the curve", cborEncode(cborPairs{coseKeyType, coseKeyTypeEC2, coseKeyAlgorithm, COSE_ALG_ES256, coseKeyCurve, coseCurveP256, coseKeyX, x, coseKeyY, x}), ErrUnsupportedKey},
+		{"OKP wrong curve", cborEncode(cborPairs{coseKeyType, coseKeyTypeOKP, coseKeyAlgorithm, COSE_ALG_EDDSA, coseKeyCurve, 7, coseKeyX, x}), ErrUnsupportedKey},
+		{"OKP short key", cborEncode(cborPairs{coseKeyType, coseKeyTypeOKP, coseKeyAlgorithm, COSE_ALG_EDDSA, coseKeyCurve, coseCurveEd25519, coseKeyX, x[1:]}), ErrUnsupportedKey},
+		{"RSA key below the minimum size", coseRSAKey(&smallRSAKey.PublicKey), ErrUnsupportedKey},
+		{"RSA missing exponent", cborEncode(cborPairs{coseKeyType, coseKeyTypeRSA, coseKeyAlgorithm, COSE_ALG_RS256, coseKeyRSAN, rsaKey.N.Bytes()}), ErrUnsupportedKey},
+		{"RSA empty exponent", cborEncode(cborPairs{coseKeyType, coseKeyTypeRSA, coseKeyAlgorithm, COSE_ALG_RS256, coseKeyRSAN, rsaKey.N.Bytes(), coseKeyRSAE, []byte{}}), ErrUnsupportedKey},
+		{"RSA exponent too long", cborEncode(cborPairs{coseKeyType, coseKeyTypeRSA, coseKeyAlgorithm, COSE_ALG_RS256, coseKeyRSAN, rsaKey.N.Bytes(), coseKeyRSAE, []byte{1, 0, 0, 0, 1}}), ErrUnsupportedKey},
+		{"RSA exponent one", cborEncode(cborPairs{coseKeyType, coseKeyTypeRSA, coseKeyAlgorithm, COSE_ALG_RS256, coseKeyRSAN, rsaKey.N.Bytes(), coseKeyRSAE, []byte{1}}), ErrUnsupportedKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := ParsePublicKey(tt.coseKey); !errors.Is(err, tt.err) {
+				t.Errorf("ParsePublicKey() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	message := []byte("authenticator data and client data hash")
+	digest := sha256.Sum256(message)
+
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecSignature, _ := ecdsa.SignASN1(rand.Reader, ecKey, digest[:])
+	edPublicKey, edPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	edSignature := ed25519.Sign(edPrivateKey, message)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, minRSAKeyBits)
+	rsaSignature, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+
+	tests := []struct {
+		name      string
+		coseKey   []byte
+		message   []byte
+		signature []byte
+		err       error
+	}{
+		{"ES256", coseEC2Key(&ecKey.PublicKey), message, ecSignature, nil},
+		{"EdDSA", coseOKPKey(edPublicKey), message, edSignature, nil},
+		{"RS256", coseRSAKey(&rsaKey.PublicKey), message, rsaSignature, nil},
+		{"ES256 other message", coseEC2Key(&ecKey.PublicKey), []byte("other"), ecSignature, ErrBadSignature},
+		{"EdDSA other message", coseOKPKey(edPublicKey), []byte("other"), edSignature, ErrBadSignature},
+		{"RS256 other message", coseRSAKey(&rsaKey.PublicKey), []byte("other"), rsaSignature, ErrBadSignature},
+		{"ES256 malformed signature", coseEC2Key(&ecKey.PublicKey), message, []byte{0x30, 0x00}, ErrBadSignature},
+		{"EdDSA signature of another algorithm", coseOKPKey(edPublicKey), message, ecSignature, ErrBadSignature},
+		{"empty signature", coseRSAKey(&rsaKey.PublicKey), message, []byte{}, ErrBadSignature},
+		{"malformed key", []byte{0xa1}, message, ecSignature, ErrInvalidCBOR},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifySignature(tt.coseKey, tt.message, tt.signature); !errors.Is(err, tt.err) {
+				t.Errorf("verifySignature() error = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,418 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// length in bytes of the ceremony challenges
+	challengeBytes = 32
+	// time the client is given to complete a ceremony
+	CeremonyTimeout = 5 * time.Minute
+
+	credentialTypePublicKey = "public-key"
+	clientDataTypeCreate    = "webauthn.create"
+	clientDataTypeGet       = "webauthn.get"
+)
+
+var (
+	ErrInvalidResponse     = errors.New("webauthn: invalid client response")
+	ErrInvalidClientData   = errors.New("webauthn: invalid client data")
+	ErrOriginMismatch      = errors.New("webauthn: origin not allowed")
+	ErrRPIDMismatch        = errors.New("webauthn: relying party id mismatch")
+	ErrUserNotPresent      = errors.New("webauthn: user presence missing")
+	ErrUserNotVerified     = errors.New("webauthn: user verification missing")
+	ErrSignCountRegression = errors.New("webauthn: sign counter did not increase, the authenticator may be cloned")
+)
+
+// RelyingParty verifies the WebAuthn ceremonies of a relying party. Only the "none"
+// attestation is requested, attestation statements are not verified.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// NewRelyingParty creates a relying party for the RP ID, usually the domain, accepting
+// ceremonies from the given origins
+func NewRelyingParty(id string, name string, origins []string) *RelyingParty {
+	return &RelyingParty{
+		ID:      id,
+		Name:    name,
+		Origins: origins,
+	}
+}
+
+// NewChallenge returns a new random base64url encoded challenge
+func NewChallenge() (string, error) {
+	challenge := make([]byte, challengeBytes)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", err
+	}
+
+	return EncodeBase64URL(challenge), nil
+}
+
+// EncodeBase64URL encodes binary values the way the WebAuthn JSON serialization does
+func EncodeBase64URL(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeBase64URL decodes base64url values with or without padding
+func DecodeBase64URL(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// CredentialDescriptor identifies a credential in the ceremony options
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// NewCredentialDescriptor describes a public key credential
+func NewCredentialDescriptor(credentialID []byte, transports []string) CredentialDescriptor {
+	return CredentialDescriptor{
+		Type:       credentialTypePublicKey,
+		ID:         EncodeBase64URL(credentialID),
+		Transports: transports,
+	}
+}
+
+// UserEntity represents the account a credential is registered for
+type UserEntity struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type rpEntity struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type credentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int    `json:"alg"`
+}
+
+type authenticatorSelection struct {
+	ResidentKey        string `json:"residentKey"`
+	RequireResidentKey bool   `json:"requireResidentKey"`
+	UserVerification   string `json:"userVerification"`
+}
+
+// CreationOptions represents the JSON serialization of PublicKeyCredentialCreationOptions
+type CreationOptions struct {
+	RP                     rpEntity               `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	Challenge              string                 `json:"challenge"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection authenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+// RequestOptions represents the JSON serialization of PublicKeyCredentialRequestOptions
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// CreationOptions returns the options of a registration ceremony, credentials must be
+// discoverable (passkeys) and user verification is required
+func (rp *RelyingParty) CreationOptions(challenge string, user UserEntity, exclude []CredentialDescriptor) CreationOptions {
+	params := make([]credentialParameter, 0, len(SupportedAlgorithms))
+	for _, algorithm := range SupportedAlgorithms {
+		params = append(params, credentialParameter{Type: credentialTypePublicKey, Algorithm: algorithm})
+	}
+	if exclude == nil {
+		exclude = []CredentialDescriptor{}
+	}
+
+	return CreationOptions{
+		RP:                 rpEntity{ID: rp.ID, Name: rp.Name},
+		User:               user,
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            CeremonyTimeout.Milliseconds(),
+		ExcludeCredentials: exclude,
+		AuthenticatorSelection: authenticatorSelection{
+			ResidentKey:        "required",
+			RequireResidentKey: true,
+			UserVerification:   "required",
+		},
+		Attestation: "none",
+	}
+}
+
+// RequestOptions returns the options of an authentication ceremony, an empty allow list
+// lets the user pick any discoverable credential of the relying party
+func (rp *RelyingParty) RequestOptions(challenge string, allow []CredentialDescriptor) RequestOptions {
+	if allow == nil {
+		allow = []CredentialDescriptor{}
+	}
+
+	return RequestOptions{
+		Challenge:        challenge,
+		Timeout:          CeremonyTimeout.Milliseconds(),
+		RPID:             rp.ID,
+		AllowCredentials: allow,
+		UserVerification: "required",
+	}
+}
+
+// RegistrationResponse represents the JSON serialization of the credential returned by
+// navigator.credentials.create
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse represents the JSON serialization of the credential returned by
+// navigator.credentials.get
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// clientData represents the CollectedClientData signed by the authenticator
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// Registration represents a verified registration ceremony
+type Registration struct {
+	// Challenge is the challenge the client answered, the caller checks it was issued
+	Challenge    string
+	CredentialID []byte
+	PublicKey    []byte
+	Algorithm    int
+	SignCount    uint32
+	AAGUID       []byte
+	Transports   []string
+}
+
+// VerifyRegistration verifies a registration response, except for the challenge which is
+// returned for the caller to check against the issued ones
+func (rp *RelyingParty) VerifyRegistration(resp RegistrationResponse) (Registration, error) {
+	if resp.Type != credentialTypePublicKey {
+		return Registration{}, ErrInvalidResponse
+	}
+
+	clientDataJSON, err := DecodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return Registration{}, ErrInvalidResponse
+	}
+
+	collected, err := rp.verifyClientData(clientDataJSON, clientDataTypeCreate)
+	if err != nil {
+		return Registration{}, err
+	}
+
+	attestationObject, err := DecodeBase64URL(resp.Response.AttestationObject)
+	if err != nil {
+		return Registration{}, ErrInvalidResponse
+	}
+
+	decoded, _, err := decodeCBOR(attestationObject)
+	if err != nil {
+		return Registration{}, err
+	}
+	attestation, ok := decoded.(map[any]any)
+	if !ok {
+		return Registration{}, ErrInvalidResponse
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return Registration{}, ErrInvalidResponse
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return Registration{}, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return Registration{}, err
+	}
+
+	if authData.CredentialID == nil {
+		return Registration{}, ErrInvalidAuthenticatorData
+	}
+
+	// the id reported by the client must be the one the authenticator attested
+	rawID, err := DecodeBase64URL(resp.RawID)
+	if err != nil || !bytes.Equal(rawID, authData.CredentialID) {
+		return Registration{}, ErrInvalidResponse
+	}
+
+	_, algorithm, err := ParsePublicKey(authData.PublicKey)
+	if err != nil {
+		return Registration{}, err
+	}
+
+	return Registration{
+		Challenge:    collected.Challenge,
+		CredentialID: authData.CredentialID,
+		PublicKey:    authData.PublicKey,
+		Algorithm:    algorithm,
+		SignCount:    authData.SignCount,
+		AAGUID:       authData.AAGUID,
+		Transports:   resp.Response.Transports,
+	}, nil
+}
+
+// Assertion represents a decoded authentication response awaiting verification
+type Assertion struct {
+	// Challenge is the challenge the client answered, the caller checks it was issued
+	Challenge    string
+	CredentialID []byte
+	UserHandle   []byte
+
+	clientDataJSON    []byte
+	authenticatorData []byte
+	signature         []byte
+}
+
+// ParseAssertion decodes an authentication response, the caller looks the credential up
+// by its ID and calls VerifyAssertion with its public key
+func (rp *RelyingParty) ParseAssertion(resp AssertionResponse) (Assertion, error) {
+	if resp.Type != credentialTypePublicKey {
+		return Assertion{}, ErrInvalidResponse
+	}
+
+	credentialID, err := DecodeBase64URL(resp.RawID)
+	if err != nil || len(credentialID) == 0 {
+		return Assertion{}, ErrInvalidResponse
+	}
+
+	clientDataJSON, err := DecodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return Assertion{}, ErrInvalidResponse
+	}
+
+	authenticatorData, err := DecodeBase64URL(resp.Response.AuthenticatorData)
+	if err != nil {
+		return Assertion{}, ErrInvalidResponse
+	}
+
+	signature, err := DecodeBase64URL(resp.Response.Signature)
+	if err != nil {
+		return Assertion{}, ErrInvalidResponse
+	}
+
+	userHandle, err := DecodeBase64URL(resp.Response.UserHandle)
+	if err != nil {
+		return Assertion{}, ErrInvalidResponse
+	}
+
+	var collected clientData
+	if err := json.Unmarshal(clientDataJSON, &collected); err != nil {
+		return Assertion{}, ErrInvalidClientData
+	}
+
+	return Assertion{
+		Challenge:         collected.Challenge,
+		CredentialID:      credentialID,
+		UserHandle:        userHandle,
+		clientDataJSON:    clientDataJSON,
+		authenticatorData: authenticatorData,
+		signature:         signature,
+	}, nil
+}
+
+// VerifyAssertion verifies the assertion with the stored credential public key and sign
+// counter, and returns the new sign counter to store
+func (rp *RelyingParty) VerifyAssertion(assertion Assertion, publicKey []byte, storedSignCount uint32) (uint32, error) {
+	if _, err := rp.verifyClientData(assertion.clientDataJSON, clientDataTypeGet); err != nil {
+		return 0, err
+	}
+
+	authData, err := parseAuthenticatorData(assertion.authenticatorData)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := rp.verifyAuthenticatorData(authData); err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(assertion.clientDataJSON)
+	signed := append(append([]byte{}, assertion.authenticatorData...), clientDataHash[:]...)
+	if err := verifySignature(publicKey, signed, assertion.signature); err != nil {
+		return 0, err
+	}
+
+	// authenticators without a counter, like most synced passkeys, always report zero
+	if (authData.SignCount != 0 || storedSignCount != 0) && authData.SignCount <= storedSignCount {
+		return 0, ErrSignCountRegression
+	}
+
+	return authData.SignCount, nil
+}
+
+// verifyClientData checks the ceremony type and the origin of the client data
+func (rp *RelyingParty) verifyClientData(clientDataJSON []byte, ceremonyType string) (clientData, error) {
+	var collected clientData
+	if err := json.Unmarshal(clientDataJSON, &collected); err != nil {
+		return clientData{}, ErrInvalidClientData
+	}
+
+	if collected.Type != ceremonyType || collected.Challenge == "" {
+		return clientData{}, ErrInvalidClientData
+	}
+
+	for _, origin := range rp.Origins {
+		if collected.Origin == origin {
+			return collected, nil
+		}
+	}
+
+	return clientData{}, ErrOriginMismatch
+}
+
+// verifyAuthenticatorData checks the RP ID hash and the user presence and verification flags
+func (rp *RelyingParty) verifyAuthenticatorData(authData AuthenticatorData) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if subtle.ConstantTimeCompare(authData.RPIDHash, rpIDHash[:]) != 1 {
+		return ErrRPIDMismatch
+	}
+
+	if !authData.UserPresent() {
+		return ErrUserNotPresent
+	}
+
+	if !authData.UserVerified() {
+		return ErrUserNotVerified
+	}
+
+	return nil
+}